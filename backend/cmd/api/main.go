@@ -1,11 +1,27 @@
 package main
 
 import (
+	"backend/internal/app/document"
+	"backend/internal/app/export"
+	"backend/internal/app/feed"
 	"backend/internal/app/handler"
+	"backend/internal/app/jobs"
+	"backend/internal/app/lockout"
+	"backend/internal/app/oidc"
+	"backend/internal/app/ratelimit"
+	"backend/internal/app/recommend"
+	"backend/internal/app/search"
+	"backend/internal/app/tokens"
+	"backend/internal/app/twofactor"
+	"backend/internal/app/webauthn"
 	"backend/internal/infras/database"
 	repository "backend/internal/infras/repos"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -19,6 +35,12 @@ func main() {
 		log.Println("Warning: .env file not found")
 	}
 
+	// access tokens, refuse to start without a signing key
+	tokenService, err := tokens.NewService(tokens.ConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Connect to database
 	db, err := database.Connect()
 	if err != nil {
@@ -28,19 +50,57 @@ func main() {
 	// repo
 	userRepo := &repository.UserRepository{DB: db}
 	productRepo := &repository.ProductRepository{DB: db}
-	cartRepo := &repository.CartRepository{DB: db}
+	cartRepo := &repository.CartRepository{DB: db, OrderPrefix: os.Getenv("ORDER_NUMBER_PREFIX")}
 	tmpRepo := &repository.TmpRepository{DB: db}
+	stockRepo := &repository.StockRepository{DB: db}
+	viewRepo := &repository.ProductViewRepository{DB: db}
+	recentRepo := &repository.RecentViewRepository{DB: db}
+	searchRepo := &repository.SearchQueryRepository{DB: db}
+	analyticsRepo := &repository.AnalyticsRepository{DB: db}
+	invoiceRepo := &repository.InvoiceRepository{DB: db}
+	refreshRepo := &repository.RefreshTokenRepository{DB: db}
+	sessionRepo := &repository.SessionRepository{DB: db}
+	twoFactorRepo := &repository.TwoFactorRepository{DB: db}
+	passkeyRepo := &repository.PasskeyRepository{DB: db}
+	identityRepo := &repository.IdentityRepository{DB: db}
 
-	// handlers
-	userHandler := &handler.UserHandler{Repo: userRepo}
-	authHandler := handler.NewAuthHandler(userRepo, tmpRepo)
-	productHandler := handler.NewProductHandler(productRepo)
-	cartHandler := handler.NewCartHandler(cartRepo, productRepo)
-	adminHandler := handler.NewAdminHandler(userRepo, productRepo, cartRepo)
+	if n, err := productRepo.BackfillSlugs(); err != nil {
+		log.Printf("Failed to backfill product slugs: %v", err)
+	} else if n > 0 {
+		log.Printf("Assigned slugs to %d products", n)
+	}
 
-	r := gin.Default()
+	if n, err := cartRepo.BackfillOrderNumbers(); err != nil {
+		log.Printf("Failed to backfill order numbers: %v", err)
+	} else if n > 0 {
+		log.Printf("Assigned order numbers to %d orders", n)
+	}
+
+	if n, err := identityRepo.BackfillGoogleIDs(); err != nil {
+		log.Printf("Failed to copy Google accounts to user identities: %v", err)
+	} else if n > 0 {
+		log.Printf("Copied %d Google accounts to user identities", n)
+	}
+
+	// invoices and packing slips
+	taxRate, _ := strconv.ParseFloat(os.Getenv("TAX_RATE"), 64)
+	invoiceCurrency := os.Getenv("INVOICE_CURRENCY")
+	if invoiceCurrency == "" {
+		invoiceCurrency = os.Getenv("FEED_CURRENCY")
+	}
+	documents := document.NewService(cartRepo, invoiceRepo, document.SellerFromEnv(), taxRate, invoiceCurrency)
+
+	// two-factor secrets are encrypted with their own key, refuse to start without it
+	twoFactorKey := os.Getenv("TWO_FACTOR_ENCRYPTION_KEY")
+	if twoFactorKey == "" {
+		log.Fatal("TWO_FACTOR_ENCRYPTION_KEY must be set to encrypt two-factor secrets")
+	}
+	requireAdmin2FA, _ := strconv.ParseBool(os.Getenv("REQUIRE_ADMIN_2FA"))
+	twoFactorService, err := twofactor.NewService(twoFactorRepo, twoFactorKey, os.Getenv("TWO_FACTOR_ISSUER"), requireAdmin2FA)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// CORS
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:3000"
@@ -50,62 +110,261 @@ func main() {
 		frontendURL = frontendURL[:len(frontendURL)-1]
 	}
 
+	// passkeys are bound to the storefront's domain unless WEBAUTHN_RP_ID says otherwise
+	relyingParty, err := webauthn.NewRelyingParty(webauthn.ConfigFromEnv(frontendURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+	passkeys := webauthn.NewService(relyingParty, passkeyRepo)
+
+	// failed login and password reset attempts are counted in Postgres so every instance sees them,
+	// LOCKOUT_STORE=memory keeps them per process instead
+	limiter := lockout.NewLimiter(&repository.LoginThrottleRepository{DB: db}, lockout.PoliciesFromEnv())
+	if os.Getenv("LOCKOUT_STORE") == "memory" {
+		limiter.Store = lockout.NewMemoryStore()
+	}
+	limiter.OnLock = handler.LockoutNotifier(userRepo)
+
+	// request throttling per route group; buckets live in memory unless RATE_LIMIT_STORE=postgres,
+	// which shares them between instances at the cost of a write per request
+	rateLimiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.PoliciesFromEnv())
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		rateLimiter.Store = &repository.RateLimitRepository{DB: db}
+	}
+
+	// Google and the identity providers in OIDC_PROVIDERS; discovery happens at the first login
+	providers, err := oidc.NewRegistry(&http.Client{Timeout: 10 * time.Second}, oidc.ConfigsFromEnv()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// handlers
+	userHandler := &handler.UserHandler{Repo: userRepo, CartRepo: cartRepo}
+	authHandler := handler.NewAuthHandler(userRepo, providers, identityRepo, tmpRepo, viewRepo, recentRepo, tokenService, refreshRepo, sessionRepo, twoFactorService, limiter)
+	productHandler := handler.NewProductHandler(productRepo, viewRepo, recentRepo, searchRepo)
+	cartHandler := handler.NewCartHandler(cartRepo, productRepo, documents)
+	adminHandler := handler.NewAdminHandler(userRepo, productRepo, cartRepo)
+	inventoryHandler := handler.NewInventoryHandler(stockRepo, productRepo)
+	catalogHandler := handler.NewCatalogHandler(productRepo)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsRepo)
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "exports")
+	}
+	exporter := export.NewExporter(productRepo, userRepo, cartRepo)
+	exportJobs, err := export.NewManager(exporter, exportDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	exportHandler := handler.NewExportHandler(exporter, exportJobs)
+	documentHandler := handler.NewDocumentHandler(documents)
+	sessionHandler := handler.NewSessionHandler(sessionRepo)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService, userRepo, limiter)
+	passkeyHandler := handler.NewPasskeyHandler(passkeys, authHandler, userRepo)
+	identityHandler := handler.NewIdentityHandler(authHandler, identityRepo, passkeyRepo)
+	lockoutHandler := handler.NewLockoutHandler(limiter, userRepo)
+
+	recommender := recommend.NewService(productRepo, cartRepo, viewRepo)
+	recommendationHandler := handler.NewRecommendationHandler(productRepo, recommender)
+	recentlyViewedHandler := handler.NewRecentlyViewedHandler(recentRepo, productRepo)
+
+	suggester := search.NewSuggester(productRepo, searchRepo)
+	searchHandler := handler.NewSearchHandler(suggester, searchRepo)
+
+	// scheduled jobs
+	digestHour := 8
+	if v, err := strconv.Atoi(os.Getenv("LOW_STOCK_DIGEST_HOUR")); err == nil && v >= 0 && v < 24 {
+		digestHour = v
+	}
+	jobs.Daily("low-stock digest", digestHour, jobs.NewLowStockDigest(productRepo, userRepo).Run)
+	jobs.Now("recommendation refresh", recommender.Refresh)
+	jobs.Every("recommendation refresh", time.Hour, recommender.Refresh)
+
+	jobs.Every("export cleanup", time.Hour, exportJobs.Cleanup)
+	jobs.Now("search suggestion refresh", suggester.Refresh)
+	jobs.Every("search suggestion refresh", 10*time.Minute, suggester.Refresh)
+
+	recentRetentionDays := 90
+	if v, err := strconv.Atoi(os.Getenv("RECENTLY_VIEWED_RETENTION_DAYS")); err == nil && v > 0 {
+		recentRetentionDays = v
+	}
+	jobs.Daily("recently viewed prune", 3, jobs.NewRecentViewPrune(recentRepo, time.Duration(recentRetentionDays)*24*time.Hour).Run)
+
+	// sessions idle past the refresh token lifetime can no longer be used; expired refresh tokens
+	// are kept for a week so reuse of a rotated token is still caught
+	jobs.Daily("session prune", 4, jobs.NewSessionPrune(sessionRepo, refreshRepo, tokenService.RefreshTTL()+7*24*time.Hour, 7*24*time.Hour).Run)
+	jobs.Every("passkey challenge prune", time.Hour, passkeys.PruneChallenges)
+	jobs.Every("login throttle prune", time.Hour, limiter.Prune)
+	jobs.Every("rate limit prune", 10*time.Minute, rateLimiter.Prune)
+
+	r := gin.Default()
+
+	// client IPs key the login limiter, so X-Forwarded-For is only believed from known proxies
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := r.SetTrustedProxies(strings.Split(proxies, ",")); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// sitemap and product feeds link to the storefront
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = frontendURL
+	}
+	feedGenerator := feed.NewGenerator(productRepo, siteURL, os.Getenv("FEED_CURRENCY"))
+	feedHandler := handler.NewFeedHandler(feedGenerator)
+	jobs.Every("feed regeneration", time.Hour, feedGenerator.Regenerate)
+
+	// CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{frontendURL, "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Location", "X-Search-Id", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	r.GET("/auth/google/login", authHandler.GoogleLogin)
-	r.GET("/auth/google/callback", authHandler.GoogleCallback)
-	r.POST("/auth/register", authHandler.RegisterWithGmail)
-	r.GET("/auth/confirm", authHandler.ConfirmEmail)
-	r.POST("/auth/login", authHandler.LoginWithGmail)
-	r.POST("/auth/logout", authHandler.Logout)
-	r.POST("/auth/forgot-password", authHandler.ForgotPassword)
-	r.POST("/auth/validate-reset-token", authHandler.ValidateResetToken)
-	r.POST("/auth/reset-password", authHandler.ResetPassword)
+	r.Use(handler.VisitorMiddleware())
 
-	r.GET("/products", productHandler.GetProducts)
-	r.GET("/products/detail/:id", productHandler.GetProductByID)
-	r.POST("/products/search/", productHandler.SearchProducts)
+	// routes that take a password, a code or an email address get the strict policy
+	authLimit := handler.RateLimitMiddleware(rateLimiter, ratelimit.PolicyAuth, handler.RateLimitByIP)
+	authRoutes := r.Group("/auth", authLimit)
+	{
+		authRoutes.POST("/register", authHandler.RegisterWithGmail)
+		authRoutes.GET("/confirm", authHandler.ConfirmEmail)
+		authRoutes.POST("/login", authHandler.LoginWithGmail)
+		authRoutes.POST("/login/2fa", authHandler.LoginTwoFactor)
+		authRoutes.POST("/passkey/login/begin", passkeyHandler.BeginLogin)
+		authRoutes.POST("/passkey/login/finish", passkeyHandler.FinishLogin)
+		authRoutes.POST("/forgot-password", authHandler.ForgotPassword)
+		authRoutes.POST("/validate-reset-token", authHandler.ValidateResetToken)
+		authRoutes.POST("/reset-password", authHandler.ResetPassword)
+	}
+
+	// refreshes, provider redirects and their callbacks carry no credentials to guess
+	sessionRoutes := r.Group("/auth", handler.RateLimitMiddleware(rateLimiter, ratelimit.PolicySession, handler.RateLimitByIP))
+	{
+		sessionRoutes.GET("/google/login", authHandler.GoogleLogin)
+		sessionRoutes.GET("/google/callback", authHandler.GoogleCallback)
+		sessionRoutes.GET("/providers", authHandler.GetLoginProviders)
+		sessionRoutes.GET("/oidc/:provider/login", authHandler.ProviderLogin)
+		sessionRoutes.GET("/oidc/:provider/callback", authHandler.ProviderCallback)
+		sessionRoutes.POST("/oidc/:provider/link", authHandler.StartProviderLink)
+		sessionRoutes.POST("/exchange", authHandler.ExchangeLoginCode)
+		sessionRoutes.POST("/refresh", authHandler.Refresh)
+		sessionRoutes.POST("/logout", authHandler.Logout)
+	}
+
+	products := r.Group("/products", handler.RateLimitMiddleware(rateLimiter, ratelimit.PolicyProducts, handler.RateLimitByIP))
+	{
+		products.GET("", productHandler.GetProducts)
+		products.GET("/detail/:id", authHandler.OptionalAuthMiddleware(), productHandler.GetProductByID)
+		products.GET("/top", recommendationHandler.GetTopProducts)
+		products.GET("/suggest", searchHandler.Suggest)
+		products.GET("/:slug", authHandler.OptionalAuthMiddleware(), productHandler.GetProductBySlug)
+		products.GET("/:slug/related", recommendationHandler.GetRelated)
+		products.POST("/search/", authHandler.OptionalAuthMiddleware(), productHandler.SearchProducts)
+		products.POST("/search/click", searchHandler.RecordClick)
+	}
+
+	// recently viewed works for anonymous sessions too, so it only needs optional auth
+	r.GET("/user/recently-viewed", authHandler.OptionalAuthMiddleware(), recentlyViewedHandler.GetRecentlyViewed)
+	r.DELETE("/user/recently-viewed", authHandler.OptionalAuthMiddleware(), recentlyViewedHandler.ClearRecentlyViewed)
+
+	// feed readers given a key in FEED_API_KEYS get a bucket of their own instead of sharing one
+	// with everything else behind their address
+	feedKeys := handler.APIKeySet(strings.FieldsFunc(os.Getenv("FEED_API_KEYS"), func(r rune) bool { return r == ',' || r == ' ' }))
+	feedLimit := handler.RateLimitMiddleware(rateLimiter, ratelimit.PolicyFeeds, handler.RateLimitByAPIKey("X-API-Key", feedKeys, handler.RateLimitByIP))
+	r.GET("/sitemap.xml", feedLimit, feedHandler.Sitemap)
+	r.GET("/feeds/products.xml", feedLimit, feedHandler.MerchantFeedXML)
+	r.GET("/feeds/products.csv", feedLimit, feedHandler.MerchantFeedCSV)
 
 	auth := r.Group("/")
-	// auth.Use(authHandler.AuthMiddleware())
-	// {
-	auth.GET("/users", userHandler.GetUsers)
-	auth.GET("/user/me", authHandler.GetCurrentUser)
-	auth.POST("/auth/change-password", authHandler.ChangePassword)
-	auth.GET("/user/orders", userHandler.GetUserOrders)
-	auth.PUT("/user/profile", userHandler.UpdateProfile)
-
-	auth.GET("/cart", cartHandler.GetCart)
-	auth.POST("/cart/add", cartHandler.AddToCart)
-	auth.POST("/cart/remove", cartHandler.RemoveFromCart)
-	auth.POST("/cart/update", cartHandler.UpdateCartItem)
-	auth.POST("/cart/checkout", cartHandler.Checkout)
-
-	admin := auth.Group("/admin")
-	admin.Use(adminHandler.AdminMiddleware())
+	auth.Use(authHandler.AuthMiddleware())
 	{
-		admin.GET("/dashboard", adminHandler.GetDashboardStats)
+		auth.GET("/user/me", authHandler.GetCurrentUser)
+		auth.POST("/auth/change-password", authLimit, authHandler.ChangePassword)
+		auth.GET("/user/orders", userHandler.GetUserOrders)
+		auth.GET("/user/orders/:id/invoice", documentHandler.GetMyInvoice)
+		auth.PUT("/user/profile", userHandler.UpdateProfile)
+		auth.GET("/user/sessions", sessionHandler.GetSessions)
+		auth.DELETE("/user/sessions", sessionHandler.RevokeOtherSessions)
+		auth.DELETE("/user/sessions/:id", sessionHandler.RevokeSession)
+		auth.GET("/user/2fa", twoFactorHandler.GetStatus)
+		auth.POST("/user/2fa/setup", twoFactorHandler.Setup)
+		auth.POST("/user/2fa/verify", twoFactorHandler.Verify)
+		auth.POST("/user/2fa/disable", twoFactorHandler.Disable)
+		auth.POST("/user/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		auth.GET("/user/passkeys", passkeyHandler.GetPasskeys)
+		auth.POST("/user/passkeys/register/begin", passkeyHandler.BeginRegistration)
+		auth.POST("/user/passkeys/register/finish", passkeyHandler.FinishRegistration)
+		auth.DELETE("/user/passkeys/:id", passkeyHandler.DeletePasskey)
+		auth.GET("/user/identities", identityHandler.GetIdentities)
+		auth.POST("/user/identities/:provider/link", identityHandler.LinkIdentity)
+		auth.DELETE("/user/identities/:id", identityHandler.UnlinkIdentity)
+		auth.GET("/user/recommendations", recommendationHandler.GetUserRecommendations)
+
+		// the cart is limited per user, it runs after authentication
+		cart := auth.Group("/cart", handler.RateLimitMiddleware(rateLimiter, ratelimit.PolicyCart, handler.RateLimitByUser))
+		{
+			cart.GET("", cartHandler.GetCart)
+			cart.POST("/add", cartHandler.AddToCart)
+			cart.POST("/remove", cartHandler.RemoveFromCart)
+			cart.POST("/update", cartHandler.UpdateCartItem)
+			cart.POST("/checkout", cartHandler.Checkout)
+		}
+
+		admin := auth.Group("/admin")
+		admin.Use(adminHandler.AdminMiddleware(), twoFactorHandler.AdminTwoFactorMiddleware())
+		{
+			admin.GET("/dashboard", adminHandler.GetDashboardStats)
+
+			admin.GET("/users", adminHandler.GetAllUsers)
+			admin.GET("/users/:id", adminHandler.GetUserByID)
+			admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+			admin.POST("/users/:id/unlock", lockoutHandler.UnlockUser)
+			admin.GET("/lockouts", lockoutHandler.GetLockouts)
+
+			admin.GET("/products", adminHandler.GetProducts)
+			admin.POST("/products", adminHandler.CreateProduct)
+			admin.GET("/products/archived", adminHandler.GetArchivedProducts)
+			admin.GET("/products/low-stock", adminHandler.GetLowStockProducts)
+			admin.POST("/products/import", catalogHandler.ImportProducts)
+			admin.GET("/products/export", catalogHandler.ExportProducts)
+			admin.PUT("/products/:id", adminHandler.UpdateProduct)
+			admin.DELETE("/products/:id", adminHandler.DeleteProduct)
+			admin.PUT("/products/:id/status", adminHandler.UpdateProductStatus)
+			admin.POST("/products/:id/restore", adminHandler.RestoreProduct)
+
+			admin.GET("/products/:id/stock/movements", inventoryHandler.GetMovements)
+			admin.POST("/products/:id/stock/movements", inventoryHandler.PostMovement)
+			admin.GET("/products/:id/stock/reconcile", inventoryHandler.GetReconciliation)
+			admin.POST("/products/:id/stock/reconcile", inventoryHandler.SyncLedger)
+
+			admin.GET("/search/popular", searchHandler.GetPopularQueries)
+			admin.GET("/search/zero-results", searchHandler.GetZeroResultQueries)
+			admin.GET("/search/report", searchHandler.GetSearchReport)
 
-		admin.GET("/users", adminHandler.GetAllUsers)
-		admin.GET("/users/:id", adminHandler.GetUserByID)
-		admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+			admin.GET("/analytics/revenue", analyticsHandler.GetRevenue)
+			admin.GET("/analytics/top-products", analyticsHandler.GetTopProducts)
+			admin.GET("/analytics/customers", analyticsHandler.GetCustomers)
+			admin.GET("/analytics/conversion", analyticsHandler.GetConversion)
 
-		admin.POST("/products", adminHandler.CreateProduct)
-		admin.PUT("/products/:id", adminHandler.UpdateProduct)
-		admin.DELETE("/products/:id", adminHandler.DeleteProduct)
+			admin.GET("/exports/jobs", exportHandler.GetJobs)
+			admin.GET("/exports/jobs/:id", exportHandler.GetJob)
+			admin.GET("/exports/jobs/:id/download", exportHandler.DownloadJob)
+			admin.GET("/exports/:report", exportHandler.Export)
+			admin.POST("/exports/:report", exportHandler.StartExport)
 
-		admin.GET("/orders", adminHandler.GetAllOrders)
-		admin.GET("/orders/:id", adminHandler.GetOrderByID)
-		admin.PUT("/orders/:id/status", adminHandler.UpdateOrderStatus)
+			admin.GET("/orders", adminHandler.GetAllOrders)
+			admin.GET("/orders/:id", adminHandler.GetOrderByID)
+			admin.PUT("/orders/:id/status", adminHandler.UpdateOrderStatus)
+			admin.GET("/orders/:id/invoice", documentHandler.GetInvoice)
+			admin.GET("/orders/:id/packing-slip", documentHandler.GetPackingSlip)
+		}
 	}
-	// }
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,58 @@
+// Command evalrecs evaluates the personalised recommendations offline.
+// The latest completed order of every customer is held out, the model is trained on
+// everything before it, and precision@k is reported against a popularity baseline.
+//
+//	go run ./cmd/evalrecs -k 10 -days 365
+package main
+
+import (
+	"backend/internal/app/recommend"
+	"backend/internal/infras/database"
+	repository "backend/internal/infras/repos"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	k := flag.Int("k", 10, "number of recommendations per user")
+	days := flag.Int("days", 365, "how many days of history to load")
+	flag.Parse()
+
+	if *k <= 0 {
+		log.Fatal("k must be positive")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cartRepo := &repository.CartRepository{DB: db}
+	viewRepo := &repository.ProductViewRepository{DB: db}
+
+	since := time.Now().AddDate(0, 0, -*days)
+	views, err := viewRepo.GetViewsSince(since)
+	if err != nil {
+		log.Fatalf("Failed to load product views: %v", err)
+	}
+	lines, err := cartRepo.GetCartLines(since)
+	if err != nil {
+		log.Fatalf("Failed to load cart lines: %v", err)
+	}
+
+	report := recommend.Evaluate(views, lines, *k)
+
+	fmt.Printf("Evaluated %d users on their latest order (%d cold start)\n", report.Users, report.ColdStartUsers)
+	fmt.Printf("precision@%d:            %.4f\n", report.K, report.PrecisionAtK)
+	fmt.Printf("recall@%d:               %.4f\n", report.K, report.RecallAtK)
+	fmt.Printf("hit rate@%d:             %.4f\n", report.K, report.HitRate)
+	fmt.Printf("popularity precision@%d: %.4f\n", report.K, report.PopularityPrecisionAtK)
+}
@@ -0,0 +1,46 @@
+// Command migrate brings the database schema up to date and runs the migrations that are not
+// safe to run on every start of the API.
+//
+//	go run ./cmd/migrate
+//	go run ./cmd/migrate -drop-google-id
+package main
+
+import (
+	"backend/internal/infras/database"
+	repository "backend/internal/infras/repos"
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dropGoogleID := flag.Bool("drop-google-id", false, "drop users.google_id once every Google account in it is in user_identities")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	// connecting migrates the tables
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	identityRepo := &repository.IdentityRepository{DB: db}
+	n, err := identityRepo.BackfillGoogleIDs()
+	if err != nil {
+		log.Fatalf("Failed to copy Google accounts to user identities: %v", err)
+	}
+	if n > 0 {
+		log.Printf("Copied %d Google accounts to user identities", n)
+	}
+
+	if *dropGoogleID {
+		if err := identityRepo.DropGoogleIDColumn(); err != nil {
+			log.Fatalf("Kept users.google_id: %v", err)
+		}
+		log.Println("Dropped users.google_id")
+	}
+}
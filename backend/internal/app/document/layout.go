@@ -0,0 +1,247 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Party is the seller or buyer block of a document
+type Party struct {
+	Name  string
+	Lines []string
+}
+
+// Line is one product line of an order document
+type Line struct {
+	Name      string
+	SKU       string
+	Quantity  int
+	UnitPrice float64
+	Amount    float64
+}
+
+// OrderDocument holds everything printed on an invoice or packing slip
+type OrderDocument struct {
+	OrderRef  string
+	OrderedAt time.Time
+
+	// Invoice only
+	InvoiceNumber string
+	IssuedAt      time.Time
+
+	Seller Party
+	Buyer  Party
+	ShipTo Party
+
+	Lines    []Line
+	Currency string
+	Subtotal float64
+	TaxRate  float64
+	Tax      float64
+	Total    float64
+}
+
+const (
+	margin      = 50.0
+	bodySize    = 9.5
+	rowHeight   = 16.0
+	footerSpace = 60.0
+)
+
+type column struct {
+	title string
+	x     float64 // left edge, or right edge when right is set
+	width float64
+	right bool
+}
+
+// RenderInvoice draws the invoice PDF
+func RenderInvoice(d *OrderDocument) ([]byte, error) {
+	columns := []column{
+		{title: "Item", x: margin, width: 230},
+		{title: "SKU", x: margin + 235, width: 90},
+		{title: "Qty", x: margin + 365, right: true},
+		{title: "Unit price", x: margin + 425, right: true},
+		{title: "Amount", x: pageWidth - margin, right: true},
+	}
+	rows := make([][]string, len(d.Lines))
+	for i, l := range d.Lines {
+		rows[i] = []string{l.Name, l.SKU, strconv.Itoa(l.Quantity), formatAmount(l.UnitPrice), formatAmount(l.Amount)}
+	}
+
+	meta := [][2]string{
+		{"Invoice no.", d.InvoiceNumber},
+		{"Invoice date", d.IssuedAt.Format("02 Jan 2006")},
+		{"Order", d.OrderRef},
+		{"Order date", d.OrderedAt.Format("02 Jan 2006")},
+	}
+
+	p := newPDF()
+	y := drawHeading(p, "INVOICE", meta)
+	y = drawParties(p, y, []labelledParty{{"From", d.Seller}, {"Bill to", d.Buyer}, {"Ship to", d.ShipTo}})
+	y = drawTable(p, y, "INVOICE", meta, columns, rows)
+
+	// Totals need about four rows; start a new page rather than splitting them
+	if y+4*rowHeight > pageHeight-footerSpace {
+		p.AddPage()
+		y = drawHeading(p, "INVOICE", meta)
+	}
+	y += 6
+	p.Line(pageWidth-margin-200, y, pageWidth-margin, y, 0.5)
+	y += rowHeight
+	taxLabel := "Tax (" + strconv.FormatFloat(d.TaxRate*100, 'f', -1, 64) + "%)"
+	for _, t := range [][2]string{{"Subtotal", formatAmount(d.Subtotal)}, {taxLabel, formatAmount(d.Tax)}} {
+		p.TextRight(pageWidth-margin-110, y, bodySize, false, t[0])
+		p.TextRight(pageWidth-margin, y, bodySize, false, t[1])
+		y += rowHeight
+	}
+	p.TextRight(pageWidth-margin-110, y+2, 11, true, "Total ("+d.Currency+")")
+	p.TextRight(pageWidth-margin, y+2, 11, true, formatAmount(d.Total))
+
+	drawFooters(p, d.Seller.Name+" - "+d.InvoiceNumber)
+	return p.Bytes()
+}
+
+// RenderPackingSlip draws the packing slip PDF: what to ship and where, without prices
+func RenderPackingSlip(d *OrderDocument) ([]byte, error) {
+	columns := []column{
+		{title: "Item", x: margin, width: 300},
+		{title: "SKU", x: margin + 310, width: 120},
+		{title: "Qty", x: pageWidth - margin, right: true},
+	}
+	rows := make([][]string, len(d.Lines))
+	units := 0
+	for i, l := range d.Lines {
+		rows[i] = []string{l.Name, l.SKU, strconv.Itoa(l.Quantity)}
+		units += l.Quantity
+	}
+
+	meta := [][2]string{
+		{"Order", d.OrderRef},
+		{"Order date", d.OrderedAt.Format("02 Jan 2006")},
+	}
+
+	p := newPDF()
+	y := drawHeading(p, "PACKING SLIP", meta)
+	y = drawParties(p, y, []labelledParty{{"From", d.Seller}, {"Ship to", d.ShipTo}})
+	y = drawTable(p, y, "PACKING SLIP", meta, columns, rows)
+
+	if y+2*rowHeight > pageHeight-footerSpace {
+		p.AddPage()
+		y = drawHeading(p, "PACKING SLIP", meta)
+	}
+	y += rowHeight
+	p.TextRight(pageWidth-margin, y, bodySize, true, fmt.Sprintf("%d item(s) in %d line(s)", units, len(d.Lines)))
+
+	drawFooters(p, d.Seller.Name+" - "+d.OrderRef)
+	return p.Bytes()
+}
+
+// drawHeading starts a page with the title on the left and the reference block on the right
+func drawHeading(p *pdf, title string, meta [][2]string) float64 {
+	p.AddPage()
+	p.Text(margin, margin+20, 22, true, title)
+
+	y := margin + 8
+	for _, m := range meta {
+		p.TextRight(pageWidth-margin-110, y, bodySize, true, m[0])
+		p.TextRight(pageWidth-margin, y, bodySize, false, m[1])
+		y += 13
+	}
+	return math.Max(y, margin+40) + 20
+}
+
+type labelledParty struct {
+	label string
+	party Party
+}
+
+// drawParties prints the address blocks side by side
+func drawParties(p *pdf, y float64, parties []labelledParty) float64 {
+	width := (pageWidth - 2*margin) / float64(len(parties))
+	bottom := y
+	for i, lp := range parties {
+		x := margin + float64(i)*width
+		p.Text(x, y, 8, true, strings.ToUpper(lp.label))
+		ly := y + 14
+		p.Text(x, ly, bodySize, true, truncateText(lp.party.Name, width-10, bodySize, true))
+		for _, line := range lp.party.Lines {
+			ly += 12
+			p.Text(x, ly, bodySize, false, truncateText(line, width-10, bodySize, false))
+		}
+		bottom = math.Max(bottom, ly)
+	}
+	return bottom + 28
+}
+
+// drawTable prints rows under a shaded header, repeating the page heading and the table header on new pages
+func drawTable(p *pdf, y float64, title string, meta [][2]string, columns []column, rows [][]string) float64 {
+	header := func(y float64) float64 {
+		p.FillRect(margin-4, y-12, pageWidth-2*margin+8, rowHeight+2, 0.92)
+		drawRow(p, y, columns, titles(columns), true)
+		return y + rowHeight + 4
+	}
+
+	y = header(y)
+	for _, row := range rows {
+		if y > pageHeight-footerSpace {
+			y = header(drawHeading(p, title, meta))
+		}
+		drawRow(p, y, columns, row, false)
+		y += rowHeight
+	}
+	return y
+}
+
+func drawRow(p *pdf, y float64, columns []column, values []string, bold bool) {
+	for i, col := range columns {
+		if i >= len(values) {
+			break
+		}
+		if col.right {
+			p.TextRight(col.x, y, bodySize, bold, values[i])
+		} else {
+			p.Text(col.x, y, bodySize, bold, truncateText(values[i], col.width, bodySize, bold))
+		}
+	}
+}
+
+func titles(columns []column) []string {
+	t := make([]string, len(columns))
+	for i, col := range columns {
+		t[i] = col.title
+	}
+	return t
+}
+
+// drawFooters adds "label / page x of n" to every page
+func drawFooters(p *pdf, label string) {
+	n := p.PageCount()
+	for i := 0; i < n; i++ {
+		p.SetPage(i)
+		p.Line(margin, pageHeight-margin+10, pageWidth-margin, pageHeight-margin+10, 0.5)
+		p.Text(margin, pageHeight-margin+24, 8, false, label)
+		p.TextRight(pageWidth-margin, pageHeight-margin+24, 8, false, fmt.Sprintf("Page %d of %d", i+1, n))
+	}
+}
+
+// formatAmount formats a money amount with two decimals and thousands separators
+func formatAmount(f float64) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', 2, 64)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+
+	var sb strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(c)
+	}
+	if f < 0 {
+		return "-" + sb.String() + frac
+	}
+	return sb.String() + frac
+}
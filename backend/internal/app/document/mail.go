@@ -0,0 +1,89 @@
+package document
+
+import (
+	"backend/pkg/utils"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// SendOrderConfirmation emails the customer a summary of the order, with the invoice
+// PDF attached when attachInvoice is set
+func (s *Service) SendOrderConfirmation(orderID uint, attachInvoice bool) error {
+	order, err := s.Order(orderID)
+	if err != nil {
+		return err
+	}
+
+	to := order.User.Email
+	if to == "" {
+		to = order.ShippingAddress.Email
+	}
+	if to == "" {
+		return fmt.Errorf("order %d has no email address", orderID)
+	}
+
+	var attachments []utils.Attachment
+	if attachInvoice {
+		data, invoice, err := s.InvoicePDF(order)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, utils.Attachment{
+			Filename:    invoice.Number + ".pdf",
+			ContentType: "application/pdf",
+			Data:        data,
+		})
+	}
+
+	doc := s.document(order)
+	var rows strings.Builder
+	subtotal := 0.0
+	for _, line := range doc.Lines {
+		subtotal += line.Amount
+		fmt.Fprintf(&rows, `<tr><td style="padding:6px 8px;border-bottom:1px solid #eee">%s</td>`+
+			`<td style="padding:6px 8px;border-bottom:1px solid #eee;text-align:right">%d</td>`+
+			`<td style="padding:6px 8px;border-bottom:1px solid #eee;text-align:right">%s</td></tr>`,
+			html.EscapeString(line.Name), line.Quantity, formatAmount(line.Amount))
+	}
+
+	name := order.User.Name
+	if name == "" {
+		name = to
+	}
+
+	body := fmt.Sprintf(`<div style="font-family:Arial,sans-serif;max-width:600px;margin:0 auto">
+<h2>Thank you for your order!</h2>
+<p>Hi %s,</p>
+<p>We have received your order <strong>%s</strong> placed on %s.</p>
+<table style="width:100%%;border-collapse:collapse">
+<tr><th style="text-align:left;padding:6px 8px">Item</th><th style="text-align:right;padding:6px 8px">Qty</th><th style="text-align:right;padding:6px 8px">Amount (%s)</th></tr>
+%s
+<tr><td colspan="2" style="padding:6px 8px;text-align:right"><strong>Subtotal</strong></td><td style="padding:6px 8px;text-align:right"><strong>%s</strong></td></tr>
+</table>
+%s
+</div>`,
+		html.EscapeString(name),
+		html.EscapeString(doc.OrderRef),
+		doc.OrderedAt.Format("02 Jan 2006"),
+		html.EscapeString(s.Currency),
+		rows.String(),
+		formatAmount(subtotal),
+		invoiceNote(attachInvoice))
+
+	return utils.SendEmailWithAttachments([]string{to}, "Order confirmation "+doc.OrderRef, body, attachments)
+}
+
+func invoiceNote(attached bool) string {
+	if attached {
+		return "<p>Your invoice is attached to this email.</p>"
+	}
+	return "<p>You can download your invoice from your order history.</p>"
+}
+
+// AttachInvoiceFromEnv parses ORDER_EMAIL_ATTACH_INVOICE; invoices are attached unless it is set to false
+func AttachInvoiceFromEnv(value string) bool {
+	attach, err := strconv.ParseBool(value)
+	return err != nil || attach
+}
@@ -0,0 +1,212 @@
+package document
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrOrderNotFound is returned for unknown carts and carts that were never checked out
+var ErrOrderNotFound = errors.New("order not found")
+
+// Service turns completed orders into invoices and packing slips
+type Service struct {
+	CartRepo    repository.CartRepository
+	InvoiceRepo repository.InvoiceRepository
+
+	Seller   Party
+	TaxRate  float64
+	Currency string
+}
+
+func NewService(cartRepo repository.CartRepository, invoiceRepo repository.InvoiceRepository, seller Party, taxRate float64, currency string) *Service {
+	if currency == "" {
+		currency = "USD"
+	}
+	return &Service{
+		CartRepo:    cartRepo,
+		InvoiceRepo: invoiceRepo,
+		Seller:      seller,
+		TaxRate:     taxRate,
+		Currency:    strings.ToUpper(currency),
+	}
+}
+
+// SellerFromEnv reads the seller block from SELLER_NAME, SELLER_ADDRESS (lines separated
+// by "|"), SELLER_EMAIL and SELLER_TAX_ID
+func SellerFromEnv() Party {
+	seller := Party{Name: os.Getenv("SELLER_NAME")}
+	if seller.Name == "" {
+		seller.Name = "Hidden Score"
+	}
+	for _, line := range strings.Split(os.Getenv("SELLER_ADDRESS"), "|") {
+		if line = strings.TrimSpace(line); line != "" {
+			seller.Lines = append(seller.Lines, line)
+		}
+	}
+
+	email := os.Getenv("SELLER_EMAIL")
+	if email == "" {
+		email = os.Getenv("EMAIL")
+	}
+	if email != "" {
+		seller.Lines = append(seller.Lines, email)
+	}
+	if taxID := os.Getenv("SELLER_TAX_ID"); taxID != "" {
+		seller.Lines = append(seller.Lines, "Tax ID: "+taxID)
+	}
+	return seller
+}
+
+// Order loads a completed order with its user and items
+func (s *Service) Order(orderID uint) (*entity.Cart, error) {
+	order, err := s.CartRepo.GetCartWithItems(orderID)
+	if err != nil || order == nil || order.Active {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// IssueInvoice returns the order's invoice, issuing it with the next invoice number on first use.
+// Amounts use the prices captured at checkout and the tax rate in effect when the invoice is issued.
+func (s *Service) IssueInvoice(order *entity.Cart) (*entity.Invoice, error) {
+	invoice, err := s.InvoiceRepo.FindByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice != nil {
+		return invoice, nil
+	}
+
+	subtotal := 0.0
+	for _, line := range orderLines(order) {
+		subtotal += line.Amount
+	}
+	tax := roundMoney(subtotal * s.TaxRate)
+
+	invoice = &entity.Invoice{
+		OrderID:  order.ID,
+		UserID:   order.UserID,
+		IssuedAt: time.Now(),
+		Currency: s.Currency,
+		Subtotal: roundMoney(subtotal),
+		TaxRate:  s.TaxRate,
+		Tax:      tax,
+		Total:    roundMoney(subtotal + tax),
+	}
+	return s.InvoiceRepo.Issue(invoice)
+}
+
+// InvoicePDF issues the order's invoice if needed and renders it
+func (s *Service) InvoicePDF(order *entity.Cart) ([]byte, *entity.Invoice, error) {
+	invoice, err := s.IssueInvoice(order)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue invoice: %w", err)
+	}
+
+	doc := s.document(order)
+	doc.InvoiceNumber = invoice.Number
+	doc.IssuedAt = invoice.IssuedAt
+	doc.Currency = invoice.Currency
+	doc.Subtotal = invoice.Subtotal
+	doc.TaxRate = invoice.TaxRate
+	doc.Tax = invoice.Tax
+	doc.Total = invoice.Total
+
+	data, err := RenderInvoice(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render invoice: %w", err)
+	}
+	return data, invoice, nil
+}
+
+// PackingSlipPDF renders the order's packing slip
+func (s *Service) PackingSlipPDF(order *entity.Cart) ([]byte, error) {
+	data, err := RenderPackingSlip(s.document(order))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render packing slip: %w", err)
+	}
+	return data, nil
+}
+
+// OrderRef is how an order is referred to in documents and emails: its order number,
+// or its ID for orders that have none yet
+func OrderRef(order *entity.Cart) string {
+	if order.OrderNumber != nil && *order.OrderNumber != "" {
+		return *order.OrderNumber
+	}
+	return "#" + strconv.FormatUint(uint64(order.ID), 10)
+}
+
+// OrderedAt is when the order was placed; older orders only have updated_at
+func OrderedAt(order *entity.Cart) time.Time {
+	if order.OrderedAt != nil {
+		return *order.OrderedAt
+	}
+	return order.UpdatedAt
+}
+
+func (s *Service) document(order *entity.Cart) *OrderDocument {
+	buyer := Party{Name: order.User.Name, Lines: []string{order.User.Email}}
+	if buyer.Name == "" {
+		buyer.Name = order.User.Email
+		buyer.Lines = nil
+	}
+
+	shipTo := buyer
+	if addr := order.ShippingAddress; !addr.IsEmpty() {
+		shipTo = Party{Name: addr.FullName}
+		if shipTo.Name == "" {
+			shipTo.Name = buyer.Name
+		}
+		for _, line := range []string{addr.Address, strings.TrimSpace(addr.PostalCode + " " + addr.City), addr.Country} {
+			if line != "" {
+				shipTo.Lines = append(shipTo.Lines, line)
+			}
+		}
+	}
+
+	return &OrderDocument{
+		OrderRef:  OrderRef(order),
+		OrderedAt: OrderedAt(order),
+		Seller:    s.Seller,
+		Buyer:     buyer,
+		ShipTo:    shipTo,
+		Lines:     orderLines(order),
+		Currency:  s.Currency,
+	}
+}
+
+// orderLines lists the order's items at the price captured at checkout, falling back to
+// the current price for orders placed before prices were captured
+func orderLines(order *entity.Cart) []Line {
+	lines := make([]Line, 0, len(order.CartItems))
+	for _, item := range order.CartItems {
+		price := item.UnitPrice
+		if price <= 0 {
+			price = item.Product.Price
+		}
+		sku := ""
+		if item.Product.SKU != nil {
+			sku = *item.Product.SKU
+		}
+		lines = append(lines, Line{
+			Name:      item.Product.Name,
+			SKU:       sku,
+			Quantity:  item.Quantity,
+			UnitPrice: price,
+			Amount:    roundMoney(float64(item.Quantity) * price),
+		})
+	}
+	return lines
+}
+
+func roundMoney(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,230 @@
+package export
+
+import (
+	"backend/internal/domain/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+const (
+	// jobTTL is how long a finished export stays downloadable
+	jobTTL = 24 * time.Hour
+	// maxRunningJobs caps how many exports are written at the same time
+	maxRunningJobs = 2
+)
+
+// ErrJobNotFound is returned for unknown or expired jobs
+var ErrJobNotFound = errors.New("export job not found")
+
+// ErrJobNotReady is returned when downloading a job that has not finished
+var ErrJobNotReady = errors.New("export job not finished")
+
+// Job is a report export running in the background
+type Job struct {
+	ID          string     `json:"id"`
+	Report      string     `json:"report"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	Filename    string     `json:"filename"`
+	RequestedBy uint       `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	path string
+}
+
+// Manager runs export jobs and keeps their files on local disk until they expire.
+// Job state lives in memory, so downloads must hit the instance that ran the job.
+type Manager struct {
+	Exporter *Exporter
+	Dir      string
+
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	slots chan struct{}
+}
+
+// NewManager stores finished exports in dir, which is created if needed
+func NewManager(exporter *Exporter, dir string) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	return &Manager{
+		Exporter: exporter,
+		Dir:      dir,
+		jobs:     map[string]*Job{},
+		slots:    make(chan struct{}, maxRunningJobs),
+	}, nil
+}
+
+// Start queues an export and returns its job immediately
+func (m *Manager) Start(report, format string, filter repository.ExportFilter, requestedBy uint) (Job, error) {
+	if !IsValidReport(report) {
+		return Job{}, ErrUnknownReport
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:          id,
+		Report:      report,
+		Format:      format,
+		Status:      JobPending,
+		Filename:    Filename(report, format, now),
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		path:        filepath.Join(m.Dir, id+"."+format),
+	}
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	snapshot := *job
+	m.mu.Unlock()
+
+	go m.run(job, filter)
+	return snapshot, nil
+}
+
+func (m *Manager) run(job *Job, filter repository.ExportFilter) {
+	m.slots <- struct{}{}
+	defer func() { <-m.slots }()
+
+	m.update(job, func(j *Job) { j.Status = JobRunning })
+
+	rows, err := m.write(job, filter)
+
+	m.update(job, func(j *Job) {
+		now := time.Now()
+		expires := now.Add(jobTTL)
+		j.FinishedAt = &now
+		j.ExpiresAt = &expires
+		j.Rows = rows
+		if err != nil {
+			j.Status = JobFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = JobDone
+	})
+
+	if err != nil {
+		log.Printf("[EXPORT] Job %s (%s) failed: %v", job.ID, job.Report, err)
+		os.Remove(job.path)
+		return
+	}
+	log.Printf("[EXPORT] Job %s (%s) finished with %d rows", job.ID, job.Report, rows)
+}
+
+func (m *Manager) write(job *Job, filter repository.ExportFilter) (int, error) {
+	f, err := os.OpenFile(job.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := m.Exporter.Write(f, job.Report, job.Format, filter)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return rows, err
+}
+
+func (m *Manager) update(job *Job, fn func(j *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(job)
+}
+
+// Get returns a snapshot of a job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// List returns every job that has not expired yet, newest first
+func (m *Manager) List() []Job {
+	m.mu.RLock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Open returns the finished export file of a job; the caller closes it
+func (m *Manager) Open(id string) (*os.File, Job, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return nil, job, err
+	}
+	if job.Status != JobDone {
+		return nil, job, ErrJobNotReady
+	}
+
+	f, err := os.Open(job.path)
+	if err != nil {
+		return nil, job, err
+	}
+	return f, job, nil
+}
+
+// Cleanup removes expired jobs and their files. It is run by a background job.
+func (m *Manager) Cleanup() error {
+	now := time.Now()
+
+	m.mu.Lock()
+	var expired []*Job
+	for id, job := range m.jobs {
+		if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+			expired = append(expired, job)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, job := range expired {
+		if err := os.Remove(job.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[EXPORT] Failed to remove %s: %v", job.path, err)
+		}
+	}
+	return nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
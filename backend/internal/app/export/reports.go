@@ -0,0 +1,282 @@
+package export
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report names
+const (
+	ReportOrders   = "orders"
+	ReportUsers    = "users"
+	ReportProducts = "products"
+)
+
+const batchSize = 500
+
+// ErrUnknownReport is returned for a report name that is not orders, users or products
+var ErrUnknownReport = errors.New("unknown report")
+
+// IsValidReport reports whether name is a known report
+func IsValidReport(name string) bool {
+	return name == ReportOrders || name == ReportUsers || name == ReportProducts
+}
+
+var orderColumns = []Column{
+	{Name: "order_id", Numeric: true},
+	{Name: "order_number"},
+	{Name: "ordered_at"},
+	{Name: "status", Numeric: true},
+	{Name: "user_id", Numeric: true},
+	{Name: "user_email"},
+	{Name: "user_name"},
+	{Name: "item_id", Numeric: true},
+	{Name: "product_id", Numeric: true},
+	{Name: "sku"},
+	{Name: "product_name"},
+	{Name: "quantity", Numeric: true},
+	{Name: "unit_price", Numeric: true},
+	{Name: "line_total", Numeric: true},
+	{Name: "order_total", Numeric: true},
+}
+
+var userColumns = []Column{
+	{Name: "id", Numeric: true},
+	{Name: "email"},
+	{Name: "name"},
+	{Name: "role"},
+	{Name: "status"},
+	{Name: "identity_providers"},
+	{Name: "created_at"},
+}
+
+var productColumns = []Column{
+	{Name: "id", Numeric: true},
+	{Name: "sku"},
+	{Name: "slug"},
+	{Name: "name"},
+	{Name: "status"},
+	{Name: "price", Numeric: true},
+	{Name: "stock", Numeric: true},
+	{Name: "reorder_threshold", Numeric: true},
+	{Name: "created_at"},
+	{Name: "updated_at"},
+}
+
+// Exporter writes the admin reports from the repositories
+type Exporter struct {
+	ProductRepo repository.ProductRepository
+	UserRepo    repository.UserRepository
+	CartRepo    repository.CartRepository
+}
+
+func NewExporter(productRepo repository.ProductRepository, userRepo repository.UserRepository, cartRepo repository.CartRepository) *Exporter {
+	return &Exporter{
+		ProductRepo: productRepo,
+		UserRepo:    userRepo,
+		CartRepo:    cartRepo,
+	}
+}
+
+// Count returns how many records (orders, users or products) a report covers
+func (e *Exporter) Count(report string, filter repository.ExportFilter) (int64, error) {
+	switch report {
+	case ReportOrders:
+		return e.CartRepo.CountOrdersForExport(filter)
+	case ReportUsers:
+		return e.UserRepo.CountUsersForExport(filter)
+	case ReportProducts:
+		return e.ProductRepo.CountProductsForExport(filter)
+	}
+	return 0, ErrUnknownReport
+}
+
+// Write streams a report to w and returns the number of data rows written
+func (e *Exporter) Write(w io.Writer, report, format string, filter repository.ExportFilter) (int, error) {
+	var columns []Column
+	switch report {
+	case ReportOrders:
+		columns = orderColumns
+	case ReportUsers:
+		columns = userColumns
+	case ReportProducts:
+		columns = productColumns
+	default:
+		return 0, ErrUnknownReport
+	}
+
+	tw, err := newTableWriter(w, format, sheetName(report), columns)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	emit := func(values []string) error {
+		rows++
+		return tw.WriteRow(values)
+	}
+
+	switch report {
+	case ReportOrders:
+		err = e.CartRepo.StreamOrders(filter, batchSize, func(orders []entity.Cart) error {
+			for _, order := range orders {
+				if err := writeOrder(order, emit); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case ReportUsers:
+		err = e.UserRepo.StreamUsers(filter, batchSize, func(users []entity.User) error {
+			for _, u := range users {
+				if err := emit(userRecord(u)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case ReportProducts:
+		err = e.ProductRepo.StreamProductsForExport(filter, batchSize, func(products []entity.Product) error {
+			for _, p := range products {
+				if err := emit(productRecord(p)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return rows, fmt.Errorf("failed to export %s: %w", report, err)
+	}
+
+	return rows, tw.Close()
+}
+
+// Filename returns the download name of a report, e.g. orders-20260102.xlsx
+func Filename(report, format string, at time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", report, at.Format("20060102"), format)
+}
+
+func sheetName(report string) string {
+	switch report {
+	case ReportOrders:
+		return "Orders"
+	case ReportUsers:
+		return "Users"
+	default:
+		return "Products"
+	}
+}
+
+// writeOrder emits one row per order line; the order columns repeat on every line
+func writeOrder(order entity.Cart, emit func([]string) error) error {
+	orderedAt := order.UpdatedAt
+	if order.OrderedAt != nil {
+		orderedAt = *order.OrderedAt
+	}
+
+	total := 0.0
+	for _, item := range order.CartItems {
+		total += float64(item.Quantity) * unitPrice(item)
+	}
+
+	number := ""
+	if order.OrderNumber != nil {
+		number = *order.OrderNumber
+	}
+
+	for _, item := range order.CartItems {
+		price := unitPrice(item)
+		sku := ""
+		if item.Product.SKU != nil {
+			sku = *item.Product.SKU
+		}
+		record := []string{
+			formatUint(order.ID),
+			number,
+			formatTime(orderedAt),
+			strconv.Itoa(order.Status),
+			formatUint(order.UserID),
+			order.User.Email,
+			order.User.Name,
+			formatUint(item.ID),
+			formatUint(item.ProductID),
+			sku,
+			item.Product.Name,
+			strconv.Itoa(item.Quantity),
+			formatMoney(price),
+			formatMoney(float64(item.Quantity) * price),
+			formatMoney(total),
+		}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unitPrice is the price captured at checkout, or the current price for orders placed before prices were captured
+func unitPrice(item entity.CartItem) float64 {
+	if item.UnitPrice > 0 {
+		return item.UnitPrice
+	}
+	return item.Product.Price
+}
+
+func userRecord(u entity.User) []string {
+	return []string{
+		formatUint(u.ID),
+		u.Email,
+		u.Name,
+		u.Role,
+		u.Status,
+		identityProviders(u.Identities),
+		formatTime(u.CreatedAt),
+	}
+}
+
+// identityProviders lists the providers the user signs in with, separated by semicolons
+func identityProviders(identities []entity.UserIdentity) string {
+	names := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		names = append(names, identity.Provider)
+	}
+	return strings.Join(names, ";")
+}
+
+func productRecord(p entity.Product) []string {
+	sku := ""
+	if p.SKU != nil {
+		sku = *p.SKU
+	}
+	return []string{
+		formatUint(p.ID),
+		sku,
+		p.Slug,
+		p.Name,
+		p.Status,
+		formatMoney(p.Price),
+		strconv.Itoa(p.Stock),
+		strconv.Itoa(p.ReorderThreshold),
+		formatTime(p.CreatedAt),
+		formatTime(p.UpdatedAt),
+	}
+}
+
+func formatUint(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+func formatMoney(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Supported export formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// IsValidFormat reports whether f is a supported export format
+func IsValidFormat(f string) bool {
+	return f == FormatCSV || f == FormatXLSX
+}
+
+// ContentType returns the MIME type of an export format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Column describes one column of a report. Numeric columns are written as numbers in XLSX.
+type Column struct {
+	Name    string
+	Numeric bool
+}
+
+// tableWriter writes a report row by row in one output format
+type tableWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+func newTableWriter(w io.Writer, format, sheet string, columns []Column) (tableWriter, error) {
+	var tw tableWriter
+	if format == FormatXLSX {
+		xw, err := newXLSXWriter(w, sheet, columns)
+		if err != nil {
+			return nil, err
+		}
+		tw = xw
+	} else {
+		tw = &csvWriter{w: csv.NewWriter(w), columns: columns}
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	if err := tw.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+}
+
+// WriteRow writes a row, defusing text cells that a spreadsheet would run as a formula. Numeric
+// columns are formatted by us and keep their sign.
+func (c *csvWriter) WriteRow(values []string) error {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		if i < len(c.columns) && c.columns[i].Numeric {
+			cells[i] = value
+			continue
+		}
+		cells[i] = NeutralizeFormula(value)
+	}
+	return c.w.Write(cells)
+}
+
+// NeutralizeFormula prefixes a quote to values starting with a character that makes spreadsheets
+// read the cell as a formula, e.g. a product named "=HYPERLINK(...)"
+func NeutralizeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
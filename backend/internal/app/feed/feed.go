@@ -0,0 +1,204 @@
+package feed
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the generated documents
+const (
+	Sitemap         = "sitemap.xml"
+	MerchantFeedXML = "products.xml"
+	MerchantFeedCSV = "products.csv"
+)
+
+// Document is a generated file ready to be served
+type Document struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// versionTTL is how long a read catalog version is reused, so busy feeds don't query it on every request
+const versionTTL = 30 * time.Second
+
+// Generator builds the sitemap and product feeds and caches them until the catalog changes
+type Generator struct {
+	ProductRepo repository.ProductRepository
+	SiteURL     string
+	Currency    string
+
+	// mu guards the documents; it is only held exclusively while they are rebuilt
+	mu      sync.RWMutex
+	version string
+	docs    map[string]*Document
+
+	versionMu     sync.Mutex
+	latestVersion string
+	versionReadAt time.Time
+}
+
+func NewGenerator(productRepo repository.ProductRepository, siteURL, currency string) *Generator {
+	if currency == "" {
+		currency = "USD"
+	}
+	return &Generator{
+		ProductRepo: productRepo,
+		SiteURL:     strings.TrimSuffix(siteURL, "/"),
+		Currency:    currency,
+	}
+}
+
+// Get returns the named document, regenerating every document first if the catalog has changed
+func (g *Generator) Get(name string) (*Document, error) {
+	version, err := g.currentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.RLock()
+	docs := g.docs
+	current := docs != nil && g.version == version
+	g.mu.RUnlock()
+
+	if !current {
+		g.mu.Lock()
+		// another request may have rebuilt the documents while this one waited
+		if g.docs == nil || g.version != version {
+			if err := g.generate(version); err != nil {
+				g.mu.Unlock()
+				return nil, err
+			}
+		}
+		docs = g.docs
+		g.mu.Unlock()
+	}
+
+	doc, ok := docs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown document %q", name)
+	}
+	return doc, nil
+}
+
+// Regenerate rebuilds every document unconditionally. Used by the scheduled job.
+func (g *Generator) Regenerate() error {
+	version, err := g.catalogVersion()
+	if err != nil {
+		return err
+	}
+	g.rememberVersion(version)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.generate(version)
+}
+
+// currentVersion returns the catalog version, reading it from the database at most once per versionTTL.
+// The query runs without holding a lock.
+func (g *Generator) currentVersion() (string, error) {
+	g.versionMu.Lock()
+	if g.latestVersion != "" && time.Since(g.versionReadAt) < versionTTL {
+		version := g.latestVersion
+		g.versionMu.Unlock()
+		return version, nil
+	}
+	g.versionMu.Unlock()
+
+	version, err := g.catalogVersion()
+	if err != nil {
+		return "", err
+	}
+	g.rememberVersion(version)
+	return version, nil
+}
+
+func (g *Generator) rememberVersion(version string) {
+	g.versionMu.Lock()
+	defer g.versionMu.Unlock()
+	g.latestVersion = version
+	g.versionReadAt = time.Now()
+}
+
+func (g *Generator) catalogVersion() (string, error) {
+	lastUpdated, total, err := g.ProductRepo.CatalogVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to read catalog version: %w", err)
+	}
+	return fmt.Sprintf("%d-%d", lastUpdated.UnixNano(), total), nil
+}
+
+func (g *Generator) generate(version string) error {
+	var products []entity.Product
+	if err := g.ProductRepo.GetAllProducts(&products); err != nil {
+		return fmt.Errorf("failed to load products: %w", err)
+	}
+
+	lastModified := time.Time{}
+	for _, p := range products {
+		if p.UpdatedAt.After(lastModified) {
+			lastModified = p.UpdatedAt
+		}
+	}
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
+
+	sitemap, err := g.buildSitemap(products)
+	if err != nil {
+		return err
+	}
+	feedXML, err := g.buildMerchantXML(products)
+	if err != nil {
+		return err
+	}
+	feedCSV, err := g.buildMerchantCSV(products)
+	if err != nil {
+		return err
+	}
+
+	g.docs = map[string]*Document{
+		Sitemap:         newDocument(sitemap, "application/xml; charset=utf-8", lastModified),
+		MerchantFeedXML: newDocument(feedXML, "application/xml; charset=utf-8", lastModified),
+		MerchantFeedCSV: newDocument(feedCSV, "text/csv; charset=utf-8", lastModified),
+	}
+	g.version = version
+
+	log.Printf("[FEED] Generated sitemap and product feeds for %d products", len(products))
+	return nil
+}
+
+func newDocument(body []byte, contentType string, lastModified time.Time) *Document {
+	sum := sha256.Sum256(body)
+	return &Document{
+		Body:         body,
+		ContentType:  contentType,
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: lastModified.UTC().Truncate(time.Second),
+	}
+}
+
+// productURL returns the absolute storefront URL of a product
+func (g *Generator) productURL(p entity.Product) string {
+	path := p.CanonicalPath
+	if path == "" {
+		path = fmt.Sprintf("/products/detail/%d", p.ID)
+	}
+	return g.SiteURL + path
+}
+
+// absoluteURL makes relative image paths absolute against the site URL
+func (g *Generator) absoluteURL(raw string) string {
+	if raw == "" || strings.HasPrefix(raw, "http://") || strings.HasPrefix(raw, "https://") {
+		return raw
+	}
+	return g.SiteURL + "/" + strings.TrimPrefix(raw, "/")
+}
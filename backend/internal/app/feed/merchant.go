@@ -0,0 +1,108 @@
+package feed
+
+import (
+	"backend/internal/domain/entity"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// merchantItem follows the Google Merchant Center product data specification
+type merchantItem struct {
+	ID           string `xml:"g:id"`
+	Title        string `xml:"g:title"`
+	Description  string `xml:"g:description"`
+	Link         string `xml:"g:link"`
+	ImageLink    string `xml:"g:image_link,omitempty"`
+	Price        string `xml:"g:price"`
+	Availability string `xml:"g:availability"`
+	Condition    string `xml:"g:condition"`
+}
+
+type merchantRSS struct {
+	XMLName xml.Name        `xml:"rss"`
+	Version string          `xml:"version,attr"`
+	XmlnsG  string          `xml:"xmlns:g,attr"`
+	Channel merchantChannel `xml:"channel"`
+}
+
+type merchantChannel struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description"`
+	Items       []merchantItem `xml:"item"`
+}
+
+// merchantColumns is the header of the CSV feed, using the Merchant Center attribute names
+var merchantColumns = []string{"id", "title", "description", "link", "image_link", "price", "availability", "condition"}
+
+func (g *Generator) merchantItem(p entity.Product) merchantItem {
+	id := strconv.FormatUint(uint64(p.ID), 10)
+	if p.SKU != nil && *p.SKU != "" {
+		id = *p.SKU
+	}
+
+	availability := "in_stock"
+	if p.Stock <= 0 {
+		availability = "out_of_stock"
+	}
+
+	return merchantItem{
+		ID:           id,
+		Title:        p.Name,
+		Description:  p.Description,
+		Link:         g.productURL(p),
+		ImageLink:    g.absoluteURL(p.ImageURL),
+		Price:        fmt.Sprintf("%.2f %s", p.Price, g.Currency),
+		Availability: availability,
+		Condition:    "new",
+	}
+}
+
+// buildMerchantXML renders the product feed as an RSS 2.0 document
+func (g *Generator) buildMerchantXML(products []entity.Product) ([]byte, error) {
+	feed := merchantRSS{
+		Version: "2.0",
+		XmlnsG:  "http://base.google.com/ns/1.0",
+		Channel: merchantChannel{
+			Title:       "Hidden Score products",
+			Link:        g.SiteURL,
+			Description: "Hidden Score product catalog",
+		},
+	}
+
+	for _, p := range products {
+		feed.Channel.Items = append(feed.Channel.Items, g.merchantItem(p))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildMerchantCSV renders the product feed as CSV
+func (g *Generator) buildMerchantCSV(products []entity.Product) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(merchantColumns); err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		item := g.merchantItem(p)
+		record := []string{item.ID, item.Title, item.Description, item.Link, item.ImageLink, item.Price, item.Availability, item.Condition}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,63 @@
+package feed
+
+import (
+	"backend/internal/domain/entity"
+	"bytes"
+	"encoding/xml"
+	"log"
+)
+
+// maxSitemapURLs is the limit set by the sitemap protocol for a single file
+const maxSitemapURLs = 50000
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// staticPages are the public storefront pages listed in the sitemap besides products
+var staticPages = []sitemapURL{
+	{Loc: "/", ChangeFreq: "daily", Priority: "1.0"},
+	{Loc: "/products", ChangeFreq: "daily", Priority: "0.9"},
+}
+
+// buildSitemap lists the public pages and every active product.
+// There are no product categories in the catalog yet, so none are listed.
+func (g *Generator) buildSitemap(products []entity.Product) ([]byte, error) {
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	for _, page := range staticPages {
+		page.Loc = g.SiteURL + page.Loc
+		set.URLs = append(set.URLs, page)
+	}
+
+	for _, p := range products {
+		if len(set.URLs) >= maxSitemapURLs {
+			log.Printf("[FEED] Sitemap truncated at %d URLs", maxSitemapURLs)
+			break
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:        g.productURL(p),
+			LastMod:    p.UpdatedAt.UTC().Format("2006-01-02"),
+			ChangeFreq: "weekly",
+			Priority:   "0.8",
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
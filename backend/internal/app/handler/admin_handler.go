@@ -3,6 +3,7 @@ package handler
 import (
 	"backend/internal/domain/entity"
 	"backend/internal/domain/repository"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -111,10 +112,28 @@ func (h *AdminHandler) GetDashboardStats(c *gin.Context) {
 		return
 	}
 
+	lowStockCount, err := h.ProductRepo.CountLowStockProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get low-stock count: " + err.Error(),
+		})
+		return
+	}
+
+	outOfStockCount, err := h.ProductRepo.CountOutOfStockProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get out-of-stock count: " + err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"user_count":    userCount,
-		"product_count": productCount,
-		"order_count":   orderCount,
+		"user_count":         userCount,
+		"product_count":      productCount,
+		"order_count":        orderCount,
+		"low_stock_count":    lowStockCount,
+		"out_of_stock_count": outOfStockCount,
 	})
 }
 
@@ -204,6 +223,10 @@ func (h *AdminHandler) CreateProduct(c *gin.Context) {
 		Price       float64 `json:"price" binding:"required"`
 		ImageURL    string  `json:"image_url" binding:"required"`
 		Stock       int     `json:"stock" binding:"required"`
+		SKU         string  `json:"sku"`
+		Status      string  `json:"status"`
+		// ReorderThreshold is optional and defaults to 0 (alert only when out of stock)
+		ReorderThreshold int `json:"reorder_threshold" binding:"min=0"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -213,15 +236,40 @@ func (h *AdminHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	if input.Status == "" {
+		input.Status = entity.ProductStatusActive
+	}
+	if input.Status != entity.ProductStatusActive && input.Status != entity.ProductStatusDraft {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid status. New products must be 'draft' or 'active'",
+		})
+		return
+	}
+
+	// Stock starts at zero and the initial quantity goes through the ledger
 	product := entity.Product{
-		Name:        input.Name,
-		Description: input.Description,
-		Price:       input.Price,
-		ImageURL:    input.ImageURL,
-		Stock:       input.Stock,
+		Name:             input.Name,
+		Description:      input.Description,
+		Price:            input.Price,
+		ImageURL:         input.ImageURL,
+		Status:           input.Status,
+		ReorderThreshold: input.ReorderThreshold,
+	}
+	if input.SKU != "" {
+		product.SKU = &input.SKU
+	}
+
+	var initialStock *entity.StockMovement
+	if input.Stock > 0 {
+		initialStock = &entity.StockMovement{
+			Type:     entity.StockMovementRestock,
+			Quantity: input.Stock,
+			ActorID:  currentUserID(c),
+			Reason:   "initial stock",
+		}
 	}
 
-	createdProduct, err := h.ProductRepo.CreateProduct(product)
+	createdProduct, err := h.ProductRepo.CreateProduct(product, initialStock)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create product: " + err.Error(),
@@ -250,7 +298,11 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 		Description string  `json:"description"`
 		Price       float64 `json:"price"`
 		ImageURL    string  `json:"image_url"`
-		Stock       int     `json:"stock"`
+		SKU         string  `json:"sku"`
+		Stock       *int    `json:"stock"`
+		StockReason string  `json:"stock_reason"`
+		// ReorderThreshold is a pointer so that 0 can be set explicitly
+		ReorderThreshold *int `json:"reorder_threshold"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -260,6 +312,20 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if input.Stock != nil && *input.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Stock cannot be negative",
+		})
+		return
+	}
+
+	if input.ReorderThreshold != nil && *input.ReorderThreshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Reorder threshold cannot be negative",
+		})
+		return
+	}
+
 	// Get current product
 	product, err := h.ProductRepo.FindByID(uint(productID))
 	if err != nil {
@@ -282,11 +348,33 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 	if input.ImageURL != "" {
 		product.ImageURL = input.ImageURL
 	}
-	if input.Stock >= 0 {
-		product.Stock = input.Stock
+	if input.SKU != "" {
+		product.SKU = &input.SKU
+	}
+	if input.ReorderThreshold != nil {
+		product.ReorderThreshold = *input.ReorderThreshold
+	}
+
+	// Stock changes are recorded as an adjustment instead of overwriting the value. The quantity
+	// is worked out against the locked row, so sales made meanwhile are not undone.
+	reason := input.StockReason
+	if reason == "" {
+		reason = "set via product update"
+	}
+	adjustment := entity.StockMovement{
+		Type:    entity.StockMovementAdjustment,
+		ActorID: currentUserID(c),
+		Reason:  reason,
 	}
 
-	updatedProduct, err := h.ProductRepo.UpdateProduct(*product)
+	updatedProduct, err := h.ProductRepo.UpdateProduct(*product, input.Stock, adjustment)
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Stock change would make stock negative",
+			"code":  "INSUFFICIENT_STOCK",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update product: " + err.Error(),
@@ -300,7 +388,31 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 	})
 }
 
-// DeleteProduct removes a product
+// GetLowStockProducts returns products at or below their reorder threshold
+func (h *AdminHandler) GetLowStockProducts(c *gin.Context) {
+	products, err := h.ProductRepo.FindLowStockProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get low-stock products: " + err.Error(),
+		})
+		return
+	}
+
+	outOfStock := 0
+	for _, p := range products {
+		if p.Stock <= 0 {
+			outOfStock++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products":           products,
+		"low_stock_count":    len(products),
+		"out_of_stock_count": outOfStock,
+	})
+}
+
+// DeleteProduct archives a product. It disappears from the storefront but carts and orders keep it.
 func (h *AdminHandler) DeleteProduct(c *gin.Context) {
 	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -319,7 +431,118 @@ func (h *AdminHandler) DeleteProduct(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Product deleted successfully",
+		"message": "Product archived successfully",
+	})
+}
+
+// GetProducts lists products for admins, optionally filtered by ?status=draft|active|archived
+func (h *AdminHandler) GetProducts(c *gin.Context) {
+	status := c.Query("status")
+
+	var products []entity.Product
+	var err error
+	switch {
+	case status == "":
+		products, err = h.ProductRepo.FindAll()
+	case status == entity.ProductStatusArchived:
+		products, err = h.ProductRepo.FindArchived()
+	case entity.IsValidProductStatus(status):
+		products, err = h.ProductRepo.FindByStatus(status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid status. Must be 'draft', 'active' or 'archived'",
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get products: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+	})
+}
+
+// GetArchivedProducts lists archived products, including ones soft-deleted before archiving existed
+func (h *AdminHandler) GetArchivedProducts(c *gin.Context) {
+	products, err := h.ProductRepo.FindArchived()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get archived products: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+	})
+}
+
+// UpdateProductStatus moves a product between draft, active and archived
+func (h *AdminHandler) UpdateProductStatus(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var input struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if !entity.IsValidProductStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid status. Must be 'draft', 'active' or 'archived'",
+		})
+		return
+	}
+
+	if err := h.ProductRepo.UpdateStatus(uint(productID), input.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update product status: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product status updated successfully",
+	})
+}
+
+// RestoreProduct makes an archived or soft-deleted product active again
+func (h *AdminHandler) RestoreProduct(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	product, err := h.ProductRepo.Restore(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Failed to restore product: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product restored successfully",
+		"product": product,
 	})
 }
 
@@ -327,7 +550,14 @@ func (h *AdminHandler) DeleteProduct(c *gin.Context) {
 
 // GetAllOrders returns a list of all orders
 func (h *AdminHandler) GetAllOrders(c *gin.Context) {
-	orders, err := h.CartRepo.GetAllCompletedCarts()
+	var orders []entity.Cart
+	var err error
+	// ?q= searches by order number, e.g. HS-2026-000123 or just 000123
+	if q := c.Query("q"); q != "" {
+		orders, err = h.CartRepo.SearchOrders(q)
+	} else {
+		orders, err = h.CartRepo.GetAllCompletedCarts()
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get orders: " + err.Error(),
@@ -340,17 +570,15 @@ func (h *AdminHandler) GetAllOrders(c *gin.Context) {
 	})
 }
 
-// GetOrderByID returns details of a specific order
+// GetOrderByID looks an order up by its ID or its order number
 func (h *AdminHandler) GetOrderByID(c *gin.Context) {
+	var order *entity.Cart
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid order ID",
-		})
-		return
+		order, err = h.CartRepo.FindOrderByNumber(c.Param("id"))
+	} else {
+		order, err = h.CartRepo.GetCartWithItems(uint(orderID))
 	}
-
-	order, err := h.CartRepo.GetCartWithItems(uint(orderID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Order not found",
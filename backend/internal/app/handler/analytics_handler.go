@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"backend/internal/domain/repository"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAnalyticsDays        = 30
+	defaultAnalyticsTopProducts = 10
+	// maxRevenueBuckets bounds the revenue series, about two years of daily periods
+	maxRevenueBuckets = 731
+)
+
+type AnalyticsHandler struct {
+	Repo repository.AnalyticsRepository
+}
+
+func NewAnalyticsHandler(repo repository.AnalyticsRepository) *AnalyticsHandler {
+	return &AnalyticsHandler{Repo: repo}
+}
+
+// GetRevenue returns revenue, order count and average order value per ?interval= (day, week
+// or month) between ?from= and ?to=. Periods without orders are included with zeros.
+func (h *AnalyticsHandler) GetRevenue(c *gin.Context) {
+	from, to, ok := parseDateRange(c, defaultAnalyticsDays)
+	if !ok {
+		return
+	}
+
+	interval := strings.ToLower(c.DefaultQuery("interval", repository.BucketDay))
+	if !repository.IsValidBucket(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid interval. Use day, week or month",
+			"code":  "INVALID_INTERVAL",
+		})
+		return
+	}
+
+	if countBuckets(from, to, interval, maxRevenueBuckets) > maxRevenueBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("The range covers more than %d periods. Choose a shorter range or a longer interval", maxRevenueBuckets),
+			"code":  "DATE_RANGE_TOO_LONG",
+		})
+		return
+	}
+
+	buckets, err := h.Repo.Revenue(from, to, interval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revenue: " + err.Error()})
+		return
+	}
+	series := fillBuckets(buckets, from, to, interval)
+
+	var revenue float64
+	var orders int64
+	for _, b := range series {
+		revenue += b.Revenue
+		orders += b.Orders
+	}
+	aov := 0.0
+	if orders > 0 {
+		aov = revenue / float64(orders)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format(dateLayout),
+		"to":       to.AddDate(0, 0, -1).Format(dateLayout),
+		"interval": interval,
+		"totals": gin.H{
+			"revenue": revenue,
+			"orders":  orders,
+			"aov":     aov,
+		},
+		"series": series,
+	})
+}
+
+// GetTopProducts returns the best performing products between ?from= and ?to=, by ?by=revenue (default) or units
+func (h *AnalyticsHandler) GetTopProducts(c *gin.Context) {
+	from, to, ok := parseDateRange(c, defaultAnalyticsDays)
+	if !ok {
+		return
+	}
+	limit := queryLimit(c, defaultAnalyticsTopProducts, 100)
+
+	by := strings.ToLower(c.DefaultQuery("by", "revenue"))
+	var products []repository.ProductRevenue
+	var err error
+	switch by {
+	case "revenue":
+		products, err = h.Repo.TopProductsByRevenue(from, to, limit)
+	case "units":
+		products, err = h.Repo.TopProductsByUnits(from, to, limit)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ranking. Use revenue or units",
+			"code":  "INVALID_RANKING",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top products: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format(dateLayout),
+		"to":       to.AddDate(0, 0, -1).Format(dateLayout),
+		"by":       by,
+		"products": products,
+	})
+}
+
+// GetCustomers compares new and returning customers between ?from= and ?to=
+func (h *AnalyticsHandler) GetCustomers(c *gin.Context) {
+	from, to, ok := parseDateRange(c, defaultAnalyticsDays)
+	if !ok {
+		return
+	}
+
+	split, err := h.Repo.Customers(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get customer analytics: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from.Format(dateLayout),
+		"to":        to.AddDate(0, 0, -1).Format(dateLayout),
+		"customers": split,
+	})
+}
+
+// GetConversion returns the share of carts started between ?from= and ?to= that reached checkout
+func (h *AnalyticsHandler) GetConversion(c *gin.Context) {
+	from, to, ok := parseDateRange(c, defaultAnalyticsDays)
+	if !ok {
+		return
+	}
+
+	conversion, err := h.Repo.CartConversion(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart conversion: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from.Format(dateLayout),
+		"to":         to.AddDate(0, 0, -1).Format(dateLayout),
+		"conversion": conversion,
+	})
+}
+
+// fillBuckets returns one bucket per period in [from, to), taking the values from buckets where present
+func fillBuckets(buckets []repository.RevenueBucket, from, to time.Time, interval string) []repository.RevenueBucket {
+	byPeriod := make(map[time.Time]repository.RevenueBucket, len(buckets))
+	for _, b := range buckets {
+		byPeriod[b.Period.UTC()] = b
+	}
+
+	series := []repository.RevenueBucket{}
+	for period := bucketStart(from, interval); period.Before(to); period = nextBucket(period, interval) {
+		b, ok := byPeriod[period]
+		if !ok {
+			b = repository.RevenueBucket{}
+		}
+		b.Period = period
+		series = append(series, b)
+	}
+	return series
+}
+
+// countBuckets counts the periods in [from, to), stopping once it passes limit
+func countBuckets(from, to time.Time, interval string, limit int) int {
+	n := 0
+	for period := bucketStart(from, interval); period.Before(to) && n <= limit; period = nextBucket(period, interval) {
+		n++
+	}
+	return n
+}
+
+// bucketStart truncates t like Postgres date_trunc: weeks start on Monday
+func bucketStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case repository.BucketWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case repository.BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case repository.BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case repository.BucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
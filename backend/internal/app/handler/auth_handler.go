@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"backend/internal/app/lockout"
+	"backend/internal/app/oidc"
+	"backend/internal/app/tokens"
+	"backend/internal/app/twofactor"
 	"backend/internal/domain/entity"
 	"backend/internal/domain/models"
 	"backend/internal/domain/repository"
@@ -8,10 +12,8 @@ import (
 	cryptorand "crypto/rand"
 	"crypto/tls"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/smtp"
@@ -21,41 +23,55 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 
-	"github.com/dgrijalva/jwt-go"
 	"gorm.io/gorm"
 )
 
-type GoogleUserInfo struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
-	VerifiedEmail bool   `json:"verified_email"`
-}
-
 type AuthHandler struct {
-	UserRepo    repository.UserRepository
-	OAuthConfig *oauth2.Config
-	tmpRepo     repository.TmpRepository
+	UserRepo     repository.UserRepository
+	Providers    *oidc.Registry
+	IdentityRepo repository.IdentityRepository
+	tmpRepo      repository.TmpRepository
+	ViewRepo     repository.ProductViewRepository
+	RecentRepo   repository.RecentViewRepository
+	Tokens       *tokens.Service
+	RefreshRepo  repository.RefreshTokenRepository
+	SessionRepo  repository.SessionRepository
+	TwoFactor    *twofactor.Service
+	Limiter      *lockout.Limiter
 }
 
-func NewAuthHandler(userRepo repository.UserRepository, tmpRepo repository.TmpRepository) *AuthHandler {
-	oauthConfig := &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
+func NewAuthHandler(userRepo repository.UserRepository, providers *oidc.Registry, identityRepo repository.IdentityRepository, tmpRepo repository.TmpRepository, viewRepo repository.ProductViewRepository, recentRepo repository.RecentViewRepository, tokenService *tokens.Service, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, twoFactor *twofactor.Service, limiter *lockout.Limiter) *AuthHandler {
+	return &AuthHandler{
+		UserRepo:     userRepo,
+		Providers:    providers,
+		IdentityRepo: identityRepo,
+		tmpRepo:      tmpRepo,
+		ViewRepo:     viewRepo,
+		RecentRepo:   recentRepo,
+		Tokens:       tokenService,
+		RefreshRepo:  refreshRepo,
+		SessionRepo:  sessionRepo,
+		TwoFactor:    twoFactor,
+		Limiter:      limiter,
 	}
+}
 
-	return &AuthHandler{
-		UserRepo:    userRepo,
-		OAuthConfig: oauthConfig,
-		tmpRepo:     tmpRepo,
+// mergeVisitorActivity hands the anonymous session's browsing history over to the user who just logged in
+func (h *AuthHandler) mergeVisitorActivity(c *gin.Context, userID uint) {
+	sessionID := currentVisitorID(c)
+	if sessionID == "" {
+		return
 	}
+
+	go func() {
+		if err := h.RecentRepo.MergeSession(sessionID, userID); err != nil {
+			log.Printf("Failed to merge recently viewed products into user %d: %v", userID, err)
+		}
+		if err := h.ViewRepo.AssignSessionToUser(sessionID, userID); err != nil {
+			log.Printf("Failed to attribute session views to user %d: %v", userID, err)
+		}
+	}()
 }
 
 // hashPassword hashes the given password using bcrypt
@@ -149,7 +165,7 @@ func (h *AuthHandler) RegisterWithGmail(c *gin.Context) {
 		return
 	}
 
-	// Trực tiếp kết nối DB để thêm user với GoogleID là NULL
+	// Trực tiếp kết nối DB để thêm user
 	db, dbErr := database.Connect()
 	if dbErr != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -300,8 +316,15 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 		return
 	}
 
+	// unknown addresses are counted too, so a lockout does not reveal which accounts exist
+	attempt := []lockout.Key{lockout.Email(lockout.LoginAccount, input.Email), lockout.IP(lockout.LoginIP, c.ClientIP())}
+	if throttled(c, h.Limiter.Check(attempt...)) {
+		return
+	}
+
 	user, err := h.UserRepo.FindByEmail(input.Email)
 	if err != nil || user == nil {
+		h.Limiter.Fail(attempt...)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Authentication failed",
 			"message": "Invalid email or password. Please check  again.",
@@ -310,12 +333,12 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 		return
 	}
 
-	// Check if this is a Google account
-	if user.GoogleID != nil {
+	// Accounts created through an identity provider have no password
+	if user.Password == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Google account",
-			"message": "This email is registered with Google. Please use Google Sign-In instead.",
-			"code":    "GOOGLE_ACCOUNT",
+			"error":   "External account",
+			"message": "This email is registered with an identity provider. Please sign in with it instead.",
+			"code":    "EXTERNAL_ACCOUNT",
 		})
 		return
 	}
@@ -323,6 +346,9 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 	// Verify password first
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
 	if err != nil {
+		if throttled(c, h.Limiter.Fail(attempt...)) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Authentication failed",
 			"message": "Invalid email or password. Please check again.",
@@ -331,9 +357,33 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token early
-	token, err := h.generateJWT(user)
+	// Accounts with two-factor get a challenge instead of tokens
+	if challenged, err := h.challengeSecondFactor(user); err != nil {
+		log.Printf("Failed to check two-factor status of user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Authentication failed",
+			"message": "We couldn't complete your login. Please try again later.",
+			"code":    "TWO_FACTOR_CHECK_FAILED",
+		})
+		return
+	} else if challenged != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Enter the code from your authenticator app",
+			"mfa_token": challenged,
+			"code":      "TWO_FACTOR_REQUIRED",
+		})
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// completeLogin starts the session once every login step passed and sends the tokens
+func (h *AuthHandler) completeLogin(c *gin.Context, user *entity.User) {
+	// Issue the tokens and set the cookies before doing any other database operations
+	session, err := h.startSession(c, user)
 	if err != nil {
+		log.Printf("Failed to start session for user %d: %v", user.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Token generation failed",
 			"message": "We couldn't create your authentication token. Please try again later.",
@@ -342,16 +392,8 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 		return
 	}
 
-	// Set the cookie before doing any other database operations
-	c.SetCookie(
-		"auth_token",
-		token,
-		3600*24, // 1 day
-		"/",
-		"",
-		false,
-		true,
-	)
+	h.Limiter.Succeed(lockout.Email(lockout.LoginAccount, user.Email))
+	h.mergeVisitorActivity(c, user.ID)
 
 	// Check if user has a cart and create one if needed - do this in the background after response
 	go func() {
@@ -380,199 +422,39 @@ func (h *AuthHandler) LoginWithGmail(c *gin.Context) {
 
 	// Immediately send the response
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+		"message":       "Login successful",
+		"token":         session.Access,
+		"refresh_token": session.Refresh,
+		"expires_in":    int(h.Tokens.TTL().Seconds()),
 		"user": gin.H{
 			"id":    user.ID,
 			"email": user.Email,
 			"name":  user.Name,
 			"role":  user.Role,
 		},
-		"code": "LOGIN_SUCCESS",
+		// admins without two-factor can log in but are sent to enrolment before the admin area opens
+		"two_factor_setup_required": h.TwoFactor.Required(user.Role) && !h.twoFactorEnabled(user.ID),
+		"code":                      "LOGIN_SUCCESS",
 	})
 }
 
-// GoogleLogin initiates the Google OAuth2 login flow
+// GoogleLogin initiates the Google login flow. It stays at its own path for existing links.
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	url := h.OAuthConfig.AuthCodeURL("state", oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusTemporaryRedirect, url)
+	h.startProviderLogin(c, "google")
 }
 
-// GoogleCallback handles the callback from Google OAuth2
+// GoogleCallback handles the callback from Google at the redirect URL registered with Google
 func (h *AuthHandler) GoogleCallback(c *gin.Context) {
-	log.Printf("[DEBUG] GoogleCallback started: %s", c.Request.URL.String())
-
-	code := c.Query("code")
-	if code == "" {
-		log.Printf("[ERROR] No code provided in Google callback")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No code provided"})
-		return
-	}
-	log.Printf("[DEBUG] Received OAuth code: %s...", code[:10])
-
-	token, err := h.OAuthConfig.Exchange(c, code)
-	if err != nil {
-		log.Printf("[ERROR] Error exchanging Google code for token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token: " + err.Error()})
-		return
-	}
-	log.Printf("[DEBUG] Successfully exchanged code for token. Access token: %s...", token.AccessToken[:10])
-
-	userInfo, err := h.getUserInfoFromGoogle(token.AccessToken)
-	if err != nil {
-		log.Printf("[ERROR] Error getting user info from Google: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info: " + err.Error()})
-		return
-	}
-
-	if userInfo.ID == "" || userInfo.Email == "" {
-		log.Printf("[ERROR] Invalid user info from Google: ID=%s, Email=%s", userInfo.ID, userInfo.Email)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user information from Google"})
-		return
-	}
-
-	log.Printf("[DEBUG] Successfully received Google user info for: %s (%s)", userInfo.Email, userInfo.ID)
-
-	existingUserByEmail, _ := h.UserRepo.FindByEmail(userInfo.Email)
-	if existingUserByEmail != nil {
-		log.Printf("[DEBUG] Found existing user with email %s (ID: %d, GoogleID: %v)",
-			existingUserByEmail.Email, existingUserByEmail.ID, existingUserByEmail.GoogleID)
-	}
-
-	var user entity.User
-
-	if existingUserByEmail != nil && existingUserByEmail.GoogleID == nil {
-		log.Printf("[DEBUG] Updating existing email user %d to link with Google ID: %s", existingUserByEmail.ID, userInfo.ID)
-		existingUserByEmail.GoogleID = &userInfo.ID
-		existingUserByEmail.Picture = userInfo.Picture
-
-		db, dbErr := database.Connect()
-		if dbErr != nil {
-			log.Printf("[ERROR] Database connection error: %v", dbErr)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error"})
-			return
-		}
-
-		if err := db.Save(existingUserByEmail).Error; err != nil {
-			log.Printf("[ERROR] Failed to update user with Google ID: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link Google account: " + err.Error()})
-			return
-		}
-		log.Printf("[DEBUG] Successfully linked Google ID to existing user %d", existingUserByEmail.ID)
-		user = *existingUserByEmail
-	} else {
-		user, err = h.UserRepo.FindUserByGoogleID(userInfo.ID)
-		if err != nil {
-			log.Printf("[DEBUG] Creating new user from Google: %s", userInfo.Email)
-
-			googleID := userInfo.ID
-			newUser := entity.User{
-				GoogleID: &googleID,
-				Email:    userInfo.Email,
-				Name:     userInfo.Name,
-				Picture:  userInfo.Picture,
-				Status:   "active",
-			}
-
-			user, err = h.UserRepo.CreateUser(newUser)
-			if err != nil {
-				log.Printf("[ERROR] Failed to create user from Google: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user: " + err.Error()})
-				return
-			}
-			log.Printf("[DEBUG] Successfully created new user from Google: ID=%d", user.ID)
-
-			err = h.createCartForUser(user.ID)
-			if err != nil {
-				log.Printf("[ERROR] Failed to create cart for new Google user %d: %v", user.ID, err)
-			} else {
-				log.Printf("[DEBUG] Successfully created cart for new Google user %d", user.ID)
-			}
-		}
-	}
-
-	jwtToken, err := h.generateJWT(&user)
-	if err != nil {
-		log.Printf("[ERROR] Failed to generate JWT token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token: " + err.Error()})
-		return
-	}
-	log.Printf("[DEBUG] Generated JWT token for user %d: %s...", user.ID, jwtToken[:10])
-
-	// Set the auth cookie
-	c.SetCookie(
-		"auth_token",
-		jwtToken,
-		3600*24, // 1 day
-		"/",
-		"",
-		false,
-		true,
-	)
-	log.Printf("[DEBUG] Set auth_token cookie with expiry 24h")
+	h.finishProviderLogin(c, "google")
+}
 
+// frontendBaseURL is the storefront address without a trailing slash
+func frontendBaseURL() string {
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
-		frontendURL = "http://localhost:3000" // Fallback
-		log.Printf("[DEBUG] Using fallback frontend URL: %s", frontendURL)
-	} else {
-		log.Printf("[DEBUG] Using frontend URL from env: %s", frontendURL)
-	}
-
-	// Đảm bảo frontendURL không kết thúc bằng dấu gạch chéo
-	if strings.HasSuffix(frontendURL, "/") {
-		frontendURL = strings.TrimSuffix(frontendURL, "/")
-		log.Printf("[DEBUG] Removed trailing slash from frontend URL: %s", frontendURL)
-	}
-
-	redirectURL := frontendURL + "/auth/google?token=" + jwtToken
-	log.Printf("[DEBUG] Final redirect URL: %s", redirectURL)
-
-	if strings.Contains(redirectURL, "localhost") && !strings.HasPrefix(redirectURL, "http://") {
-		redirectURL = strings.Replace(redirectURL, "https://", "http://", 1)
-		log.Printf("[DEBUG] Adjusted protocol for localhost: %s", redirectURL)
-	}
-
-	log.Printf("[DEBUG] Redirecting Google user to: %s", redirectURL)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-}
-
-// getUserInfoFromGoogle fetches user info from Google API
-func (h *AuthHandler) getUserInfoFromGoogle(accessToken string) (*GoogleUserInfo, error) {
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + accessToken)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var userInfo GoogleUserInfo
-	if err = json.Unmarshal(body, &userInfo); err != nil {
-		return nil, err
+		frontendURL = "http://localhost:3000"
 	}
-
-	return &userInfo, nil
-}
-
-// generateJWT creates a JWT token for the user
-func (h *AuthHandler) generateJWT(user *entity.User) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-
-	claims["user_id"] = user.ID
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // Token expires in 24 hours
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return strings.TrimSuffix(frontendURL, "/")
 }
 
 // GetCurrentUser returns the currently authenticated user's data
@@ -638,91 +520,133 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	log.Printf("[USER INFO] GetCurrentUser completed successfully for user ID %d", user.ID)
 }
 
-// AuthMiddleware authenticates requests using JWT token
-func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Ghi log URI và method của request
-		log.Printf("[DEBUG] Request: %s %s", c.Request.Method, c.Request.RequestURI)
-
-		// Kiểm tra token từ header Authorization
-		authHeader := c.GetHeader("Authorization")
-		var tokenString string
-		var err error
-
-		if authHeader != "" {
-			// Format: "Bearer {token}"
-			if len(authHeader) > 7 && strings.HasPrefix(authHeader, "Bearer ") {
-				tokenString = authHeader[7:]
-				log.Printf("[DEBUG] Found token in Authorization header: %s", tokenString[:10]+"...")
-			} else {
-				log.Printf("[DEBUG] Invalid Authorization header format: %s", authHeader)
-			}
-		} else {
-			log.Printf("[DEBUG] No Authorization header found")
-		}
+var (
+	errNoToken        = errors.New("no authentication token")
+	errSessionRevoked = errors.New("session revoked")
+)
 
-		// Nếu không có token trong header, thử lấy từ cookie
-		if tokenString == "" {
-			tokenString, err = c.Cookie("auth_token")
-			if err != nil {
-				log.Printf("[DEBUG] No auth_token cookie found: %v", err)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No authentication token"})
-				return
-			} else {
-				log.Printf("[DEBUG] Found token in cookie: %s", tokenString[:10]+"...")
-			}
+// sessionTouchInterval limits how often a session's last-seen time is written
+const sessionTouchInterval = time.Minute
+
+// bearerToken returns the access token from the Authorization header, falling back to the auth_token cookie
+func bearerToken(c *gin.Context) string {
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimSpace(authHeader[7:])
+	}
+	if cookie, err := c.Cookie("auth_token"); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// authenticate verifies the request's token, checks that its session is still active and loads
+// the user it was issued for. It returns the session ID alongside the user.
+func (h *AuthHandler) authenticate(c *gin.Context) (*entity.User, string, error) {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
+		return nil, "", errNoToken
+	}
+
+	claims, err := h.Tokens.Parse(tokenString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if claims.SessionID != "" {
+		if err := h.checkSession(c, claims); err != nil {
+			return nil, "", err
 		}
+	}
+
+	user, err := h.UserRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, claims.SessionID, nil
+}
 
-		// Giống code cũ - xác thực token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Printf("[DEBUG] Unexpected signing method: %v", token.Header["alg"])
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// checkSession rejects access tokens whose session was revoked, so revoking takes effect before
+// the token expires, and records the activity for the sessions list
+func (h *AuthHandler) checkSession(c *gin.Context, claims *tokens.Claims) error {
+	session, err := h.SessionRepo.FindByID(claims.SessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.RevokedAt != nil || session.UserID != claims.UserID {
+		return errSessionRevoked
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		ip := c.ClientIP()
+		go func() {
+			if err := h.SessionRepo.Touch(session.ID, ip, now); err != nil {
+				log.Printf("Failed to update session %s: %v", session.ID, err)
 			}
-			return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-		})
+		}()
+	}
+	return nil
+}
 
-		if err != nil || !token.Valid {
-			log.Printf("[DEBUG] Invalid token: %v, Valid: %v", err, token.Valid)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
+// AuthMiddleware authenticates requests using JWT token
+func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, sessionID, err := h.authenticate(c)
+		switch {
+		case errors.Is(err, errNoToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No authentication token", "code": "NOT_AUTHENTICATED"})
 			return
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Printf("[DEBUG] Invalid token claims type")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		case errors.Is(err, tokens.ErrInvalidToken):
+			log.Printf("[AUTH] Rejected token for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token", "code": "INVALID_TOKEN"})
 			return
-		}
-
-		userID := uint(claims["user_id"].(float64))
-		log.Printf("[DEBUG] Token contains userID: %d", userID)
-		user, err := h.UserRepo.GetUserByID(userID)
-		if err != nil {
-			log.Printf("[DEBUG] User not found for ID %d: %v", userID, err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		case errors.Is(err, errSessionRevoked):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "SESSION_REVOKED"})
+			return
+		case err != nil || user == nil:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
 			return
 		}
-		log.Printf("[DEBUG] User found: %s (ID: %d), Storing with type: %T", user.Email, user.ID, user)
 
-		// Đặt thông tin người dùng vào context
-		c.Set("user", user) // user là con trỏ *entity.User từ GetUserByID
+		c.Set("user", user)
+		c.Set("sessionID", sessionID)
 		c.Next()
 	}
 }
 
+// OptionalAuthMiddleware loads the user when a valid token is present but lets anonymous requests through.
+// Used on public routes that behave differently for logged-in users.
+func (h *AuthHandler) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, sessionID, err := h.authenticate(c); err == nil && user != nil {
+			c.Set("user", user)
+			c.Set("sessionID", sessionID)
+		}
+		c.Next()
+	}
+}
+
+// revokeSessionsAfterPasswordChange ends the user's sessions other than keepID when requested.
+// The password is already changed at this point, so a failure is logged rather than returned.
+func (h *AuthHandler) revokeSessionsAfterPasswordChange(userID uint, keepID string, requested bool) int64 {
+	if !requested {
+		return 0
+	}
+	n, err := h.SessionRepo.RevokeAll(userID, keepID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %d after password change: %v", userID, err)
+	}
+	return n
+}
+
 // Logout handles user logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// Clear the auth cookie
-	c.SetCookie(
-		"auth_token",
-		"",
-		-1, // expire immediately
-		"/",
-		"",
-		false,
-		true,
-	)
+	// Revoke the refresh token server-side so it cannot be exchanged again
+	if err := h.revokePresentedSession(c); err != nil {
+		log.Printf("Failed to revoke session on logout: %v", err)
+	}
+
+	clearAuthCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logged out successfully",
@@ -917,6 +841,13 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
+	// every request counts, a reset email is sent whether or not the previous one was used
+	attempt := []lockout.Key{lockout.Email(lockout.ResetAccount, input.Email), lockout.IP(lockout.ResetIP, c.ClientIP())}
+	if throttled(c, h.Limiter.Check(attempt...)) {
+		return
+	}
+	h.Limiter.Fail(attempt...)
+
 	// Check if user exists
 	user, err := h.UserRepo.FindByEmail(input.Email)
 	if err != nil || user == nil {
@@ -985,9 +916,17 @@ func (h *AuthHandler) ValidateResetToken(c *gin.Context) {
 		return
 	}
 
+	attempt := lockout.IP(lockout.ResetTokenIP, c.ClientIP())
+	if throttled(c, h.Limiter.Check(attempt)) {
+		return
+	}
+
 	// Find tmp record with this token
 	tmpReset, err := h.tmpRepo.FindByToken(input.Token)
 	if err != nil || tmpReset == nil || tmpReset.Status != "reset_password" {
+		if throttled(c, h.Limiter.Fail(attempt)) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid token",
 			"message": "The reset token is invalid or has expired.",
@@ -1016,6 +955,8 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var input struct {
 		Token    string `json:"token" binding:"required"`
 		Password string `json:"password" binding:"required,min=8"`
+		// RevokeSessions logs the account out everywhere
+		RevokeSessions bool `json:"revokeSessions"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -1027,9 +968,17 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	attempt := lockout.IP(lockout.ResetTokenIP, c.ClientIP())
+	if throttled(c, h.Limiter.Check(attempt)) {
+		return
+	}
+
 	// Find tmp record with this token
 	tmpReset, err := h.tmpRepo.FindByToken(input.Token)
 	if err != nil || tmpReset == nil || tmpReset.Status != "reset_password" {
+		if throttled(c, h.Limiter.Fail(attempt)) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid token",
 			"message": "The reset token is invalid or has expired.",
@@ -1085,8 +1034,11 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		log.Printf("Failed to mark reset token as used: %v", err)
 	}
 
+	revoked := h.revokeSessionsAfterPasswordChange(user.ID, "", input.RevokeSessions)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Your password has been reset successfully. You can now log in with your new password.",
+		"message":          "Your password has been reset successfully. You can now log in with your new password.",
+		"revoked_sessions": revoked,
 	})
 }
 
@@ -1124,6 +1076,8 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	var input struct {
 		CurrentPassword string `json:"currentPassword" binding:"required"`
 		NewPassword     string `json:"newPassword" binding:"required,min=8"`
+		// RevokeSessions logs out every other device, the current session stays active
+		RevokeSessions bool `json:"revokeSessions"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -1146,9 +1100,18 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Password guesses here count against the same account as at login
+	attempt := lockout.Email(lockout.LoginAccount, user.Email)
+	if throttled(c, h.Limiter.Check(attempt)) {
+		return
+	}
+
 	// Verify current password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword))
 	if err != nil {
+		if throttled(c, h.Limiter.Fail(attempt)) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Invalid password",
 			"message": "Your current password is incorrect.",
@@ -1156,6 +1119,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		})
 		return
 	}
+	h.Limiter.Succeed(attempt)
 
 	// Hash the new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
@@ -1190,8 +1154,11 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	revoked := h.revokeSessionsAfterPasswordChange(userID, currentSessionID(c), input.RevokeSessions)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Your password has been changed successfully.",
+		"message":          "Your password has been changed successfully.",
+		"revoked_sessions": revoked,
 	})
 }
 
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"backend/internal/app/tokens"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const refreshCookie = "refresh_token"
+
+// authTokens is the pair handed out at login and on every refresh
+type authTokens struct {
+	Access    string
+	Refresh   string
+	SessionID string
+}
+
+// secureCookies is on in production, where the frontend and the API live on different sites
+func secureCookies() bool {
+	return os.Getenv("APP_ENV") == "production"
+}
+
+// setAuthCookies stores both tokens in HttpOnly cookies. The refresh token is only sent to /auth.
+func (h *AuthHandler) setAuthCookies(c *gin.Context, pair *authTokens) {
+	secure := secureCookies()
+	if secure {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie("auth_token", pair.Access, int(h.Tokens.TTL().Seconds()), "/", "", secure, true)
+	c.SetCookie(refreshCookie, pair.Refresh, int(h.Tokens.RefreshTTL().Seconds()), "/auth", "", secure, true)
+}
+
+func clearAuthCookies(c *gin.Context) {
+	secure := secureCookies()
+	c.SetCookie("auth_token", "", -1, "/", "", secure, true)
+	c.SetCookie(refreshCookie, "", -1, "/auth", "", secure, true)
+}
+
+// startSession records a new session for the device, starts its refresh token family and sets
+// the auth cookies
+func (h *AuthHandler) startSession(c *gin.Context, user *entity.User) (*authTokens, error) {
+	familyID, err := tokens.NewFamilyID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	session := &entity.Session{
+		ID:         familyID,
+		UserID:     user.ID,
+		Device:     deviceLabel(userAgent),
+		IP:         c.ClientIP(),
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := h.SessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	refresh, hash, err := tokens.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &entity.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(h.Tokens.RefreshTTL()),
+	}
+	if err := h.RefreshRepo.Create(record); err != nil {
+		return nil, err
+	}
+
+	access, err := h.Tokens.Issue(user.ID, user.Email, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	pair := &authTokens{Access: access, Refresh: refresh, SessionID: familyID}
+	h.setAuthCookies(c, pair)
+	return pair, nil
+}
+
+// presentedRefreshToken reads the refresh token from the JSON body, falling back to the cookie
+func presentedRefreshToken(c *gin.Context) string {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength != 0 {
+		_ = c.ShouldBindJSON(&input)
+	}
+	if input.RefreshToken != "" {
+		return input.RefreshToken
+	}
+	if cookie, err := c.Cookie(refreshCookie); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token. The presented
+// token can only be used once; presenting it again means it leaked, so its whole family is revoked.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	presented := presentedRefreshToken(c)
+	if presented == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token is required", "code": "REFRESH_TOKEN_REQUIRED"})
+		return
+	}
+
+	current, err := h.RefreshRepo.FindByHash(tokens.HashRefreshToken(presented))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session", "code": "DATABASE_ERROR"})
+		return
+	}
+	if current == nil {
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token", "code": "INVALID_REFRESH_TOKEN"})
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case current.UsedAt != nil:
+		h.revokeReusedFamily(c, current)
+		return
+	case current.RevokedAt != nil:
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "REFRESH_TOKEN_REVOKED"})
+		return
+	case !now.Before(current.ExpiresAt):
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has expired", "code": "REFRESH_TOKEN_EXPIRED"})
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(current.UserID)
+	if err != nil {
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+
+	refresh, hash, err := tokens.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session", "code": "TOKEN_GENERATION_FAILED"})
+		return
+	}
+	next := &entity.RefreshToken{
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(h.Tokens.RefreshTTL()),
+	}
+	if err := h.RefreshRepo.Rotate(current, next); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			h.revokeReusedFamily(c, current)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	access, err := h.Tokens.Issue(user.ID, user.Email, current.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session", "code": "TOKEN_GENERATION_FAILED"})
+		return
+	}
+
+	if err := h.SessionRepo.Touch(current.FamilyID, c.ClientIP(), now); err != nil {
+		log.Printf("Failed to update session %s: %v", current.FamilyID, err)
+	}
+
+	pair := &authTokens{Access: access, Refresh: refresh, SessionID: current.FamilyID}
+	h.setAuthCookies(c, pair)
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         pair.Access,
+		"refresh_token": pair.Refresh,
+		"expires_in":    int(h.Tokens.TTL().Seconds()),
+		"code":          "TOKEN_REFRESHED",
+	})
+}
+
+func (h *AuthHandler) revokeReusedFamily(c *gin.Context, token *entity.RefreshToken) {
+	log.Printf("[AUTH] Refresh token reuse detected for user %d, revoking session %s", token.UserID, token.FamilyID)
+	if err := h.SessionRepo.Revoke(token.FamilyID); err != nil {
+		log.Printf("Failed to revoke session %s: %v", token.FamilyID, err)
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used", "code": "REFRESH_TOKEN_REUSED"})
+}
+
+// revokePresentedSession revokes the family of the refresh token sent with the request, or the
+// session the access token belongs to when no refresh token was sent
+func (h *AuthHandler) revokePresentedSession(c *gin.Context) error {
+	if presented := presentedRefreshToken(c); presented != "" {
+		token, err := h.RefreshRepo.FindByHash(tokens.HashRefreshToken(presented))
+		if err != nil {
+			return err
+		}
+		if token != nil {
+			return h.SessionRepo.Revoke(token.FamilyID)
+		}
+	}
+
+	if access := bearerToken(c); access != "" {
+		if claims, err := h.Tokens.Parse(access); err == nil && claims.SessionID != "" {
+			return h.SessionRepo.Revoke(claims.SessionID)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"backend/internal/app/document"
 	"backend/internal/domain/entity"
 	"backend/internal/domain/repository"
+	"errors"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -13,12 +17,17 @@ import (
 type CartHandler struct {
 	CartRepo    repository.CartRepository
 	ProductRepo repository.ProductRepository
+	Documents   *document.Service
+	// AttachInvoice attaches the invoice PDF to the order confirmation email
+	AttachInvoice bool
 }
 
-func NewCartHandler(cartRepo repository.CartRepository, productRepo repository.ProductRepository) *CartHandler {
+func NewCartHandler(cartRepo repository.CartRepository, productRepo repository.ProductRepository, documents *document.Service) *CartHandler {
 	return &CartHandler{
-		CartRepo:    cartRepo,
-		ProductRepo: productRepo,
+		CartRepo:      cartRepo,
+		ProductRepo:   productRepo,
+		Documents:     documents,
+		AttachInvoice: document.AttachInvoiceFromEnv(os.Getenv("ORDER_EMAIL_ATTACH_INVOICE")),
 	}
 }
 
@@ -150,6 +159,14 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 		return
 	}
 
+	if !product.IsAvailable() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product is no longer available",
+			"code":  "PRODUCT_UNAVAILABLE",
+		})
+		return
+	}
+
 	// Check stock
 	if product.Stock < request.Quantity {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough stock available"})
@@ -418,6 +435,20 @@ func (h *CartHandler) Checkout(c *gin.Context) {
 		return
 	}
 
+	// The shipping address is optional; the checkout page sends it as shippingDetails
+	var input struct {
+		ShippingDetails entity.Address `json:"shippingDetails"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid checkout details",
+				"code":  "INVALID_INPUT",
+			})
+			return
+		}
+	}
+
 	// Get active cart
 	cart, err := h.CartRepo.FindActiveCartByUserID(userIDUint)
 	if err != nil {
@@ -430,12 +461,66 @@ func (h *CartHandler) Checkout(c *gin.Context) {
 		return
 	}
 
-	// Close the cart
-	err = h.CartRepo.CloseCart(cart.ID)
+	// Archived products cannot be bought; the user has to remove them first
+	var unavailable []uint
+	for _, item := range cart.CartItems {
+		if !item.Product.IsAvailable() {
+			unavailable = append(unavailable, item.ID)
+		}
+	}
+	if len(unavailable) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Some products in your cart are no longer available",
+			"code":     "PRODUCT_UNAVAILABLE",
+			"item_ids": unavailable,
+		})
+		return
+	}
+
+	// Take the purchased quantities out of stock through the ledger
+	reference := strconv.FormatUint(uint64(cart.ID), 10)
+	movements := make([]entity.StockMovement, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		movements = append(movements, entity.StockMovement{
+			ProductID: item.ProductID,
+			Type:      entity.StockMovementSale,
+			Quantity:  -item.Quantity,
+			ActorID:   &userIDUint,
+			Reason:    "checkout",
+			Reference: reference,
+		})
+	}
+
+	// Stock and order are committed together, a double-submitted checkout is refused as a whole
+	orderNumber, err := h.CartRepo.CloseCart(cart.ID, input.ShippingDetails, movements)
 	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Not enough stock available for one or more items",
+				"code":  "INSUFFICIENT_STOCK",
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrCartNotActive) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "This order has already been placed",
+				"code":  "ORDER_ALREADY_PLACED",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Checkout successful"})
+	go func(orderID uint) {
+		if err := h.Documents.SendOrderConfirmation(orderID, h.AttachInvoice); err != nil {
+			log.Printf("Failed to send confirmation for order %d: %v", orderID, err)
+		}
+	}(cart.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Checkout successful",
+		"order_id":     cart.ID,
+		"order_number": orderNumber,
+	})
 }
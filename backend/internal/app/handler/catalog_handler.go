@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImportSize   = 10 << 20 // 10 MB
+	exportBatchSize = 500
+)
+
+// catalogColumns is the column order used by CSV exports; imports accept them in any order
+var catalogColumns = []string{"id", "sku", "name", "description", "price", "image_url", "stock", "reorder_threshold", "status"}
+
+type CatalogHandler struct {
+	ProductRepo repository.ProductRepository
+}
+
+func NewCatalogHandler(productRepo repository.ProductRepository) *CatalogHandler {
+	return &CatalogHandler{ProductRepo: productRepo}
+}
+
+// ImportProducts upserts products from an uploaded CSV or JSON file.
+// With ?dry_run=true nothing is written and the validation report is returned.
+func (h *CatalogHandler) ImportProducts(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	body, filename, err := importBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_IMPORT_FILE",
+		})
+		return
+	}
+	defer body.Close()
+
+	format := importFormat(c, filename)
+	var rows []repository.ProductImportRow
+	var rowErrors []repository.ProductImportError
+
+	switch format {
+	case "csv":
+		rows, rowErrors, err = parseCSVImport(body)
+	case "json":
+		rows, rowErrors, err = parseJSONImport(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported import format. Use csv or json",
+			"code":  "UNSUPPORTED_FORMAT",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to parse import file: " + err.Error(),
+			"code":  "INVALID_IMPORT_FILE",
+		})
+		return
+	}
+
+	rowErrors = append(rowErrors, checkDuplicateRows(rows)...)
+
+	// Rows with parse errors are left out, and the rest is only validated against the database
+	valid := make([]repository.ProductImportRow, 0, len(rows))
+	rejected := make(map[int]bool, len(rowErrors))
+	for _, e := range rowErrors {
+		rejected[e.Row] = true
+	}
+	for _, row := range rows {
+		if !rejected[row.Row] {
+			valid = append(valid, row)
+		}
+	}
+
+	report, err := h.ProductRepo.ImportProducts(valid, currentUserID(c), dryRun || len(rowErrors) > 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to import products: " + err.Error(),
+		})
+		return
+	}
+
+	report.DryRun = dryRun
+	report.Total = len(rows)
+	report.Errors = append(rowErrors, report.Errors...)
+
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Import rejected, no products were changed",
+			"code":    "IMPORT_VALIDATION_FAILED",
+			"applied": false,
+			"report":  report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Import completed successfully",
+		"applied": !dryRun,
+		"report":  report,
+	})
+}
+
+// ExportProducts streams the full catalog as CSV or JSON
+func (h *CatalogHandler) ExportProducts(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	filename := "products-" + time.Now().Format("20060102")
+
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		if err := w.Write(catalogColumns); err != nil {
+			return
+		}
+		err := h.ProductRepo.StreamProducts(exportBatchSize, func(products []entity.Product) error {
+			for _, p := range products {
+				if err := w.Write(productCSVRecord(p)); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			c.Writer.Flush()
+			return w.Error()
+		})
+		if err != nil {
+			// Headers are already sent, so the error can only be logged by gin
+			c.Error(err)
+		}
+	case "json":
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.Status(http.StatusOK)
+
+		enc := json.NewEncoder(c.Writer)
+		first := true
+		io.WriteString(c.Writer, "[")
+		err := h.ProductRepo.StreamProducts(exportBatchSize, func(products []entity.Product) error {
+			for _, p := range products {
+				if !first {
+					io.WriteString(c.Writer, ",")
+				}
+				first = false
+				if err := enc.Encode(productExportRecord(p)); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+		io.WriteString(c.Writer, "]")
+		if err != nil {
+			c.Error(err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported export format. Use csv or json",
+			"code":  "UNSUPPORTED_FORMAT",
+		})
+	}
+}
+
+// importBody returns the uploaded file from the "file" form field, or the raw request body
+func importBody(c *gin.Context) (io.ReadCloser, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("missing file field")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		return file, fileHeader.Filename, nil
+	}
+
+	return c.Request.Body, "", nil
+}
+
+// importFormat picks the format from ?format=, the file extension or the content type
+func importFormat(c *gin.Context, filename string) string {
+	if format := c.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	}
+
+	switch c.ContentType() {
+	case "text/csv", "application/csv":
+		return "csv"
+	case "application/json":
+		return "json"
+	}
+
+	return ""
+}
+
+// parseCSVImport reads a CSV file with a header row. Empty cells leave the field unchanged.
+func parseCSVImport(r io.Reader) ([]repository.ProductImportRow, []repository.ProductImportError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("missing header row: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		if _, ok := columns["sku"]; !ok {
+			if _, ok := columns["name"]; !ok {
+				return nil, nil, errors.New("header must contain at least one of id, sku or name")
+			}
+		}
+	}
+
+	var rows []repository.ProductImportRow
+	var rowErrors []repository.ProductImportError
+
+	// Row numbers match spreadsheet lines, the header being line 1
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, repository.ProductImportError{Row: line, Message: err.Error()})
+			continue
+		}
+
+		cell := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return "", false
+			}
+			value := strings.TrimSpace(record[i])
+			return value, value != ""
+		}
+
+		row := repository.ProductImportRow{Row: line}
+		var errs []repository.ProductImportError
+		fail := func(field, message string) {
+			errs = append(errs, repository.ProductImportError{Row: line, Field: field, Message: message})
+		}
+
+		if v, ok := cell("id"); ok {
+			id, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || id == 0 {
+				fail("id", "must be a positive integer")
+			}
+			row.ID = uint(id)
+		}
+		if v, ok := cell("sku"); ok {
+			row.SKU = v
+		}
+		if v, ok := cell("name"); ok {
+			row.Name = &v
+		}
+		if v, ok := cell("description"); ok {
+			row.Description = &v
+		}
+		if v, ok := cell("price"); ok {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil || price < 0 {
+				fail("price", "must be a non-negative number")
+			}
+			row.Price = &price
+		}
+		if v, ok := cell("image_url"); ok {
+			row.ImageURL = &v
+		}
+		if v, ok := cell("stock"); ok {
+			stock, err := strconv.Atoi(v)
+			if err != nil || stock < 0 {
+				fail("stock", "must be a non-negative integer")
+			}
+			row.Stock = &stock
+		}
+		if v, ok := cell("reorder_threshold"); ok {
+			threshold, err := strconv.Atoi(v)
+			if err != nil || threshold < 0 {
+				fail("reorder_threshold", "must be a non-negative integer")
+			}
+			row.ReorderThreshold = &threshold
+		}
+		if v, ok := cell("status"); ok {
+			if !entity.IsValidProductStatus(v) {
+				fail("status", "must be draft, active or archived")
+			}
+			row.Status = &v
+		}
+
+		rows = append(rows, row)
+		rowErrors = append(rowErrors, errs...)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// parseJSONImport reads a JSON array of product objects. Missing fields leave the value unchanged.
+func parseJSONImport(r io.Reader) ([]repository.ProductImportRow, []repository.ProductImportError, error) {
+	var records []repository.ProductImportRow
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, nil, err
+	}
+
+	var rowErrors []repository.ProductImportError
+	for i := range records {
+		row := &records[i]
+		row.Row = i + 1
+
+		if row.Price != nil && *row.Price < 0 {
+			rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "price", Message: "must be a non-negative number"})
+		}
+		if row.Stock != nil && *row.Stock < 0 {
+			rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "stock", Message: "must be a non-negative integer"})
+		}
+		if row.ReorderThreshold != nil && *row.ReorderThreshold < 0 {
+			rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "reorder_threshold", Message: "must be a non-negative integer"})
+		}
+		if row.Status != nil && !entity.IsValidProductStatus(*row.Status) {
+			rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "status", Message: "must be draft, active or archived"})
+		}
+	}
+
+	return records, rowErrors, nil
+}
+
+// checkDuplicateRows rejects rows that target the same ID or SKU as an earlier row
+func checkDuplicateRows(rows []repository.ProductImportRow) []repository.ProductImportError {
+	var rowErrors []repository.ProductImportError
+	seenIDs := make(map[uint]int)
+	seenSKUs := make(map[string]int)
+
+	for _, row := range rows {
+		if row.ID != 0 {
+			if first, ok := seenIDs[row.ID]; ok {
+				rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "id", Message: fmt.Sprintf("duplicate of row %d", first)})
+			} else {
+				seenIDs[row.ID] = row.Row
+			}
+		}
+		if row.SKU != "" {
+			if first, ok := seenSKUs[row.SKU]; ok {
+				rowErrors = append(rowErrors, repository.ProductImportError{Row: row.Row, Field: "sku", Message: fmt.Sprintf("duplicate of row %d", first)})
+			} else {
+				seenSKUs[row.SKU] = row.Row
+			}
+		}
+	}
+
+	return rowErrors
+}
+
+func productCSVRecord(p entity.Product) []string {
+	sku := ""
+	if p.SKU != nil {
+		sku = *p.SKU
+	}
+	return []string{
+		strconv.FormatUint(uint64(p.ID), 10),
+		sku,
+		p.Name,
+		p.Description,
+		strconv.FormatFloat(p.Price, 'f', 2, 64),
+		p.ImageURL,
+		strconv.Itoa(p.Stock),
+		strconv.Itoa(p.ReorderThreshold),
+		p.Status,
+	}
+}
+
+// productExportRecord uses the same field names as the JSON import so exports can be re-imported
+func productExportRecord(p entity.Product) gin.H {
+	sku := ""
+	if p.SKU != nil {
+		sku = *p.SKU
+	}
+	return gin.H{
+		"id":                p.ID,
+		"sku":               sku,
+		"name":              p.Name,
+		"description":       p.Description,
+		"price":             p.Price,
+		"image_url":         p.ImageURL,
+		"stock":             p.Stock,
+		"reorder_threshold": p.ReorderThreshold,
+		"status":            p.Status,
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"backend/internal/domain/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser returns the authenticated user stored in the context by AuthMiddleware
+func currentUser(c *gin.Context) (*entity.User, bool) {
+	userObj, exists := c.Get("user")
+	if !exists {
+		return nil, false
+	}
+
+	switch u := userObj.(type) {
+	case entity.User:
+		return &u, true
+	case *entity.User:
+		return u, u != nil
+	}
+
+	return nil, false
+}
+
+// currentUserID returns a pointer to the authenticated user's ID, or nil when there is none
+func currentUserID(c *gin.Context) *uint {
+	user, ok := currentUser(c)
+	if !ok {
+		return nil
+	}
+	id := user.ID
+	return &id
+}
+
+// currentSessionID returns the session the request's access token belongs to, or "" when there is none
+func currentSessionID(c *gin.Context) string {
+	return c.GetString("sessionID")
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDateRange reads ?from= and ?to= (YYYY-MM-DD, both inclusive) and returns the
+// half-open range [from, to+1 day). Without ?from= the range starts defDays before ?to=,
+// which itself defaults to today. On invalid input it writes a 400 and returns ok=false.
+func parseDateRange(c *gin.Context, defDays int) (from, to time.Time, ok bool) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	lastDay := today
+	if v := c.Query("to"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD", "code": "INVALID_DATE_RANGE"})
+			return from, to, false
+		}
+		lastDay = d
+	}
+
+	from = lastDay.AddDate(0, 0, -(defDays - 1))
+	if v := c.Query("from"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD", "code": "INVALID_DATE_RANGE"})
+			return from, to, false
+		}
+		from = d
+	}
+
+	to = lastDay.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to", "code": "INVALID_DATE_RANGE"})
+		return from, to, false
+	}
+
+	return from, to, true
+}
+
+// parseOptionalDateRange reads ?from= and ?to= (YYYY-MM-DD, both inclusive) when present.
+// Either bound may be missing; the returned to is exclusive. On invalid input it writes a 400.
+func parseOptionalDateRange(c *gin.Context) (from, to *time.Time, ok bool) {
+	if v := c.Query("from"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD", "code": "INVALID_DATE_RANGE"})
+			return nil, nil, false
+		}
+		from = &d
+	}
+
+	if v := c.Query("to"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD", "code": "INVALID_DATE_RANGE"})
+			return nil, nil, false
+		}
+		d = d.AddDate(0, 0, 1)
+		to = &d
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to", "code": "INVALID_DATE_RANGE"})
+		return nil, nil, false
+	}
+	return from, to, true
+}
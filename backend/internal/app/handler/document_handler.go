@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"backend/internal/app/document"
+	"backend/internal/domain/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocumentHandler struct {
+	Service *document.Service
+}
+
+func NewDocumentHandler(service *document.Service) *DocumentHandler {
+	return &DocumentHandler{Service: service}
+}
+
+// GetMyInvoice downloads the invoice of one of the current user's orders
+func (h *DocumentHandler) GetMyInvoice(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+			"code":  "NOT_AUTHENTICATED",
+		})
+		return
+	}
+
+	order := h.findOrder(c)
+	if order == nil {
+		return
+	}
+	// Other users' orders are reported as missing rather than forbidden
+	if order.UserID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found", "code": "ORDER_NOT_FOUND"})
+		return
+	}
+
+	h.sendInvoice(c, order)
+}
+
+// GetInvoice downloads the invoice of any order
+func (h *DocumentHandler) GetInvoice(c *gin.Context) {
+	if order := h.findOrder(c); order != nil {
+		h.sendInvoice(c, order)
+	}
+}
+
+// GetPackingSlip downloads the packing slip of any order
+func (h *DocumentHandler) GetPackingSlip(c *gin.Context) {
+	order := h.findOrder(c)
+	if order == nil {
+		return
+	}
+
+	data, err := h.Service.PackingSlipPDF(order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate packing slip: " + err.Error()})
+		return
+	}
+
+	name := strconv.FormatUint(uint64(order.ID), 10)
+	if order.OrderNumber != nil {
+		name = *order.OrderNumber
+	}
+	sendPDF(c, "packing-slip-"+name+".pdf", data)
+}
+
+func (h *DocumentHandler) sendInvoice(c *gin.Context, order *entity.Cart) {
+	data, invoice, err := h.Service.InvoicePDF(order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invoice: " + err.Error()})
+		return
+	}
+
+	sendPDF(c, invoice.Number+".pdf", data)
+}
+
+// findOrder loads the completed order named by the :id parameter, writing the error response when it can't
+func (h *DocumentHandler) findOrder(c *gin.Context) *entity.Cart {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return nil
+	}
+
+	order, err := h.Service.Order(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found", "code": "ORDER_NOT_FOUND"})
+		return nil
+	}
+	return order
+}
+
+func sendPDF(c *gin.Context, filename string, data []byte) {
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", data)
+}
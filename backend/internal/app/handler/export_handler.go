@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"backend/internal/app/export"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// syncExportLimit is the largest report, in orders, users or products, that is streamed
+// directly; bigger ones run as background jobs
+const syncExportLimit = 5000
+
+type ExportHandler struct {
+	Exporter *export.Exporter
+	Jobs     *export.Manager
+}
+
+func NewExportHandler(exporter *export.Exporter, jobs *export.Manager) *ExportHandler {
+	return &ExportHandler{
+		Exporter: exporter,
+		Jobs:     jobs,
+	}
+}
+
+// Export downloads the orders, users or products report as ?format=csv (default) or xlsx.
+// Filters: ?from= and ?to= (YYYY-MM-DD), ?status= and, for users, ?role=.
+// Reports over syncExportLimit records are started as a background job and answered with 202.
+func (h *ExportHandler) Export(c *gin.Context) {
+	report, format, filter, ok := h.parseRequest(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.Exporter.Count(report, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare export: " + err.Error()})
+		return
+	}
+
+	if count > syncExportLimit {
+		h.startJob(c, report, format, filter)
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+export.Filename(report, format, time.Now())+`"`)
+	c.Status(http.StatusOK)
+
+	if _, err := h.Exporter.Write(c.Writer, report, format, filter); err != nil {
+		// Headers are already sent, so the error can only be logged by gin
+		c.Error(err)
+	}
+}
+
+// StartExport always runs the report as a background job
+func (h *ExportHandler) StartExport(c *gin.Context) {
+	report, format, filter, ok := h.parseRequest(c)
+	if !ok {
+		return
+	}
+	h.startJob(c, report, format, filter)
+}
+
+func (h *ExportHandler) startJob(c *gin.Context, report, format string, filter repository.ExportFilter) {
+	var requestedBy uint
+	if id := currentUserID(c); id != nil {
+		requestedBy = *id
+	}
+
+	job, err := h.Jobs.Start(report, format, filter, requestedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export: " + err.Error()})
+		return
+	}
+
+	c.Header("Location", "/admin/exports/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export started",
+		"job":     job,
+	})
+}
+
+// GetJobs lists the export jobs that are still downloadable or running
+func (h *ExportHandler) GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": h.Jobs.List()})
+}
+
+// GetJob returns the state of one export job
+func (h *ExportHandler) GetJob(c *gin.Context) {
+	job, err := h.Jobs.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found", "code": "EXPORT_NOT_FOUND"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// DownloadJob sends the file of a finished export job
+func (h *ExportHandler) DownloadJob(c *gin.Context) {
+	f, job, err := h.Jobs.Open(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, export.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found", "code": "EXPORT_NOT_FOUND"})
+		case errors.Is(err, export.ErrJobNotReady):
+			c.JSON(http.StatusConflict, gin.H{"error": "Export is not ready", "code": "EXPORT_NOT_READY", "job": job})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open export: " + err.Error()})
+		}
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open export: " + err.Error()})
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, info.Size(), export.ContentType(job.Format), f, map[string]string{
+		"Content-Disposition": `attachment; filename="` + job.Filename + `"`,
+	})
+}
+
+// parseRequest validates the report name, format and filters, writing a 400 when they are invalid
+func (h *ExportHandler) parseRequest(c *gin.Context) (report, format string, filter repository.ExportFilter, ok bool) {
+	report = c.Param("report")
+	if !export.IsValidReport(report) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Unknown report. Use orders, users or products",
+			"code":  "UNKNOWN_REPORT",
+		})
+		return
+	}
+
+	format = strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+	if !export.IsValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported export format. Use csv or xlsx",
+			"code":  "UNSUPPORTED_FORMAT",
+		})
+		return
+	}
+
+	from, to, valid := parseOptionalDateRange(c)
+	if !valid {
+		return
+	}
+	filter = repository.ExportFilter{
+		From:   from,
+		To:     to,
+		Status: c.Query("status"),
+		Role:   c.Query("role"),
+	}
+
+	if filter.Status != "" {
+		switch report {
+		case export.ReportOrders:
+			if _, err := strconv.Atoi(filter.Status); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Order status must be a number", "code": "INVALID_STATUS"})
+				return
+			}
+		case export.ReportProducts:
+			if !entity.IsValidProductStatus(filter.Status) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product status", "code": "INVALID_STATUS"})
+				return
+			}
+		}
+	}
+
+	if filter.Role != "" && report != export.ReportUsers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The role filter only applies to users", "code": "INVALID_FILTER"})
+		return
+	}
+
+	return report, format, filter, true
+}
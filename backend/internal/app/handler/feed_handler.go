@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"backend/internal/app/feed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedMaxAge is how long clients and crawlers may cache the generated documents
+const feedMaxAge = "public, max-age=3600"
+
+type FeedHandler struct {
+	Generator *feed.Generator
+}
+
+func NewFeedHandler(generator *feed.Generator) *FeedHandler {
+	return &FeedHandler{Generator: generator}
+}
+
+// Sitemap serves sitemap.xml
+func (h *FeedHandler) Sitemap(c *gin.Context) {
+	h.serve(c, feed.Sitemap)
+}
+
+// MerchantFeedXML serves the product feed as RSS 2.0
+func (h *FeedHandler) MerchantFeedXML(c *gin.Context) {
+	h.serve(c, feed.MerchantFeedXML)
+}
+
+// MerchantFeedCSV serves the product feed as CSV
+func (h *FeedHandler) MerchantFeedCSV(c *gin.Context) {
+	h.serve(c, feed.MerchantFeedCSV)
+}
+
+// serve writes a generated document with caching headers, answering conditional requests with 304
+func (h *FeedHandler) serve(c *gin.Context, name string) {
+	doc, err := h.Generator.Get(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate " + name})
+		return
+	}
+
+	c.Header("Cache-Control", feedMaxAge)
+	c.Header("ETag", doc.ETag)
+	c.Header("Last-Modified", doc.LastModified.Format(http.TimeFormat))
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if match == doc.ETag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !doc.LastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, doc.ContentType, doc.Body)
+}
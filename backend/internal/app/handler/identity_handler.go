@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IdentityHandler struct {
+	Auth     *AuthHandler
+	Repo     repository.IdentityRepository
+	Passkeys repository.PasskeyRepository
+}
+
+func NewIdentityHandler(auth *AuthHandler, repo repository.IdentityRepository, passkeys repository.PasskeyRepository) *IdentityHandler {
+	return &IdentityHandler{Auth: auth, Repo: repo, Passkeys: passkeys}
+}
+
+// loginMethods counts the ways the user can sign in: a password, each linked identity and each passkey
+func loginMethods(user *entity.User, identities []entity.UserIdentity, passkeys []entity.Passkey) int {
+	n := len(identities) + len(passkeys)
+	if user.Password != "" {
+		n++
+	}
+	return n
+}
+
+// GetIdentities lists the provider accounts linked to the user and the providers that can be linked
+func (h *IdentityHandler) GetIdentities(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	identities, err := h.Repo.ListByUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load linked accounts", "code": "DATABASE_ERROR"})
+		return
+	}
+	passkeys, err := h.Passkeys.ListByUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load linked accounts", "code": "DATABASE_ERROR"})
+		return
+	}
+	canUnlink := loginMethods(user, identities, passkeys) > 1
+
+	linked := make(map[string]bool, len(identities))
+	items := make([]gin.H, 0, len(identities))
+	for _, identity := range identities {
+		linked[identity.Provider] = true
+		displayName := identity.Provider
+		if provider, ok := h.Auth.Providers.Provider(identity.Provider); ok {
+			displayName = provider.DisplayName()
+		}
+		items = append(items, gin.H{
+			"id":            identity.ID,
+			"provider":      identity.Provider,
+			"display_name":  displayName,
+			"email":         identity.Email,
+			"created_at":    identity.CreatedAt,
+			"last_login_at": identity.LastLoginAt,
+			"can_unlink":    canUnlink,
+		})
+	}
+
+	providers := make([]gin.H, 0, len(h.Auth.Providers.Providers()))
+	for _, provider := range h.Auth.Providers.Providers() {
+		providers = append(providers, gin.H{
+			"name":         provider.Name(),
+			"display_name": provider.DisplayName(),
+			"linked":       linked[provider.Name()],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities":   items,
+		"providers":    providers,
+		"has_password": user.Password != "",
+	})
+}
+
+// LinkIdentity starts linking an account at the provider to the logged-in user. The browser can't
+// send the access token on a navigation, so this returns a short-lived ticket that the settings
+// page form-posts to link_url, which sends the user on to the provider.
+func (h *IdentityHandler) LinkIdentity(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	provider, ok := h.Auth.Providers.Provider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider", "code": "PROVIDER_NOT_FOUND"})
+		return
+	}
+
+	ticket, err := h.Auth.Tokens.IssueChallenge(user.ID, user.Email, linkPurpose)
+	if err != nil {
+		log.Printf("Failed to issue link ticket for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking", "code": "TOKEN_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"link_token": ticket,
+		"link_url":   "/auth/oidc/" + provider.Name() + "/link",
+	})
+}
+
+// UnlinkIdentity removes a linked provider account. The last way to sign in can't be removed, the
+// user has to add a passkey, link another account or set a password through password reset first.
+func (h *IdentityHandler) UnlinkIdentity(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID", "code": "INVALID_INPUT"})
+		return
+	}
+
+	identities, err := h.Repo.ListByUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink account", "code": "DATABASE_ERROR"})
+		return
+	}
+	found := false
+	for _, identity := range identities {
+		if identity.ID == uint(id) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Linked account not found", "code": "IDENTITY_NOT_FOUND"})
+		return
+	}
+
+	passkeys, err := h.Passkeys.ListByUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink account", "code": "DATABASE_ERROR"})
+		return
+	}
+	if loginMethods(user, identities, passkeys) <= 1 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Last login method",
+			"message": "This is the only way you can sign in. Add a passkey, link another account or set a password through password reset before unlinking it.",
+			"code":    "LAST_LOGIN_METHOD",
+		})
+		return
+	}
+
+	deleted, err := h.Repo.Delete(user.ID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink account", "code": "DATABASE_ERROR"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Linked account not found", "code": "IDENTITY_NOT_FOUND"})
+		return
+	}
+
+	log.Printf("Unlinked identity %d from user %d", id, user.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked"})
+}
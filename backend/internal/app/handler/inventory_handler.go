@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryHandler struct {
+	StockRepo   repository.StockRepository
+	ProductRepo repository.ProductRepository
+}
+
+func NewInventoryHandler(stockRepo repository.StockRepository, productRepo repository.ProductRepository) *InventoryHandler {
+	return &InventoryHandler{
+		StockRepo:   stockRepo,
+		ProductRepo: productRepo,
+	}
+}
+
+// PostMovement records a manual stock movement (restock, return, adjustment, ...) for a product
+func (h *InventoryHandler) PostMovement(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var input struct {
+		Type      string `json:"type" binding:"required"`
+		Quantity  int    `json:"quantity" binding:"required"`
+		Reason    string `json:"reason"`
+		Reference string `json:"reference"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if !entity.IsValidStockMovementType(input.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid movement type. Must be one of restock, sale, return, adjustment, reservation",
+		})
+		return
+	}
+
+	// Restocks and returns always add stock, reservations and sales always remove it
+	switch input.Type {
+	case entity.StockMovementRestock, entity.StockMovementReturn:
+		if input.Quantity < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Quantity must be positive for " + input.Type,
+			})
+			return
+		}
+	case entity.StockMovementReservation:
+		if input.Quantity > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Quantity must be negative for " + input.Type,
+			})
+			return
+		}
+	case entity.StockMovementSale:
+		if input.Quantity > 0 {
+			input.Quantity = -input.Quantity
+		}
+	}
+
+	if input.Type == entity.StockMovementAdjustment && input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A reason is required for manual adjustments",
+		})
+		return
+	}
+
+	movement := &entity.StockMovement{
+		ProductID: uint(productID),
+		Type:      input.Type,
+		Quantity:  input.Quantity,
+		ActorID:   currentUserID(c),
+		Reason:    input.Reason,
+		Reference: input.Reference,
+	}
+
+	if err := h.StockRepo.RecordMovement(movement); err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Movement would make stock negative",
+				"code":  "INSUFFICIENT_STOCK",
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+		log.Printf("Failed to record stock movement for product %d: %v", productID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to record stock movement",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Stock movement recorded successfully",
+		"movement": movement,
+	})
+}
+
+// GetMovements returns the stock movement history of a product
+func (h *InventoryHandler) GetMovements(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	product, err := h.ProductRepo.FindByID(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	movements, err := h.StockRepo.GetMovementsByProductID(product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get stock movements: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": product.ID,
+		"stock":      product.Stock,
+		"movements":  movements,
+	})
+}
+
+// GetReconciliation compares the stored stock of a product with its ledger
+func (h *InventoryHandler) GetReconciliation(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	report, err := h.StockRepo.Reconcile(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Failed to reconcile stock: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reconciliation": report,
+	})
+}
+
+// SyncLedger writes a correcting ledger entry so the ledger matches the stored stock
+func (h *InventoryHandler) SyncLedger(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	movement, err := h.StockRepo.SyncLedger(uint(productID), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to sync stock ledger: " + err.Error(),
+		})
+		return
+	}
+
+	if movement == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Stock ledger already in sync",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Stock ledger synced successfully",
+		"movement": movement,
+	})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"backend/internal/app/lockout"
+	"backend/internal/domain/repository"
+	"backend/pkg/utils"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttled answers the request when the limiter refused it and reports whether it did. Locked
+// accounts get 423 so the login page can point to password reset, everything else gets 429.
+func throttled(c *gin.Context, d lockout.Decision) bool {
+	if d.Allowed {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(d.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	if d.Locked && (d.Scope == lockout.LoginAccount || d.Scope == lockout.ResetAccount) {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":       "Account locked",
+			"message":     "Too many failed attempts. Your account is temporarily locked, please try again later or reset your password.",
+			"code":        "ACCOUNT_LOCKED",
+			"retry_after": retryAfter,
+		})
+		return true
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many attempts",
+		"message":     fmt.Sprintf("Too many failed attempts. Please wait %d seconds and try again.", retryAfter),
+		"code":        "TOO_MANY_ATTEMPTS",
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
+// LockoutNotifier emails the owner of an account when it gets locked, so that someone guessing
+// their password does not go unnoticed
+func LockoutNotifier(userRepo repository.UserRepository) func(key lockout.Key, until time.Time) {
+	return func(key lockout.Key, until time.Time) {
+		if key.Scope != lockout.LoginAccount {
+			return
+		}
+		go func() {
+			user, err := userRepo.FindByEmail(key.Value)
+			if err != nil || user == nil {
+				// nobody to tell when the guesses were against an unknown address
+				return
+			}
+			if err := sendAccountLockedEmail(user.Email, until); err != nil {
+				log.Printf("Failed to send lockout email to user %d: %v", user.ID, err)
+			}
+		}()
+	}
+}
+
+func sendAccountLockedEmail(email string, until time.Time) error {
+	resetLink := frontendBaseURL() + "/forgot-password"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Account Locked</title>
+</head>
+<body style="font-family: Arial, sans-serif; color: #333; max-width: 600px; margin: 0 auto;">
+    <h2>Your account has been temporarily locked</h2>
+    <p>Hello,</p>
+    <p>We noticed several failed sign-in attempts on your account, so we have locked it until %s (UTC).</p>
+    <p>If this was you, you can try again after that time. If it was not, someone may be trying to guess your password and we recommend that you reset it:</p>
+    <p><a href="%s">%s</a></p>
+    <p style="font-size: 12px; color: #777;">© 2025 Hidden Score. All rights reserved.</p>
+</body>
+</html>
+`, until.UTC().Format("2006-01-02 15:04"), html.EscapeString(resetLink), html.EscapeString(resetLink))
+
+	return utils.SendEmail([]string{email}, "Your account has been temporarily locked", body)
+}
+
+type LockoutHandler struct {
+	Limiter  *lockout.Limiter
+	UserRepo repository.UserRepository
+}
+
+func NewLockoutHandler(limiter *lockout.Limiter, userRepo repository.UserRepository) *LockoutHandler {
+	return &LockoutHandler{Limiter: limiter, UserRepo: userRepo}
+}
+
+// GetLockouts lists the accounts that are locked right now
+func (h *LockoutHandler) GetLockouts(c *gin.Context) {
+	locked, err := h.Limiter.Locked(lockout.LoginAccount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lockouts", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(locked))
+	for _, t := range locked {
+		items = append(items, gin.H{
+			"email":           t.Key,
+			"failures":        t.Failures,
+			"last_failure_at": t.LastFailureAt,
+			"locked_until":    t.LockedUntil,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lockouts": items})
+}
+
+// UnlockUser lifts the login lockout of an account and clears its failed attempts
+func (h *LockoutHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID", "code": "INVALID_INPUT"})
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user", "code": "DATABASE_ERROR"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+
+	for _, key := range []lockout.Key{lockout.Email(lockout.LoginAccount, user.Email), lockout.Email(lockout.ResetAccount, user.Email)} {
+		if err := h.Limiter.Unlock(key); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account", "code": "DATABASE_ERROR"})
+			return
+		}
+	}
+
+	log.Printf("Unlocked user %d", user.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"backend/internal/app/tokens"
+	"backend/internal/domain/models"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oauthStateCookie carries the signed state from the login redirect to the callback
+	oauthStateCookie = "oauth_state"
+	// oauthLoginStatus marks the one-time login codes in tmp_users
+	oauthLoginStatus = "oauth_login"
+	// oauthLoginCodeTTL is how long the frontend has to exchange the login code
+	oauthLoginCodeTTL = 2 * time.Minute
+)
+
+var errInvalidOAuthState = errors.New("OAuth state does not match")
+
+// allowedRedirect checks a post-login redirect target. Paths on the storefront are always
+// allowed; absolute URLs only when their origin is the storefront or listed in
+// OAUTH_REDIRECT_ALLOWLIST. It returns the target to use, "/" when none was given.
+func allowedRedirect(target string) (string, bool) {
+	if target == "" {
+		return "/", true
+	}
+	// backslashes are read as slashes by some browsers, which turns "/\evil.com" into a host
+	if strings.ContainsAny(target, "\\\r\n") {
+		return "", false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+	if !u.IsAbs() && u.Host == "" {
+		if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+			return "", false
+		}
+		return target, true
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", false
+	}
+	origin := u.Scheme + "://" + u.Host
+	if strings.EqualFold(origin, frontendBaseURL()) {
+		return target, true
+	}
+	for _, allowed := range strings.Split(os.Getenv("OAUTH_REDIRECT_ALLOWLIST"), ",") {
+		if allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/"); allowed != "" && strings.EqualFold(origin, allowed) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// setOAuthState starts a login redirect: it binds a random state, a nonce and a PKCE verifier to
+// the browser in a short-lived signed cookie and returns them for the authorization URL. A
+// linkUserID other than 0 links the provider account to that user instead of logging in.
+func (h *AuthHandler) setOAuthState(c *gin.Context, provider, redirectTo string, linkUserID uint) (*tokens.OAuthState, error) {
+	state, err := generateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	oauthState := &tokens.OAuthState{
+		Provider:   provider,
+		State:      state,
+		Verifier:   oauth2.GenerateVerifier(),
+		Nonce:      nonce,
+		RedirectTo: redirectTo,
+		LinkUserID: linkUserID,
+	}
+
+	signed, err := h.Tokens.IssueOAuthState(*oauthState)
+	if err != nil {
+		return nil, err
+	}
+
+	// the callback is a top-level navigation from the provider, which Lax cookies survive
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, signed, int(h.Tokens.OAuthStateTTL().Seconds()), "/auth", "", secureCookies(), true)
+
+	return oauthState, nil
+}
+
+// consumeOAuthState checks the state the provider echoed against the cookie and clears the cookie,
+// so every redirect can be completed once. The login must have been started with the same provider.
+func (h *AuthHandler) consumeOAuthState(c *gin.Context, provider string) (*tokens.OAuthState, error) {
+	cookie, err := c.Cookie(oauthStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, "", -1, "/auth", "", secureCookies(), true)
+	if err != nil {
+		return nil, errInvalidOAuthState
+	}
+
+	state, err := h.Tokens.ParseOAuthState(cookie)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(state.State), []byte(c.Query("state"))) != 1 || state.Provider != provider {
+		return nil, errInvalidOAuthState
+	}
+	return state, nil
+}
+
+// redirectWithLoginCode ends an external login by sending the browser to the storefront with a
+// one-time code. Tokens never appear in the URL; the frontend trades the code at /auth/exchange.
+func (h *AuthHandler) redirectWithLoginCode(c *gin.Context, userID uint, redirectTo string) {
+	code, err := generateToken(32)
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate login code: %v", err)
+		redirectLoginError(c, "auth_failed")
+		return
+	}
+
+	if err := h.tmpRepo.Create(&models.TmpUser{
+		UserID:      userID,
+		Status:      oauthLoginStatus,
+		TokenRemain: code,
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		log.Printf("[ERROR] Failed to store login code for user %d: %v", userID, err)
+		redirectLoginError(c, "auth_failed")
+		return
+	}
+
+	target := frontendBaseURL() + "/auth/google?login_code=" + url.QueryEscape(code)
+	if redirectTo != "" && redirectTo != "/" {
+		target += "&redirect_to=" + url.QueryEscape(redirectTo)
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// redirectLoginError sends the browser back to the login page with an error the page can show
+func redirectLoginError(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, frontendBaseURL()+"/login?error="+url.QueryEscape(code))
+}
+
+// ExchangeLoginCode completes an external login. The one-time code from the redirect is traded
+// for the same response as a password login: tokens, or a two-factor challenge. The redirect
+// target travels through the storefront URL, so it is checked against the allowlist again.
+func (h *AuthHandler) ExchangeLoginCode(c *gin.Context) {
+	var input struct {
+		Code       string `json:"code" binding:"required"`
+		RedirectTo string `json:"redirect_to"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+	// refused before the code is spent, so the frontend only follows targets the server accepted
+	if _, ok := allowedRedirect(input.RedirectTo); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect target is not allowed", "code": "INVALID_REDIRECT"})
+		return
+	}
+
+	// spending the code is the check, so two exchanges of one code can't both start a session
+	spent, err := h.tmpRepo.Spend(input.Code, oauthLoginStatus, "used", time.Now().Add(-oauthLoginCodeTTL))
+	if err != nil {
+		log.Printf("Failed to spend login code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login", "code": "DATABASE_ERROR"})
+		return
+	}
+	if !spent {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Login expired",
+			"message": "Your login attempt has expired. Please log in again.",
+			"code":    "LOGIN_CODE_INVALID",
+		})
+		return
+	}
+
+	tmp, err := h.tmpRepo.FindByToken(input.Code)
+	if err != nil || tmp == nil {
+		log.Printf("Failed to load spent login code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(tmp.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+
+	if challenged, err := h.challengeSecondFactor(&user); err != nil {
+		log.Printf("Failed to check two-factor status of user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Authentication failed",
+			"message": "We couldn't complete your login. Please try again later.",
+			"code":    "TWO_FACTOR_CHECK_FAILED",
+		})
+		return
+	} else if challenged != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Enter the code from your authenticator app",
+			"mfa_token": challenged,
+			"code":      "TWO_FACTOR_REQUIRED",
+		})
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
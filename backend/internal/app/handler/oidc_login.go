@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"backend/internal/app/oidc"
+	"backend/internal/domain/entity"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+var (
+	errEmailNotVerified = errors.New("identity provider has not verified the email address")
+	errEmailInUse       = errors.New("email belongs to an account the provider account is not linked to")
+)
+
+// linkPurpose is the challenge purpose of the tickets that start linking a provider account
+const linkPurpose = "link"
+
+// GetLoginProviders lists the identity providers the login page offers
+func (h *AuthHandler) GetLoginProviders(c *gin.Context) {
+	providers := make([]gin.H, 0, len(h.Providers.Providers()))
+	for _, provider := range h.Providers.Providers() {
+		providers = append(providers, gin.H{
+			"name":         provider.Name(),
+			"display_name": provider.DisplayName(),
+			"login_url":    "/auth/oidc/" + provider.Name() + "/login",
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// ProviderLogin sends the browser to the sign-in page of the provider in the URL. The optional
+// redirect_to is where the user lands after logging in and must pass the redirect allowlist.
+func (h *AuthHandler) ProviderLogin(c *gin.Context) {
+	h.startProviderLogin(c, c.Param("provider"))
+}
+
+// ProviderCallback completes a login started by ProviderLogin
+func (h *AuthHandler) ProviderCallback(c *gin.Context) {
+	h.finishProviderLogin(c, c.Param("provider"))
+}
+
+func (h *AuthHandler) startProviderLogin(c *gin.Context, name string) {
+	provider, ok := h.Providers.Provider(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider", "code": "PROVIDER_NOT_FOUND"})
+		return
+	}
+
+	redirectTo, ok := allowedRedirect(c.Query("redirect_to"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect target is not allowed", "code": "INVALID_REDIRECT"})
+		return
+	}
+
+	if authURL, ok := h.providerAuthURL(c, provider, redirectTo, 0); ok {
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// StartProviderLink sends a logged-in user to the provider to link an account there to theirs. The
+// settings page gets here with a form post carrying a ticket from LinkIdentity, which keeps the
+// ticket out of URLs; the redirect_to field defaults to the settings page. The browser must be
+// logged in as the user the ticket was issued to, or a page could post its own ticket and link the
+// visitor's provider account to somebody else.
+func (h *AuthHandler) StartProviderLink(c *gin.Context) {
+	claims, err := h.Tokens.ParseChallenge(c.PostForm("link_token"), linkPurpose)
+	if err != nil {
+		redirectWithResult(c, "/settings", "link_error", "link_expired")
+		return
+	}
+
+	// the form post carries the auth cookie, not the Authorization header
+	user, _, err := h.authenticate(c)
+	if err != nil || user.ID != claims.UserID {
+		log.Printf("[ERROR] Rejected link ticket of user %d: not presented by that user's session", claims.UserID)
+		redirectWithResult(c, "/settings", "link_error", "auth_failed")
+		return
+	}
+
+	provider, ok := h.Providers.Provider(c.Param("provider"))
+	if !ok {
+		redirectWithResult(c, "/settings", "link_error", "provider_not_found")
+		return
+	}
+
+	target := c.PostForm("redirect_to")
+	if target == "" {
+		target = "/settings"
+	}
+	redirectTo, ok := allowedRedirect(target)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect target is not allowed", "code": "INVALID_REDIRECT"})
+		return
+	}
+
+	if authURL, ok := h.providerAuthURL(c, provider, redirectTo, claims.UserID); ok {
+		c.Redirect(http.StatusSeeOther, authURL)
+	}
+}
+
+// providerAuthURL sets the state cookie and returns the provider's sign-in URL. When it fails it
+// answers the request and returns false.
+func (h *AuthHandler) providerAuthURL(c *gin.Context, provider *oidc.Provider, redirectTo string, linkUserID uint) (string, bool) {
+	state, err := h.setOAuthState(c, provider.Name(), redirectTo, linkUserID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to start %s login: %v", provider.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login", "code": "OAUTH_START_FAILED"})
+		return "", false
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, oauth2.S256ChallengeOption(state.Verifier))
+	if err != nil {
+		log.Printf("[ERROR] Failed to start %s login: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Identity provider unavailable",
+			"message": fmt.Sprintf("We couldn't reach %s. Please try again later.", provider.DisplayName()),
+			"code":    "PROVIDER_UNAVAILABLE",
+		})
+		return "", false
+	}
+	return authURL, true
+}
+
+// finishProviderLogin handles the provider's callback. The state must match the cookie set when the
+// login started, the code is redeemed with that login's PKCE verifier and the ID token must carry
+// its nonce.
+func (h *AuthHandler) finishProviderLogin(c *gin.Context, name string) {
+	provider, ok := h.Providers.Provider(name)
+	if !ok {
+		redirectLoginError(c, "auth_failed")
+		return
+	}
+
+	state, err := h.consumeOAuthState(c, provider.Name())
+	if err != nil {
+		log.Printf("[ERROR] Rejected %s callback: %v", provider.Name(), err)
+		redirectLoginError(c, "auth_failed")
+		return
+	}
+
+	// failed links go back to the page linking was started from
+	fail := func(code string) {
+		if state.LinkUserID != 0 {
+			redirectWithResult(c, state.RedirectTo, "link_error", code)
+			return
+		}
+		redirectLoginError(c, code)
+	}
+
+	// the user declined or the provider refused the request
+	if providerErr := c.Query("error"); providerErr != "" {
+		log.Printf("[DEBUG] %s login returned error: %s", provider.Name(), providerErr)
+		fail("auth_failed")
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		log.Printf("[ERROR] No code provided in %s callback", provider.Name())
+		fail("auth_failed")
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), code, state.Nonce, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		log.Printf("[ERROR] Failed to complete %s login: %v", provider.Name(), err)
+		fail("auth_failed")
+		return
+	}
+
+	if state.LinkUserID != 0 {
+		h.finishProviderLink(c, identity, state.LinkUserID, state.RedirectTo)
+		return
+	}
+
+	user, err := h.userForIdentity(identity)
+	if errors.Is(err, errEmailNotVerified) {
+		redirectLoginError(c, "email_unverified")
+		return
+	}
+	// the owner of the account links the provider account from their settings, not whoever signs in
+	// at the provider with the same address
+	if errors.Is(err, errEmailInUse) {
+		c.Redirect(http.StatusFound, frontendBaseURL()+"/login?error=email_exists&provider="+url.QueryEscape(provider.Name()))
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to sign in %s account %s: %v", provider.Name(), identity.Subject, err)
+		redirectLoginError(c, "auth_failed")
+		return
+	}
+
+	// the two-factor check and the session happen when the frontend exchanges the code
+	h.redirectWithLoginCode(c, user.ID, state.RedirectTo)
+}
+
+// userForIdentity returns the user linked to the provider account. Unlinked accounts get a new
+// user; when their email already has an account, the owner has to link them while logged in.
+func (h *AuthHandler) userForIdentity(identity *oidc.Identity) (*entity.User, error) {
+	now := time.Now()
+
+	linked, err := h.IdentityRepo.FindBySubject(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		if err := h.IdentityRepo.RecordLogin(linked.ID, identity.Email, now); err != nil {
+			log.Printf("Failed to record login of identity %d: %v", linked.ID, err)
+		}
+		user, err := h.UserRepo.FindByID(linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %d of identity %d no longer exists", linked.UserID, linked.ID)
+		}
+		return user, nil
+	}
+
+	// an unverified address could be anybody's, it neither matches nor creates an account
+	if !identity.EmailVerified {
+		return nil, errEmailNotVerified
+	}
+
+	existing, err := h.UserRepo.FindByEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errEmailInUse
+	}
+
+	user, err := h.UserRepo.CreateUser(entity.User{
+		Email:   identity.Email,
+		Name:    identity.Name,
+		Picture: identity.Picture,
+		Status:  "active",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("[DEBUG] Created user %d from %s", user.ID, identity.Provider)
+	if err := h.createCartForUser(user.ID); err != nil {
+		log.Printf("[ERROR] Failed to create cart for new user %d: %v", user.ID, err)
+	}
+
+	if err := h.IdentityRepo.Create(&entity.UserIdentity{
+		UserID:      user.ID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	return &user, nil
+}
+
+// finishProviderLink links the provider account to the user who started linking it. An account
+// already linked to somebody else stays with them.
+func (h *AuthHandler) finishProviderLink(c *gin.Context, identity *oidc.Identity, userID uint, redirectTo string) {
+	user, err := h.UserRepo.FindByID(userID)
+	if err != nil || user == nil {
+		log.Printf("[ERROR] Failed to load user %d to link %s account: %v", userID, identity.Provider, err)
+		redirectWithResult(c, redirectTo, "link_error", "auth_failed")
+		return
+	}
+
+	linked, err := h.IdentityRepo.FindBySubject(identity.Provider, identity.Subject)
+	if err != nil {
+		log.Printf("[ERROR] Failed to look up %s account: %v", identity.Provider, err)
+		redirectWithResult(c, redirectTo, "link_error", "auth_failed")
+		return
+	}
+	if linked != nil && linked.UserID != user.ID {
+		redirectWithResult(c, redirectTo, "link_error", "identity_in_use")
+		return
+	}
+
+	if linked == nil {
+		if err := h.IdentityRepo.Create(&entity.UserIdentity{
+			UserID:    user.ID,
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			log.Printf("[ERROR] Failed to link %s account to user %d: %v", identity.Provider, user.ID, err)
+			redirectWithResult(c, redirectTo, "link_error", "auth_failed")
+			return
+		}
+		log.Printf("Linked %s account to user %d", identity.Provider, user.ID)
+	}
+
+	redirectWithResult(c, redirectTo, "linked", identity.Provider)
+}
+
+// redirectWithResult sends the browser back to a storefront page, or an allowlisted URL, with the
+// outcome of linking in its query
+func redirectWithResult(c *gin.Context, target, key, value string) {
+	if strings.HasPrefix(target, "/") {
+		target = frontendBaseURL() + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		u, _ = url.Parse(frontendBaseURL())
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, u.String())
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"backend/internal/app/lockout"
+	"backend/internal/app/webauthn"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyHandler struct {
+	Service  *webauthn.Service
+	Auth     *AuthHandler
+	UserRepo repository.UserRepository
+}
+
+func NewPasskeyHandler(service *webauthn.Service, auth *AuthHandler, userRepo repository.UserRepository) *PasskeyHandler {
+	return &PasskeyHandler{Service: service, Auth: auth, UserRepo: userRepo}
+}
+
+// passkeyError maps a failed ceremony to a response. Verification details are logged, the
+// client only learns that the passkey was not accepted.
+func passkeyError(c *gin.Context, err error, status int, code string) {
+	switch {
+	case errors.Is(err, webauthn.ErrChallengeNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Passkey request expired",
+			"message": "The passkey request has expired. Please try again.",
+			"code":    "PASSKEY_CHALLENGE_EXPIRED",
+		})
+	case errors.Is(err, webauthn.ErrCredentialExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "This passkey is already registered", "code": "PASSKEY_EXISTS"})
+	case errors.Is(err, webauthn.ErrCredentialNotFound):
+		c.JSON(status, gin.H{
+			"error":   "Passkey not recognised",
+			"message": "This passkey is not registered with any account.",
+			"code":    "PASSKEY_NOT_FOUND",
+		})
+	case errors.Is(err, webauthn.ErrUserNotVerified):
+		c.JSON(status, gin.H{
+			"error":   "Verification required",
+			"message": "Please unlock your passkey with your PIN or biometrics.",
+			"code":    "PASSKEY_USER_NOT_VERIFIED",
+		})
+	case errors.Is(err, webauthn.ErrInvalidResponse), errors.Is(err, webauthn.ErrChallengeMismatch),
+		errors.Is(err, webauthn.ErrOriginNotAllowed), errors.Is(err, webauthn.ErrRPIDMismatch),
+		errors.Is(err, webauthn.ErrUserNotPresent), errors.Is(err, webauthn.ErrBadSignature),
+		errors.Is(err, webauthn.ErrUnsupportedFormat), errors.Is(err, webauthn.ErrSignCountRegression):
+		log.Printf("Passkey verification failed: %v", err)
+		c.JSON(status, gin.H{"error": "Passkey verification failed", "code": code})
+	default:
+		log.Printf("Passkey ceremony failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Passkey request failed", "code": "DATABASE_ERROR"})
+	}
+}
+
+// GetPasskeys lists the passkeys registered to the user
+func (h *PasskeyHandler) GetPasskeys(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	passkeys, err := h.Service.List(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load passkeys", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passkeys": passkeys})
+}
+
+// BeginRegistration returns the options for navigator.credentials.create()
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	options, err := h.Service.BeginRegistration(user)
+	if err != nil {
+		log.Printf("Failed to start passkey registration for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey registration", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistration verifies the new credential and adds it to the user's account
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	var input struct {
+		Name       string                         `json:"name" binding:"max=100"`
+		Credential *webauthn.RegistrationResponse `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+
+	passkey, err := h.Service.FinishRegistration(user, input.Name, input.Credential)
+	if err != nil {
+		passkeyError(c, err, http.StatusBadRequest, "PASSKEY_REGISTRATION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Passkey added", "passkey": passkey})
+}
+
+// DeletePasskey removes one of the user's passkeys
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID", "code": "INVALID_INPUT"})
+		return
+	}
+
+	// like linked accounts, the last way to sign in stays
+	passkeys, err := h.Service.List(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete passkey", "code": "DATABASE_ERROR"})
+		return
+	}
+	identities, err := h.Auth.IdentityRepo.ListByUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete passkey", "code": "DATABASE_ERROR"})
+		return
+	}
+	if loginMethods(user, identities, passkeys) <= 1 {
+		for _, passkey := range passkeys {
+			if passkey.ID == uint(id) {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":   "Last login method",
+					"message": "This is the only way you can sign in. Add another passkey, link an account or set a password through password reset before deleting it.",
+					"code":    "LAST_LOGIN_METHOD",
+				})
+				return
+			}
+		}
+	}
+
+	deleted, err := h.Service.Delete(user.ID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete passkey", "code": "DATABASE_ERROR"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found", "code": "PASSKEY_NOT_FOUND"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey deleted"})
+}
+
+// BeginLogin returns the options for navigator.credentials.get(). With an email the browser only
+// offers that account's passkeys; without one it offers any discoverable passkey for the site.
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"omitempty,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+
+	// unknown emails get an empty allow list so the response does not reveal which accounts exist
+	var user *entity.User
+	if input.Email != "" {
+		if found, err := h.UserRepo.FindByEmail(input.Email); err == nil {
+			user = found
+		}
+	}
+
+	options, err := h.Service.BeginLogin(user)
+	if err != nil {
+		log.Printf("Failed to start passkey login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey login", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishLogin verifies the assertion and logs the user in. A passkey that verified the user counts
+// as two factors; otherwise accounts with two-factor still get the code challenge.
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var input webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+
+	// passkeys cannot be guessed, but failed assertions from one address are still slowed down
+	attempt := lockout.IP(lockout.LoginIP, c.ClientIP())
+	if throttled(c, h.Auth.Limiter.Check(attempt)) {
+		return
+	}
+
+	passkey, assertion, err := h.Service.FinishLogin(&input)
+	if err != nil {
+		h.Auth.Limiter.Fail(attempt)
+		passkeyError(c, err, http.StatusUnauthorized, "PASSKEY_AUTH_FAILED")
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(passkey.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+
+	if !assertion.UserVerified {
+		if challenged, err := h.Auth.challengeSecondFactor(&user); err != nil {
+			log.Printf("Failed to check two-factor status of user %d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Authentication failed",
+				"message": "We couldn't complete your login. Please try again later.",
+				"code":    "TWO_FACTOR_CHECK_FAILED",
+			})
+			return
+		} else if challenged != "" {
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "Enter the code from your authenticator app",
+				"mfa_token": challenged,
+				"code":      "TWO_FACTOR_REQUIRED",
+			})
+			return
+		}
+	}
+
+	h.Auth.completeLogin(c, &user)
+}
@@ -5,16 +5,42 @@ import (
 	"backend/internal/domain/repository"
 	"backend/internal/infras/interfaces"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strconv"
 )
 
 type ProductHandler struct {
-	Repo repository.ProductRepository
+	Repo       repository.ProductRepository
+	ViewRepo   repository.ProductViewRepository
+	RecentRepo repository.RecentViewRepository
+	SearchRepo repository.SearchQueryRepository
 }
 
-func NewProductHandler(repo repository.ProductRepository) *ProductHandler {
-	return &ProductHandler{Repo: repo}
+func NewProductHandler(repo repository.ProductRepository, viewRepo repository.ProductViewRepository, recentRepo repository.RecentViewRepository, searchRepo repository.SearchQueryRepository) *ProductHandler {
+	return &ProductHandler{Repo: repo, ViewRepo: viewRepo, RecentRepo: recentRepo, SearchRepo: searchRepo}
+}
+
+// recordView stores the product view and bumps the viewer's recently viewed list in the
+// background so it never slows down the response
+func (p *ProductHandler) recordView(c *gin.Context, productID uint) {
+	view := &entity.ProductView{
+		UserID:    currentUserID(c),
+		SessionID: currentVisitorID(c),
+		ProductID: productID,
+	}
+	if view.UserID == nil && view.SessionID == "" {
+		return
+	}
+
+	go func() {
+		if err := p.ViewRepo.RecordView(view); err != nil {
+			log.Printf("Failed to record view of product %d: %v", productID, err)
+		}
+		if err := p.RecentRepo.Touch(view.UserID, view.SessionID, productID); err != nil {
+			log.Printf("Failed to update recently viewed products: %v", err)
+		}
+	}()
 }
 
 func (p *ProductHandler) GetProducts(c *gin.Context) {
@@ -39,11 +65,47 @@ func (p *ProductHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	if product == nil {
+	if product == nil || !product.IsAvailable() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
+	p.recordView(c, product.ID)
+	c.JSON(http.StatusOK, product)
+}
+
+// GetProductBySlug looks a product up by slug. Old slugs redirect permanently to the current one,
+// and numeric IDs are accepted for links created before slugs existed.
+func (p *ProductHandler) GetProductBySlug(c *gin.Context) {
+	key := c.Param("slug")
+	id, convErr := strconv.ParseUint(key, 10, 32)
+	byID := convErr == nil && id > 0
+
+	var product *entity.Product
+	var err error
+	if byID {
+		// FindByID reports a missing product as an error
+		product, _ = p.Repo.FindByID(uint(id))
+	} else {
+		product, err = p.Repo.FindBySlug(key)
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
+	}
+
+	if product == nil || !product.IsAvailable() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	if !byID && product.Slug != key {
+		c.Redirect(http.StatusMovedPermanently, product.CanonicalPath)
+		return
+	}
+
+	p.recordView(c, product.ID)
 	c.JSON(http.StatusOK, product)
 }
 
@@ -62,5 +124,40 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	h.logSearch(c, filter, len(products))
 	c.JSON(http.StatusOK, products)
 }
+
+// logSearch stores the search for suggestions and analytics. The search ID is returned in the
+// X-Search-Id header so the client can report which result was clicked; it is only handed out
+// once the search is stored, so a quick click can't miss it.
+func (h *ProductHandler) logSearch(c *gin.Context, filter interfaces.ProductFilter, resultCount int) {
+	normalized := entity.NormalizeSearchQuery(filter.Name)
+	if normalized == "" && filter.Description == "" && filter.MinPrice <= 0 && filter.MaxPrice <= 0 {
+		return
+	}
+
+	searchID, err := generateToken(16)
+	if err != nil {
+		log.Printf("Failed to generate search ID: %v", err)
+		return
+	}
+	entry := &entity.SearchQuery{
+		SearchID:    searchID,
+		Query:       filter.Name,
+		Normalized:  normalized,
+		Description: filter.Description,
+		MinPrice:    filter.MinPrice,
+		MaxPrice:    filter.MaxPrice,
+		UserID:      currentUserID(c),
+		SessionID:   currentVisitorID(c),
+		ResultCount: resultCount,
+	}
+
+	if err := h.SearchRepo.LogQuery(entry); err != nil {
+		log.Printf("Failed to log search query %q: %v", normalized, err)
+		return
+	}
+	c.Header("X-Search-Id", searchID)
+}
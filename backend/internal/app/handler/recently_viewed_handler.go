@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"backend/internal/domain/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecentlyViewedHandler struct {
+	Repo        repository.RecentViewRepository
+	ProductRepo repository.ProductRepository
+}
+
+func NewRecentlyViewedHandler(repo repository.RecentViewRepository, productRepo repository.ProductRepository) *RecentlyViewedHandler {
+	return &RecentlyViewedHandler{
+		Repo:        repo,
+		ProductRepo: productRepo,
+	}
+}
+
+// GetRecentlyViewed returns the current user's recently viewed products, or the anonymous
+// session's when nobody is logged in. Products that are no longer available are skipped.
+func (h *RecentlyViewedHandler) GetRecentlyViewed(c *gin.Context) {
+	userID := currentUserID(c)
+	sessionID := currentVisitorID(c)
+	if userID == nil && sessionID == "" {
+		c.JSON(http.StatusOK, gin.H{"products": []interface{}{}})
+		return
+	}
+
+	entries, err := h.Repo.List(userID, sessionID, queryLimit(c, repository.MaxRecentViews, repository.MaxRecentViews))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recently viewed products"})
+		return
+	}
+
+	ids := make([]uint, 0, len(entries))
+	viewedAt := make(map[uint]interface{}, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ProductID)
+		viewedAt[entry.ProductID] = entry.ViewedAt
+	}
+
+	products, err := h.ProductRepo.FindActiveByIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recently viewed products"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(products))
+	for _, product := range products {
+		items = append(items, gin.H{
+			"product":   product,
+			"viewed_at": viewedAt[product.ID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": items})
+}
+
+// ClearRecentlyViewed empties the current user's (or anonymous session's) list
+func (h *RecentlyViewedHandler) ClearRecentlyViewed(c *gin.Context) {
+	userID := currentUserID(c)
+	sessionID := currentVisitorID(c)
+	if userID == nil && sessionID == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Recently viewed products cleared"})
+		return
+	}
+
+	if err := h.Repo.Clear(userID, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear recently viewed products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recently viewed products cleared"})
+}
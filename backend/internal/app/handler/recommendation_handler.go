@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"backend/internal/app/recommend"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRelatedLimit = 8
+	defaultTopLimit     = 10
+	defaultTopDays      = 30
+)
+
+type RecommendationHandler struct {
+	ProductRepo repository.ProductRepository
+	Service     *recommend.Service
+}
+
+func NewRecommendationHandler(productRepo repository.ProductRepository, service *recommend.Service) *RecommendationHandler {
+	return &RecommendationHandler{
+		ProductRepo: productRepo,
+		Service:     service,
+	}
+}
+
+// GetRelated returns products related to the given product (by ID or slug),
+// blending "customers also bought" with content similarity
+func (h *RecommendationHandler) GetRelated(c *gin.Context) {
+	product := h.findProduct(c.Param("slug"))
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	limit := queryLimit(c, defaultRelatedLimit, 20)
+	scored := h.Service.Related(product.ID, limit)
+
+	ids := make([]uint, 0, len(scored))
+	for _, s := range scored {
+		ids = append(ids, s.ProductID)
+	}
+
+	products, err := h.ProductRepo.FindActiveByIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": product.ID,
+		"products":   products,
+	})
+}
+
+// GetTopProducts returns the bestsellers over the last ?days= days (7, 30, 90 or 365)
+func (h *RecommendationHandler) GetTopProducts(c *gin.Context) {
+	days := defaultTopDays
+	if v := c.Query("days"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+			return
+		}
+		days = parsed
+	}
+
+	sales, ok := h.Service.Top(days, queryLimit(c, defaultTopLimit, 50))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported window. Use days=7, 30, 90 or 365",
+		})
+		return
+	}
+
+	ids := make([]uint, 0, len(sales))
+	for _, s := range sales {
+		ids = append(ids, s.ProductID)
+	}
+
+	products, err := h.ProductRepo.FindActiveByIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch top products"})
+		return
+	}
+
+	byID := make(map[uint]entity.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	result := make([]gin.H, 0, len(products))
+	for _, s := range sales {
+		p, ok := byID[s.ProductID]
+		if !ok {
+			continue
+		}
+		result = append(result, gin.H{
+			"product": p,
+			"units":   s.Units,
+			"orders":  s.Orders,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":         days,
+		"products":     result,
+		"refreshed_at": h.Service.RefreshedAt(),
+	})
+}
+
+// GetUserRecommendations returns personalised recommendations for the logged-in user,
+// falling back to popular products for users without history
+func (h *RecommendationHandler) GetUserRecommendations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+			"code":  "NOT_AUTHENTICATED",
+		})
+		return
+	}
+
+	scored, personalised, err := h.Service.ForUser(user.ID, queryLimit(c, defaultRelatedLimit, 50))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute recommendations"})
+		return
+	}
+
+	ids := make([]uint, 0, len(scored))
+	for _, s := range scored {
+		ids = append(ids, s.ProductID)
+	}
+
+	products, err := h.ProductRepo.FindActiveByIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"personalised": personalised,
+		"products":     products,
+	})
+}
+
+// findProduct resolves a numeric ID or a slug to an available product
+func (h *RecommendationHandler) findProduct(key string) *entity.Product {
+	var product *entity.Product
+	if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+		product, _ = h.ProductRepo.FindByID(uint(id))
+	} else {
+		product, _ = h.ProductRepo.FindBySlug(key)
+	}
+
+	if product == nil || !product.IsAvailable() {
+		return nil
+	}
+	return product
+}
+
+// queryLimit reads ?limit=, falling back to def and capping at max
+func queryLimit(c *gin.Context, def, max int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"backend/internal/app/export"
+	"backend/internal/app/search"
+	"backend/internal/domain/repository"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit    = 5
+	defaultQueryStatsLimit = 50
+	defaultQueryStatsDays  = 30
+)
+
+type SearchHandler struct {
+	Suggester *search.Suggester
+	QueryRepo repository.SearchQueryRepository
+}
+
+func NewSearchHandler(suggester *search.Suggester, queryRepo repository.SearchQueryRepository) *SearchHandler {
+	return &SearchHandler{
+		Suggester: suggester,
+		QueryRepo: queryRepo,
+	}
+}
+
+// Suggest returns autocomplete suggestions for ?q=: matching product names and popular past queries
+func (h *SearchHandler) Suggest(c *gin.Context) {
+	suggestions, err := h.Suggester.Suggest(c.Query("q"), queryLimit(c, defaultSuggestLimit, 20))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suggestions"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// RecordClick attributes a click on a search result to the search that produced it.
+// The search ID comes from the X-Search-Id header of the search response.
+func (h *SearchHandler) RecordClick(c *gin.Context) {
+	var input struct {
+		SearchID  string `json:"search_id" binding:"required"`
+		ProductID uint   `json:"product_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error(), "code": "INVALID_INPUT"})
+		return
+	}
+
+	if err := h.QueryRepo.RecordClick(input.SearchID, input.ProductID); err != nil {
+		if errors.Is(err, repository.ErrSearchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Search not found", "code": "SEARCH_NOT_FOUND"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record click"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Click recorded"})
+}
+
+// GetPopularQueries returns the most frequent searches that found products over the last ?days= days
+func (h *SearchHandler) GetPopularQueries(c *gin.Context) {
+	days := queryDays(c, defaultQueryStatsDays)
+	stats, err := h.QueryRepo.PopularQueries(time.Now().AddDate(0, 0, -days), queryLimit(c, defaultQueryStatsLimit, 500))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch popular queries: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":    days,
+		"queries": stats,
+	})
+}
+
+// GetZeroResultQueries returns the most frequent searches that found nothing over the last ?days= days
+func (h *SearchHandler) GetZeroResultQueries(c *gin.Context) {
+	days := queryDays(c, defaultQueryStatsDays)
+	stats, err := h.QueryRepo.ZeroResultQueries(time.Now().AddDate(0, 0, -days), queryLimit(c, defaultQueryStatsLimit, 500))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch zero-result queries: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":    days,
+		"queries": stats,
+	})
+}
+
+// GetSearchReport returns the search analytics for ?from= to ?to=: totals, top queries and
+// zero-result queries with click-through rate. ?format=csv downloads the same data as CSV.
+func (h *SearchHandler) GetSearchReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c, defaultQueryStatsDays)
+	if !ok {
+		return
+	}
+	limit := queryLimit(c, defaultQueryStatsLimit, 1000)
+
+	totals, err := h.QueryRepo.Totals(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build search report: " + err.Error()})
+		return
+	}
+	top, err := h.QueryRepo.TopQueries(from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build search report: " + err.Error()})
+		return
+	}
+	zero, err := h.QueryRepo.TopZeroResultQueries(from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build search report: " + err.Error()})
+		return
+	}
+
+	lastDay := to.AddDate(0, 0, -1).Format(dateLayout)
+	if strings.ToLower(c.Query("format")) == "csv" {
+		filename := fmt.Sprintf("search-report-%s-%s.csv", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102"))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"section", "query", "searches", "zero_result_searches", "clicks", "ctr", "avg_results"})
+		w.Write([]string{"total", "", formatInt(totals.Searches), formatInt(totals.ZeroResultSearches), formatInt(totals.Clicks), formatRatio(totals.CTR), ""})
+		for _, row := range top {
+			w.Write(queryReportRecord("top", row))
+		}
+		for _, row := range zero {
+			w.Write(queryReportRecord("zero_results", row))
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":                from.Format(dateLayout),
+		"to":                  lastDay,
+		"totals":              totals,
+		"top_queries":         top,
+		"zero_result_queries": zero,
+	})
+}
+
+// queryReportRecord is a CSV row of the search report. Customers typed the query, so it is kept
+// from running as a formula in the admin's spreadsheet.
+func queryReportRecord(section string, row repository.QueryReportRow) []string {
+	return []string{
+		section,
+		export.NeutralizeFormula(row.Query),
+		formatInt(row.Searches),
+		formatInt(row.ZeroResultSearches),
+		formatInt(row.Clicks),
+		formatRatio(row.CTR),
+		strconv.FormatFloat(row.AvgResults, 'f', 2, 64),
+	}
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func formatRatio(r float64) string {
+	return strconv.FormatFloat(r, 'f', 4, 64)
+}
+
+// queryDays parses ?days=, between 1 and 365
+func queryDays(c *gin.Context, def int) int {
+	days, err := strconv.Atoi(c.Query("days"))
+	if err != nil || days <= 0 {
+		return def
+	}
+	if days > 365 {
+		return 365
+	}
+	return days
+}
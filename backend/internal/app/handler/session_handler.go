@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"backend/internal/domain/repository"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	Repo repository.SessionRepository
+}
+
+func NewSessionHandler(repo repository.SessionRepository) *SessionHandler {
+	return &SessionHandler{Repo: repo}
+}
+
+// GetSessions lists the devices the user is logged in on. The session making the request is
+// flagged as current.
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	sessions, err := h.Repo.ListActive(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sessions", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	current := currentSessionID(c)
+	items := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, gin.H{
+			"id":           s.ID,
+			"device":       s.Device,
+			"ip":           s.IP,
+			"user_agent":   s.UserAgent,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"current":      s.ID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": items})
+}
+
+// RevokeSession logs one of the user's devices out
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	session, err := h.Repo.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session", "code": "DATABASE_ERROR"})
+		return
+	}
+	// other users' sessions are reported as missing rather than forbidden
+	if session == nil || session.UserID != user.ID || session.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found", "code": "SESSION_NOT_FOUND"})
+		return
+	}
+
+	if err := h.Repo.Revoke(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	if session.ID == currentSessionID(c) {
+		clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked", "code": "SESSION_REVOKED"})
+}
+
+// RevokeOtherSessions logs the user out everywhere except the device making the request
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	n, err := h.Repo.RevokeAll(user.ID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": n, "code": "SESSIONS_REVOKED"})
+}
+
+// deviceLabel turns a user agent into a short "Browser on OS" description for the sessions list
+func deviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "postman"), strings.Contains(ua, "go-http-client"):
+		browser = "API client"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "cros "):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
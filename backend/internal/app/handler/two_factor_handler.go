@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"backend/internal/app/lockout"
+	"backend/internal/app/twofactor"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorPurpose is the challenge token purpose of the second login step
+const twoFactorPurpose = "2fa"
+
+// challengeSecondFactor returns a challenge token when the user has two-factor enabled, or ""
+// when the password alone completes the login
+func (h *AuthHandler) challengeSecondFactor(user *entity.User) (string, error) {
+	enabled, err := h.TwoFactor.Enabled(user.ID)
+	if err != nil || !enabled {
+		return "", err
+	}
+	return h.Tokens.IssueChallenge(user.ID, user.Email, twoFactorPurpose)
+}
+
+func (h *AuthHandler) twoFactorEnabled(userID uint) bool {
+	enabled, err := h.TwoFactor.Enabled(userID)
+	if err != nil {
+		log.Printf("Failed to check two-factor status of user %d: %v", userID, err)
+	}
+	return enabled
+}
+
+// LoginTwoFactor is the second login step. It takes the challenge token from the first step and a
+// TOTP code or a recovery code.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var input struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid input",
+			"message": "Please enter the code from your authenticator app.",
+			"code":    "INVALID_INPUT",
+		})
+		return
+	}
+
+	claims, err := h.Tokens.ParseChallenge(input.MFAToken, twoFactorPurpose)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Login expired",
+			"message": "Your login attempt has expired. Please log in again.",
+			"code":    "MFA_TOKEN_INVALID",
+		})
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+
+	// code guesses count against the same account as password guesses
+	attempt := []lockout.Key{lockout.Email(lockout.LoginAccount, user.Email), lockout.IP(lockout.LoginIP, c.ClientIP())}
+	if throttled(c, h.Limiter.Check(attempt...)) {
+		return
+	}
+
+	if err := h.TwoFactor.Verify(user.ID, input.Code); err != nil {
+		if errors.Is(err, twofactor.ErrInvalidCode) || errors.Is(err, twofactor.ErrNotEnabled) {
+			if throttled(c, h.Limiter.Fail(attempt...)) {
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Invalid code",
+				"message": "The code is invalid or has already been used.",
+				"code":    "INVALID_TWO_FACTOR_CODE",
+			})
+			return
+		}
+		log.Printf("Failed to verify two-factor code of user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code", "code": "TWO_FACTOR_CHECK_FAILED"})
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
+
+type TwoFactorHandler struct {
+	Service  *twofactor.Service
+	UserRepo repository.UserRepository
+	Limiter  *lockout.Limiter
+}
+
+func NewTwoFactorHandler(service *twofactor.Service, userRepo repository.UserRepository, limiter *lockout.Limiter) *TwoFactorHandler {
+	return &TwoFactorHandler{Service: service, UserRepo: userRepo, Limiter: limiter}
+}
+
+// GetStatus reports whether two-factor is enabled and how many recovery codes are left
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	status, err := h.Service.Status(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load two-factor status", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":             status.Enabled,
+		"enabled_at":          status.EnabledAt,
+		"recovery_codes_left": status.RecoveryCodesLeft,
+		"required":            h.Service.Required(user.Role),
+	})
+}
+
+// Setup starts enrolment and returns the secret with its otpauth:// provisioning URI for the QR code
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	enrolment, err := h.Service.BeginEnrolment(user.ID, user.Email)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled", "code": "TWO_FACTOR_ALREADY_ENABLED"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup", "code": "TWO_FACTOR_SETUP_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           enrolment.Secret,
+		"provisioning_uri": enrolment.URI,
+	})
+}
+
+// Verify confirms enrolment with a code from the authenticator app and returns the recovery codes
+func (h *TwoFactorHandler) Verify(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required", "code": "INVALID_INPUT"})
+		return
+	}
+
+	codes, err := h.Service.ConfirmEnrolment(user.ID, input.Code)
+	switch {
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code", "code": "INVALID_TWO_FACTOR_CODE"})
+		return
+	case errors.Is(err, twofactor.ErrNoEnrolment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor setup has not been started", "code": "TWO_FACTOR_NOT_STARTED"})
+		return
+	case errors.Is(err, twofactor.ErrAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled", "code": "TWO_FACTOR_ALREADY_ENABLED"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication", "code": "TWO_FACTOR_SETUP_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+		"code":           "TWO_FACTOR_ENABLED",
+	})
+}
+
+// reauthenticate checks the password again before a sensitive change. Accounts created through
+// an identity provider have no password and confirm with a current two-factor code instead.
+// Wrong guesses count against the account's login lockout. When the check fails it answers the
+// request and returns false.
+func (h *TwoFactorHandler) reauthenticate(c *gin.Context, user *entity.User, password, code string) bool {
+	attempt := lockout.Email(lockout.LoginAccount, user.Email)
+	if throttled(c, h.Limiter.Check(attempt)) {
+		return false
+	}
+
+	var ok bool
+	if user.Password != "" {
+		ok = password != "" && checkPasswordHash(password, user.Password)
+	} else {
+		ok = code != "" && h.Service.VerifyCode(user.ID, code) == nil
+	}
+	if !ok {
+		if throttled(c, h.Limiter.Fail(attempt)) {
+			return false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect", "code": "INVALID_PASSWORD"})
+		return false
+	}
+
+	h.Limiter.Succeed(attempt)
+	return true
+}
+
+// Disable turns two-factor off after the password is re-entered
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	current, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	var input struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+
+	if h.Service.Required(current.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for administrators", "code": "TWO_FACTOR_REQUIRED_FOR_ROLE"})
+		return
+	}
+
+	// the context user may be stale, the password hash is read fresh
+	user, err := h.UserRepo.GetUserByID(current.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+	if !h.reauthenticate(c, &user, input.Password, input.Code) {
+		return
+	}
+
+	if err := h.Service.Disable(user.ID); err != nil {
+		if errors.Is(err, twofactor.ErrNotEnabled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled", "code": "TWO_FACTOR_NOT_ENABLED"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled", "code": "TWO_FACTOR_DISABLED"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after the password is re-entered
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	current, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required", "code": "AUTH_REQUIRED"})
+		return
+	}
+
+	var input struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "code": "INVALID_INPUT"})
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(current.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+		return
+	}
+	if !h.reauthenticate(c, &user, input.Password, input.Code) {
+		return
+	}
+
+	codes, err := h.Service.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrNotEnabled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled", "code": "TWO_FACTOR_NOT_ENABLED"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes", "code": "DATABASE_ERROR"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// AdminTwoFactorMiddleware blocks the admin area for admins without two-factor when it is
+// required for their role. It runs after AdminMiddleware.
+func (h *TwoFactorHandler) AdminTwoFactorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok || !h.Service.Required(user.Role) {
+			c.Next()
+			return
+		}
+
+		enabled, err := h.Service.Enabled(user.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor status", "code": "DATABASE_ERROR"})
+			return
+		}
+		if !enabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Two-factor authentication required",
+				"message": "Administrators must enable two-factor authentication before using the admin area.",
+				"code":    "TWO_FACTOR_SETUP_REQUIRED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"backend/internal/domain/entity"
 	"backend/internal/domain/models"
 	"backend/internal/domain/repository"
-	"log"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -13,28 +13,15 @@ import (
 )
 
 type UserHandler struct {
-	Repo repository.UserRepository
-}
-
-// Updated to use gin context
-func (h *UserHandler) GetUsers(c *gin.Context) {
-	users, err := h.Repo.GetAllUsers()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get users",
-			"code":  "DATABASE_ERROR",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, users)
+	Repo     repository.UserRepository
+	CartRepo repository.CartRepository
 }
 
 // UpdateProfile updates the user's profile information
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	// Get user ID from the authenticated context
-	userID, exists := c.Get("userID")
-	if !exists {
+	current, ok := currentUser(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Authentication required",
 			"message": "You must be logged in to update your profile.",
@@ -61,7 +48,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	// Get current user
-	user, err := h.Repo.GetUserByID(userID.(uint))
+	user, err := h.Repo.GetUserByID(current.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "User not found",
@@ -90,28 +77,17 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		Picture: user.Picture,
 	}
 
-	if user.GoogleID != nil {
-		modelUser.GoogleID = *user.GoogleID
-	}
-
 	if user.Password != "" {
 		modelUser.Password = user.Password
 	}
 
 	// Update profile information in database
-	googleID := modelUser.GoogleID
-	var googleIDPtr *string
-	if googleID != "" {
-		googleIDPtr = &googleID
-	}
-
 	if err := h.Repo.UpdateUser(entity.User{
 		Model:    gorm.Model{ID: modelUser.ID},
 		Email:    modelUser.Email,
 		Name:     modelUser.Name,
 		Status:   modelUser.Status,
 		Picture:  modelUser.Picture,
-		GoogleID: googleIDPtr,
 		Password: modelUser.Password,
 	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -134,10 +110,10 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	})
 }
 
-// GetUserOrders retrieves the order history for a user
+// GetUserOrders returns the current user's orders, newest first, with a link to each invoice
 func (h *UserHandler) GetUserOrders(c *gin.Context) {
-	userID, exists := c.Get("userID")
-	if !exists {
+	user, ok := currentUser(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Authentication required",
 			"message": "You must be logged in to view your orders.",
@@ -146,54 +122,52 @@ func (h *UserHandler) GetUserOrders(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Fetching orders for user ID: %v", userID)
-	sampleItems1 := []gin.H{
-		{
-			"id":           1,
-			"product_id":   101,
-			"product_name": "Gaming Chair",
-			"quantity":     1,
-			"price":        199.99,
-			"total":        199.99,
-		},
-		{
-			"id":           2,
-			"product_id":   102,
-			"product_name": "Mechanical Keyboard",
-			"quantity":     1,
-			"price":        89.99,
-			"total":        89.99,
-		},
-	}
-
-	sampleItems2 := []gin.H{
-		{
-			"id":           3,
-			"product_id":   103,
-			"product_name": "Gaming Mouse",
-			"quantity":     1,
-			"price":        49.99,
-			"total":        49.99,
-		},
+	orders, err := h.CartRepo.GetCompletedCartsByUserID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get orders",
+			"code":  "DATABASE_ERROR",
+		})
+		return
 	}
 
-	// Sample orders
-	sampleOrders := []gin.H{
-		{
-			"id":         1001,
-			"created_at": time.Now().AddDate(0, 0, -5).Format(time.RFC3339),
-			"status":     "Completed",
-			"total":      289.98,
-			"items":      sampleItems1,
-		},
-		{
-			"id":         1002,
-			"created_at": time.Now().AddDate(0, 0, -1).Format(time.RFC3339),
-			"status":     "Processing",
-			"total":      49.99,
-			"items":      sampleItems2,
-		},
+	result := make([]gin.H, 0, len(orders))
+	for _, order := range orders {
+		items := make([]gin.H, 0, len(order.CartItems))
+		total := 0.0
+		for _, item := range order.CartItems {
+			price := item.UnitPrice
+			if price <= 0 {
+				price = item.Product.Price
+			}
+			lineTotal := float64(item.Quantity) * price
+			total += lineTotal
+
+			items = append(items, gin.H{
+				"id":           item.ID,
+				"product_id":   item.ProductID,
+				"product_name": item.Product.Name,
+				"quantity":     item.Quantity,
+				"price":        price,
+				"total":        lineTotal,
+			})
+		}
+
+		orderedAt := order.UpdatedAt
+		if order.OrderedAt != nil {
+			orderedAt = *order.OrderedAt
+		}
+
+		result = append(result, gin.H{
+			"id":           order.ID,
+			"order_number": order.OrderNumber,
+			"created_at":   orderedAt.Format(time.RFC3339),
+			"status":       entity.OrderStatusLabel(order.Status),
+			"total":        total,
+			"items":        items,
+			"invoice_url":  fmt.Sprintf("/user/orders/%d/invoice", order.ID),
+		})
 	}
 
-	c.JSON(http.StatusOK, sampleOrders)
+	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// visitorCookie identifies an anonymous browser session across requests
+	visitorCookie = "visitor_id"
+	visitorMaxAge = 3600 * 24 * 365 // 1 year
+)
+
+// VisitorMiddleware makes sure every client carries a visitor ID cookie, so activity
+// can be attributed to anonymous sessions before the user logs in
+func VisitorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		visitorID, err := c.Cookie(visitorCookie)
+		if err != nil || len(visitorID) != 32 {
+			visitorID, err = generateToken(16)
+			if err != nil {
+				log.Printf("[VISITOR] Failed to generate visitor ID: %v", err)
+				c.Next()
+				return
+			}
+			c.SetCookie(visitorCookie, visitorID, visitorMaxAge, "/", "", false, true)
+		}
+
+		c.Set("visitor_id", visitorID)
+		c.Next()
+	}
+}
+
+// currentVisitorID returns the anonymous visitor ID set by VisitorMiddleware
+func currentVisitorID(c *gin.Context) string {
+	return c.GetString("visitor_id")
+}
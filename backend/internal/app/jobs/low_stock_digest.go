@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"backend/internal/domain/repository"
+	"backend/pkg/utils"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// LowStockDigest emails every admin the list of products at or below their reorder threshold
+type LowStockDigest struct {
+	ProductRepo repository.ProductRepository
+	UserRepo    repository.UserRepository
+}
+
+func NewLowStockDigest(productRepo repository.ProductRepository, userRepo repository.UserRepository) *LowStockDigest {
+	return &LowStockDigest{
+		ProductRepo: productRepo,
+		UserRepo:    userRepo,
+	}
+}
+
+// Run builds and sends the digest. Nothing is sent when no product is low on stock.
+func (j *LowStockDigest) Run() error {
+	products, err := j.ProductRepo.FindLowStockProducts()
+	if err != nil {
+		return fmt.Errorf("failed to load low-stock products: %w", err)
+	}
+
+	if len(products) == 0 {
+		return nil
+	}
+
+	admins, err := j.UserRepo.GetUsersByRole("admin")
+	if err != nil {
+		return fmt.Errorf("failed to load admins: %w", err)
+	}
+
+	recipients := make([]string, 0, len(admins))
+	for _, admin := range admins {
+		recipients = append(recipients, admin.Email)
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	var rows strings.Builder
+	outOfStock := 0
+	for _, p := range products {
+		if p.Stock <= 0 {
+			outOfStock++
+		}
+		fmt.Fprintf(&rows, "<tr><td>%d</td><td>%s</td><td>%d</td><td>%d</td></tr>",
+			p.ID, html.EscapeString(p.Name), p.Stock, p.ReorderThreshold)
+	}
+
+	subject := fmt.Sprintf("Low stock digest - %d products need attention", len(products))
+
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Low Stock Digest</title>
+</head>
+<body style="font-family: Arial, sans-serif; color: #333;">
+    <h2>Low Stock Digest - %s</h2>
+    <p>%d products are at or below their reorder threshold, %d of them are out of stock.</p>
+    <table border="1" cellpadding="6" cellspacing="0">
+        <tr><th>ID</th><th>Product</th><th>Stock</th><th>Reorder threshold</th></tr>
+        %s
+    </table>
+    <p style="font-size: 12px; color: #777;">© 2025 Hidden Score. All rights reserved.</p>
+</body>
+</html>
+`, time.Now().Format("2006-01-02"), len(products), outOfStock, rows.String())
+
+	// one message per admin, so no admin sees the others' addresses
+	var sendErrs []error
+	for _, recipient := range recipients {
+		if err := utils.SendEmail([]string{recipient}, subject, body); err != nil {
+			sendErrs = append(sendErrs, fmt.Errorf("failed to send digest to %s: %w", recipient, err))
+		}
+	}
+	return errors.Join(sendErrs...)
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"backend/internal/domain/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RecentViewPrune drops recently-viewed entries that have not been touched within the retention period
+type RecentViewPrune struct {
+	Repo      repository.RecentViewRepository
+	Retention time.Duration
+}
+
+func NewRecentViewPrune(repo repository.RecentViewRepository, retention time.Duration) *RecentViewPrune {
+	return &RecentViewPrune{
+		Repo:      repo,
+		Retention: retention,
+	}
+}
+
+func (j *RecentViewPrune) Run() error {
+	n, err := j.Repo.Prune(time.Now().Add(-j.Retention))
+	if err != nil {
+		return fmt.Errorf("failed to prune recently viewed products: %w", err)
+	}
+	if n > 0 {
+		log.Printf("[JOB] Pruned %d recently viewed entries", n)
+	}
+	return nil
+}
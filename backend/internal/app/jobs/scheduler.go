@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Now runs fn once in the background, e.g. to warm a cache at startup
+func Now(name string, fn func() error) {
+	go run(name, fn)
+}
+
+// Every runs fn in the background at a fixed interval until the process exits
+func Every(name string, interval time.Duration, fn func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			run(name, fn)
+		}
+	}()
+}
+
+// Daily runs fn in the background once a day at the given hour (server local time)
+func Daily(name string, hour int, fn func() error) {
+	go func() {
+		for {
+			time.Sleep(time.Until(nextDailyRun(time.Now(), hour)))
+			run(name, fn)
+		}
+	}()
+}
+
+// nextDailyRun returns the next time after now at which a daily job scheduled at hour should run
+func nextDailyRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func run(name string, fn func() error) {
+	start := time.Now()
+	if err := fn(); err != nil {
+		log.Printf("[JOB] %s failed after %s: %v", name, time.Since(start), err)
+		return
+	}
+	log.Printf("[JOB] %s completed in %s", name, time.Since(start))
+}
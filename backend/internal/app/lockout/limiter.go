@@ -0,0 +1,239 @@
+package lockout
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scopes of the counters. Account scopes are keyed by email, IP scopes by client address.
+const (
+	LoginAccount = "login:account"
+	LoginIP      = "login:ip"
+	ResetAccount = "reset:account"
+	ResetIP      = "reset:ip"
+	ResetTokenIP = "reset-token:ip"
+)
+
+// Policy decides how a scope reacts to failures. After FreeAttempts failures each further attempt
+// must wait BaseDelay, doubling per failure up to MaxDelay. After LockAfter failures the key is
+// locked for LockDuration. Failures older than Window are forgotten.
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// LockAfter is the number of failures that locks the key, 0 never locks
+	LockAfter    int
+	LockDuration time.Duration
+	Window       time.Duration
+}
+
+// delay is the wait required after the given number of failures
+func (p Policy) delay(failures int) time.Duration {
+	n := failures - p.FreeAttempts
+	if n <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// DefaultPolicies are strict for accounts, where one person makes few attempts, and lenient for
+// IP addresses, which may be shared by an office or a mobile carrier
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		LoginAccount: {FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second, LockAfter: 10, LockDuration: 15 * time.Minute, Window: time.Hour},
+		LoginIP:      {FreeAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute, LockAfter: 100, LockDuration: time.Hour, Window: time.Hour},
+		ResetAccount: {FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: 15 * time.Minute, LockAfter: 10, LockDuration: time.Hour, Window: time.Hour},
+		ResetIP:      {FreeAttempts: 10, BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Minute, LockAfter: 50, LockDuration: time.Hour, Window: time.Hour},
+		ResetTokenIP: {FreeAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute, LockAfter: 20, LockDuration: time.Hour, Window: time.Hour},
+	}
+}
+
+// PoliciesFromEnv returns the default policies with the account lockout taken from
+// LOGIN_LOCKOUT_THRESHOLD and LOGIN_LOCKOUT_MINUTES when set
+func PoliciesFromEnv() map[string]Policy {
+	policies := DefaultPolicies()
+	account := policies[LoginAccount]
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_THRESHOLD")); err == nil && v >= 0 {
+		account.LockAfter = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_MINUTES")); err == nil && v > 0 {
+		account.LockDuration = time.Duration(v) * time.Minute
+	}
+	policies[LoginAccount] = account
+	return policies
+}
+
+// Key identifies one counter
+type Key struct {
+	Scope string
+	Value string
+}
+
+// Email keys an account scope. Addresses are compared case-insensitively so the counter cannot be
+// dodged by changing case.
+func Email(scope, email string) Key {
+	return Key{Scope: scope, Value: strings.ToLower(strings.TrimSpace(email))}
+}
+
+// IP keys an IP scope
+func IP(scope, ip string) Key {
+	return Key{Scope: scope, Value: ip}
+}
+
+// Decision is whether an attempt may go ahead. When it may not, RetryAfter says how long to wait
+// and Locked whether the wait is a lockout rather than a delay.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Locked     bool
+	Scope      string
+}
+
+// stricter returns whichever decision blocks for longer
+func (d Decision) stricter(other Decision) Decision {
+	if other.Allowed {
+		return d
+	}
+	if d.Allowed || other.Locked && !d.Locked || other.RetryAfter > d.RetryAfter && other.Locked == d.Locked {
+		return other
+	}
+	return d
+}
+
+// Limiter tracks failed attempts and slows down and locks out the keys that keep failing
+type Limiter struct {
+	Store    repository.LoginThrottleRepository
+	Policies map[string]Policy
+	// OnLock is called once when a key becomes locked. It runs on the request path, so it should
+	// hand slow work such as sending mail to a goroutine.
+	OnLock func(key Key, until time.Time)
+
+	now func() time.Time
+}
+
+func NewLimiter(store repository.LoginThrottleRepository, policies map[string]Policy) *Limiter {
+	return &Limiter{Store: store, Policies: policies, now: time.Now}
+}
+
+func (l *Limiter) decide(t *entity.LoginThrottle, p Policy, now time.Time) Decision {
+	if t == nil {
+		return Decision{Allowed: true}
+	}
+	if t.LockedUntil != nil && t.LockedUntil.After(now) {
+		return Decision{RetryAfter: t.LockedUntil.Sub(now), Locked: true, Scope: t.Scope}
+	}
+	if p.Window > 0 && t.LastFailureAt.Before(now.Add(-p.Window)) {
+		return Decision{Allowed: true}
+	}
+	if next := t.LastFailureAt.Add(p.delay(t.Failures)); now.Before(next) {
+		return Decision{RetryAfter: next.Sub(now), Scope: t.Scope}
+	}
+	return Decision{Allowed: true}
+}
+
+// Check reports whether an attempt for all the keys may go ahead. Storage errors are logged and
+// let the attempt through, an unavailable counter should not lock everybody out.
+func (l *Limiter) Check(keys ...Key) Decision {
+	now := l.now()
+	decision := Decision{Allowed: true}
+	for _, key := range keys {
+		policy, ok := l.Policies[key.Scope]
+		if !ok || key.Value == "" {
+			continue
+		}
+		t, err := l.Store.Find(key.Scope, key.Value)
+		if err != nil {
+			log.Printf("Failed to check attempts for %s: %v", key.Scope, err)
+			continue
+		}
+		decision = decision.stricter(l.decide(t, policy, now))
+	}
+	return decision
+}
+
+// Fail counts a failed attempt against every key, locks the keys that reached their limit, and
+// returns the decision for the next attempt
+func (l *Limiter) Fail(keys ...Key) Decision {
+	now := l.now()
+	decision := Decision{Allowed: true}
+	for _, key := range keys {
+		policy, ok := l.Policies[key.Scope]
+		if !ok || key.Value == "" {
+			continue
+		}
+		t, err := l.Store.RecordFailure(key.Scope, key.Value, now, policy.Window)
+		if err != nil {
+			log.Printf("Failed to record failed attempt for %s: %v", key.Scope, err)
+			continue
+		}
+
+		if policy.LockAfter > 0 && t.Failures >= policy.LockAfter && t.LockedUntil == nil {
+			until := now.Add(policy.LockDuration)
+			if err := l.Store.Lock(key.Scope, key.Value, until); err != nil {
+				log.Printf("Failed to lock %s after %d failures: %v", key.Scope, t.Failures, err)
+			} else {
+				t.LockedUntil = &until
+				log.Printf("Locked %s %q until %s after %d failed attempts", key.Scope, key.Value, until.Format(time.RFC3339), t.Failures)
+				// later failures while locked do not notify again
+				if t.Failures == policy.LockAfter && l.OnLock != nil {
+					l.OnLock(key, until)
+				}
+			}
+		}
+		decision = decision.stricter(l.decide(t, policy, now))
+	}
+	return decision
+}
+
+// Succeed clears the counters of the keys after a successful attempt
+func (l *Limiter) Succeed(keys ...Key) {
+	for _, key := range keys {
+		if _, ok := l.Policies[key.Scope]; !ok || key.Value == "" {
+			continue
+		}
+		if err := l.Store.Reset(key.Scope, key.Value); err != nil {
+			log.Printf("Failed to reset attempts for %s: %v", key.Scope, err)
+		}
+	}
+}
+
+// Unlock lifts a lock and forgets the failures
+func (l *Limiter) Unlock(key Key) error {
+	return l.Store.Reset(key.Scope, key.Value)
+}
+
+// Locked lists the keys of the scope that are currently locked
+func (l *Limiter) Locked(scope string) ([]entity.LoginThrottle, error) {
+	return l.Store.ListLocked(scope, l.now())
+}
+
+// Prune deletes counters that no policy would still look at
+func (l *Limiter) Prune() error {
+	var retention time.Duration
+	for _, p := range l.Policies {
+		if p.Window > retention {
+			retention = p.Window
+		}
+	}
+	n, err := l.Store.Prune(l.now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("failed to prune login throttles: %w", err)
+	}
+	if n > 0 {
+		log.Printf("[JOB] Pruned %d expired login throttles", n)
+	}
+	return nil
+}
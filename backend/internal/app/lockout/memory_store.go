@@ -0,0 +1,104 @@
+package lockout
+
+import (
+	"backend/internal/domain/entity"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the counters in process memory. It suits a single instance and tests; the
+// counters are lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*entity.LoginThrottle
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*entity.LoginThrottle)}
+}
+
+func memoryKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+func (s *MemoryStore) Find(scope, key string) (*entity.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.counters[memoryKey(scope, key)]; ok {
+		out := *t
+		return &out, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) RecordFailure(scope, key string, at time.Time, window time.Duration) (*entity.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.counters[memoryKey(scope, key)]
+	if !ok {
+		t = &entity.LoginThrottle{Scope: scope, Key: key, CreatedAt: at}
+		s.counters[memoryKey(scope, key)] = t
+	}
+
+	expired := t.LockedUntil != nil && !t.LockedUntil.After(at)
+	if t.LastFailureAt.Before(at.Add(-window)) || expired {
+		t.Failures = 0
+	}
+	if expired {
+		t.LockedUntil = nil
+	}
+	t.Failures++
+	t.LastFailureAt = at
+
+	out := *t
+	return &out, nil
+}
+
+func (s *MemoryStore) Lock(scope, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.counters[memoryKey(scope, key)]; ok {
+		t.LockedUntil = &until
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, memoryKey(scope, key))
+	return nil
+}
+
+func (s *MemoryStore) ListLocked(scope string, now time.Time) ([]entity.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var locked []entity.LoginThrottle
+	for _, t := range s.counters {
+		if t.Scope == scope && t.LockedUntil != nil && t.LockedUntil.After(now) {
+			locked = append(locked, *t)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool { return locked[i].LockedUntil.After(*locked[j].LockedUntil) })
+	return locked, nil
+}
+
+func (s *MemoryStore) Prune(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for k, t := range s.counters {
+		if t.LastFailureAt.Before(before) && (t.LockedUntil == nil || t.LockedUntil.Before(before)) {
+			delete(s.counters, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,152 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKeySetFollowsKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "2026-09", 2048)
+	newKey := newRSAKey(t, "2026-10", 2048)
+	m := newMockProvider(t, oldKey)
+	p, clock := newTestProvider(t, m)
+
+	if _, err := p.Verify(context.Background(), m.sign(oldKey, m.claims(clock.now())), testNonce); err != nil {
+		t.Fatalf("Verify with the published key: %v", err)
+	}
+
+	m.publish(newKey)
+	// an unknown key ID refetches the set, but not more than once a minute
+	if _, err := p.Verify(context.Background(), m.sign(newKey, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+	if m.jwksRequests != 1 {
+		t.Errorf("key set fetched %d times within a minute, want 1", m.jwksRequests)
+	}
+
+	clock.advance(keySetMinRefresh + time.Second)
+	if _, err := p.Verify(context.Background(), m.sign(newKey, m.claims(clock.now())), testNonce); err != nil {
+		t.Fatalf("Verify with the rotated key: %v", err)
+	}
+	if m.jwksRequests != 2 {
+		t.Errorf("key set fetched %d times, want 2", m.jwksRequests)
+	}
+
+	// the withdrawn key is gone with the refetch
+	if _, err := p.Verify(context.Background(), m.sign(oldKey, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("withdrawn key: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestKeySetRefreshesOldKeys(t *testing.T) {
+	key := newECKey(t, "k1")
+	m := newMockProvider(t, key)
+	p, clock := newTestProvider(t, m)
+
+	if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// keys older than a day are refetched, and kept while the provider doesn't answer
+	clock.advance(keySetMaxAge + time.Minute)
+	m.jwksStatus = http.StatusServiceUnavailable
+	if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); err != nil {
+		t.Fatalf("Verify while the key set is unavailable: %v", err)
+	}
+	if m.jwksRequests != 2 {
+		t.Errorf("key set fetched %d times, want 2", m.jwksRequests)
+	}
+
+	// once the provider answers, keys it no longer lists are refused
+	m.jwksStatus = http.StatusOK
+	m.publish(newECKey(t, "k2"))
+	if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestKeySetUnavailable(t *testing.T) {
+	key := newECKey(t, "k1")
+	m := newMockProvider(t, key)
+	m.jwksStatus = http.StatusInternalServerError
+	p, clock := newTestProvider(t, m)
+
+	if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestKeySetWithoutKeyID(t *testing.T) {
+	key := newECKey(t, "k1")
+	m := newMockProvider(t, key)
+	p, clock := newTestProvider(t, m)
+	// signs with the published key but leaves the kid header out
+	unnamed := key
+	unnamed.kid = ""
+
+	// a token may leave out the key ID while the provider has one key
+	if _, err := p.Verify(context.Background(), m.sign(unnamed, m.claims(clock.now())), testNonce); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	m.publish(key, newECKey(t, "k2"))
+	clock.advance(keySetMaxAge + time.Minute)
+	if _, err := p.Verify(context.Background(), m.sign(unnamed, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token without key ID among two keys: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestKeySetSkipsUnusableKeys(t *testing.T) {
+	weak := newRSAKey(t, "weak", 1024)
+	encryption := newECKey(t, "enc")
+	encryption.public.Use = "enc"
+	good := newECKey(t, "good")
+	m := newMockProvider(t, weak, encryption, good)
+	p, clock := newTestProvider(t, m)
+
+	for _, key := range []signingKey{weak, encryption} {
+		if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("key %q: err = %v, want %v", key.kid, err, ErrInvalidToken)
+		}
+	}
+	if _, err := p.Verify(context.Background(), m.sign(good, m.claims(clock.now())), testNonce); err != nil {
+		t.Errorf("key published next to unusable ones: %v", err)
+	}
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa", 2048).public
+	ecKey := newECKey(t, "ec").public
+
+	tests := []struct {
+		name   string
+		change func(k *jsonWebKey)
+		ok     bool
+	}{
+		{"RSA", func(k *jsonWebKey) { *k = rsaKey }, true},
+		{"EC", func(k *jsonWebKey) { *k = ecKey }, true},
+		{"unknown key type", func(k *jsonWebKey) { *k = rsaKey; k.Kty = "oct" }, false},
+		{"malformed modulus", func(k *jsonWebKey) { *k = rsaKey; k.N = "not base64!" }, false},
+		{"missing exponent", func(k *jsonWebKey) { *k = rsaKey; k.E = "" }, false},
+		{"exponent of one", func(k *jsonWebKey) { *k = rsaKey; k.E = "AQ" }, false},
+		{"unsupported curve", func(k *jsonWebKey) { *k = ecKey; k.Crv = "P-224" }, false},
+		{"point off the curve", func(k *jsonWebKey) { *k = ecKey; k.Y = k.X }, false},
+		{"missing coordinate", func(k *jsonWebKey) { *k = ecKey; k.X = "" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var k jsonWebKey
+			tt.change(&k)
+			_, err := k.publicKey()
+			if tt.ok && err != nil {
+				t.Errorf("publicKey: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("publicKey accepted the key")
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testClientID = "shop"
+	testNonce    = "n-0S6_WzA2Mj"
+)
+
+// testNow is a fixed start for the provider's clock, tests move it with testClock.advance
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// signingKey is a key the mock provider signs ID tokens with and publishes in its key set
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  jsonWebKey
+}
+
+func newRSAKey(t *testing.T, kid string, bits int) signingKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return signingKey{
+		kid:     kid,
+		method:  jwt.SigningMethodRS256,
+		private: key,
+		public: jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		},
+	}
+}
+
+func newECKey(t *testing.T, kid string) signingKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	return signingKey{
+		kid:     kid,
+		method:  jwt.SigningMethodES256,
+		private: key,
+		public: jsonWebKey{
+			Kty: "EC",
+			Kid: kid,
+			Use: "sig",
+			Alg: "ES256",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+// mockProvider is an identity provider on a local test server. It serves a discovery document,
+// its key set and a token endpoint, and counts how often the documents are fetched.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu sync.Mutex
+	// metadata is the discovery document served, tests change it before the first fetch
+	metadata map[string]interface{}
+	keys     []jsonWebKey
+	// jwksStatus makes the key set endpoint fail when it is not 200
+	jwksStatus int
+	// idToken is returned by the token endpoint for the code "good-code"
+	idToken string
+
+	discoveryRequests int
+	jwksRequests      int
+}
+
+func newMockProvider(t *testing.T, keys ...signingKey) *mockProvider {
+	t.Helper()
+	m := &mockProvider{t: t, jwksStatus: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.serveDiscovery)
+	mux.HandleFunc("/jwks", m.serveKeys)
+	mux.HandleFunc("/token", m.serveToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	m.metadata = map[string]interface{}{
+		"issuer":                                m.server.URL,
+		"authorization_endpoint":                m.server.URL + "/authorize",
+		"token_endpoint":                        m.server.URL + "/token",
+		"jwks_uri":                              m.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256", "ES256"},
+	}
+	m.publish(keys...)
+	return m
+}
+
+func (m *mockProvider) issuer() string {
+	return m.server.URL
+}
+
+// publish replaces the key set, as a provider rotating its keys does
+func (m *mockProvider) publish(keys ...signingKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = nil
+	for _, key := range keys {
+		m.keys = append(m.keys, key.public)
+	}
+}
+
+func (m *mockProvider) setMetadata(name string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metadata[name] = value
+}
+
+func (m *mockProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		m.t.Errorf("encode response: %v", err)
+	}
+}
+
+func (m *mockProvider) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discoveryRequests++
+	m.writeJSON(w, m.metadata)
+}
+
+func (m *mockProvider) serveKeys(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jwksRequests++
+	if m.jwksStatus != http.StatusOK {
+		w.WriteHeader(m.jwksStatus)
+		return
+	}
+	m.writeJSON(w, map[string]interface{}{"keys": m.keys})
+}
+
+func (m *mockProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.Method != http.MethodPost || r.FormValue("grant_type") != "authorization_code" || r.FormValue("code") != "good-code" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		m.writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	m.writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     m.idToken,
+	})
+}
+
+// sign issues an ID token with the key; a key without an ID leaves the kid header out
+func (m *mockProvider) sign(key signingKey, claims jwt.MapClaims) string {
+	m.t.Helper()
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
+	raw, err := token.SignedString(key.private)
+	if err != nil {
+		m.t.Fatalf("sign ID token: %v", err)
+	}
+	return raw
+}
+
+// claims are those of a valid ID token issued by the mock at the given time
+func (m *mockProvider) claims(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.issuer(),
+		"aud":            testClientID,
+		"sub":            "248289761001",
+		"email":          "ada@acme.example",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+		"picture":        "https://acme.example/ada.png",
+		"nonce":          testNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(10 * time.Minute).Unix(),
+	}
+}
+
+// newTestProvider returns a provider for the mock, reading time from the returned clock
+func newTestProvider(t *testing.T, m *mockProvider, configure ...func(*Config)) (*Provider, *testClock) {
+	t.Helper()
+	config := Config{
+		Name:         "acme",
+		Issuer:       m.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "https://shop.example/auth/oidc/acme/callback",
+	}
+	for _, c := range configure {
+		c(&config)
+	}
+	clock := &testClock{t: testNow}
+	p := newProvider(config, m.server.Client())
+	p.now = clock.now
+	return p, clock
+}
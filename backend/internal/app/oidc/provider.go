@@ -0,0 +1,290 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrDiscovery     = errors.New("OIDC discovery failed")
+	ErrInvalidToken  = errors.New("invalid ID token")
+	ErrMissingClaims = errors.New("ID token lacks required claims")
+)
+
+// ClaimMapping names the ID token claims a provider puts the account details in. Empty fields use
+// the standard OIDC claim names.
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Name          string
+	Picture       string
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	defaults := ClaimMapping{Subject: "sub", Email: "email", EmailVerified: "email_verified", Name: "name", Picture: "picture"}
+	if m.Subject == "" {
+		m.Subject = defaults.Subject
+	}
+	if m.Email == "" {
+		m.Email = defaults.Email
+	}
+	if m.EmailVerified == "" {
+		m.EmailVerified = defaults.EmailVerified
+	}
+	if m.Name == "" {
+		m.Name = defaults.Name
+	}
+	if m.Picture == "" {
+		m.Picture = defaults.Picture
+	}
+	return m
+}
+
+// Config describes an identity provider users can sign in with
+type Config struct {
+	// Name identifies the provider in login URLs and linked identities, e.g. "google" or "acme"
+	Name string
+	// DisplayName is shown on the login button
+	DisplayName string
+	// Issuer is the URL the discovery document is fetched from and ID tokens must be issued by
+	Issuer string
+	// IssuerAliases are further issuer values accepted in ID tokens, Google also signs with its bare host
+	IssuerAliases []string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	Claims        ClaimMapping
+	// TrustEmail treats addresses as verified when the provider sends no email_verified claim, as
+	// company directories that only hand out their own addresses do
+	TrustEmail bool
+}
+
+// Metadata is the part of the discovery document the login flow uses
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	UserInfoEndpoint      string   `json:"userinfo_endpoint"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Identity is the account a user signed in with at a provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider runs the authorization code flow against one identity provider. The discovery document
+// is fetched on first use, so the API starts while a provider is unreachable.
+type Provider struct {
+	Config Config
+
+	client *http.Client
+	now    func() time.Time
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+}
+
+func newProvider(config Config, client *http.Client) *Provider {
+	config.Claims = config.Claims.withDefaults()
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{Config: config, client: client, now: time.Now}
+}
+
+// Name is the provider's name in URLs and linked identities
+func (p *Provider) Name() string {
+	return p.Config.Name
+}
+
+// DisplayName is the provider's name shown to users
+func (p *Provider) DisplayName() string {
+	return p.Config.DisplayName
+}
+
+// secureURL accepts https, and plain http on the loopback interface so a local mock server works
+func secureURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not an https URL", raw)
+}
+
+// getJSON fetches a JSON document from the provider
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Metadata returns the provider's discovery document, fetching it on first use
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	issuer := strings.TrimSuffix(p.Config.Issuer, "/")
+	if err := secureURL(issuer); err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrDiscovery, p.Config.Name, err)
+	}
+
+	var metadata Metadata
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrDiscovery, p.Config.Name, err)
+	}
+	// a document naming another issuer could hand out tokens signed by someone else's keys
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%w for %s: document is for issuer %q", ErrDiscovery, p.Config.Name, metadata.Issuer)
+	}
+	for _, endpoint := range []string{metadata.AuthorizationEndpoint, metadata.TokenEndpoint, metadata.JWKSURI} {
+		if endpoint == "" {
+			return nil, fmt.Errorf("%w for %s: document lacks an endpoint", ErrDiscovery, p.Config.Name)
+		}
+		if err := secureURL(endpoint); err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrDiscovery, p.Config.Name, err)
+		}
+	}
+
+	p.metadata = &metadata
+	p.keys = newKeySet(metadata.JWKSURI, p)
+	return p.metadata, nil
+}
+
+func (p *Provider) oauth2Config(metadata *Metadata) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.Config.ClientID,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  p.Config.RedirectURL,
+		Scopes:       p.Config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+	}
+}
+
+// AuthCodeURL is where the user is sent to sign in. The nonce comes back inside the ID token.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce string, options ...oauth2.AuthCodeOption) (string, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	options = append(options, oauth2.SetAuthURLParam("nonce", nonce))
+	return p.oauth2Config(metadata).AuthCodeURL(state, options...), nil
+}
+
+// Exchange redeems the authorization code and returns the account from the verified ID token
+func (p *Provider) Exchange(ctx context.Context, code, nonce string, options ...oauth2.AuthCodeOption) (*Identity, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2Config(metadata).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client), code, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem code at %s: %w", p.Config.Name, err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: %s returned no ID token", ErrInvalidToken, p.Config.Name)
+	}
+
+	claims, err := p.Verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return p.identity(claims)
+}
+
+// identity reads the account details out of the claims with the provider's mapping
+func (p *Provider) identity(claims map[string]interface{}) (*Identity, error) {
+	mapping := p.Config.Claims
+	identity := &Identity{
+		Provider: p.Config.Name,
+		Subject:  stringClaim(claims, mapping.Subject),
+		Email:    strings.TrimSpace(stringClaim(claims, mapping.Email)),
+		Name:     stringClaim(claims, mapping.Name),
+		Picture:  stringClaim(claims, mapping.Picture),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: no %q claim", ErrMissingClaims, mapping.Subject)
+	}
+
+	switch verified := claims[mapping.EmailVerified].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		// some providers send the flag as a string
+		identity.EmailVerified = verified == "true"
+	case nil:
+		identity.EmailVerified = p.Config.TrustEmail
+	}
+	if identity.Email == "" {
+		identity.EmailVerified = false
+	}
+	return identity, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		// some directories use numeric IDs
+		return v.String()
+	}
+	return ""
+}
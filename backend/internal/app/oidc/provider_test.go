@@ -0,0 +1,216 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestMetadataIsDiscoveredOnce(t *testing.T) {
+	m := newMockProvider(t)
+	p, _ := newTestProvider(t, m)
+
+	for i := 0; i < 2; i++ {
+		metadata, err := p.Metadata(context.Background())
+		if err != nil {
+			t.Fatalf("Metadata: %v", err)
+		}
+		if metadata.TokenEndpoint != m.issuer()+"/token" {
+			t.Errorf("token endpoint = %q, want %q", metadata.TokenEndpoint, m.issuer()+"/token")
+		}
+	}
+	if m.discoveryRequests != 1 {
+		t.Errorf("discovery fetched %d times, want 1", m.discoveryRequests)
+	}
+}
+
+func TestMetadataRejectsUntrustedDocuments(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		value interface{}
+	}{
+		{"other issuer", "issuer", "https://evil.example"},
+		{"plain http endpoint", "jwks_uri", "http://idp.example/jwks"},
+		{"missing endpoint", "token_endpoint", ""},
+		{"malformed endpoint", "authorization_endpoint", "://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.setMetadata(tt.field, tt.value)
+			p, _ := newTestProvider(t, m)
+
+			if _, err := p.Metadata(context.Background()); !errors.Is(err, ErrDiscovery) {
+				t.Errorf("err = %v, want %v", err, ErrDiscovery)
+			}
+		})
+	}
+}
+
+func TestMetadataRequiresSecureIssuer(t *testing.T) {
+	m := newMockProvider(t)
+	p, _ := newTestProvider(t, m, func(c *Config) { c.Issuer = "http://idp.example" })
+
+	if _, err := p.Metadata(context.Background()); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("err = %v, want %v", err, ErrDiscovery)
+	}
+}
+
+func TestMetadataFailureIsRetried(t *testing.T) {
+	m := newMockProvider(t)
+	m.setMetadata("issuer", "https://evil.example")
+	p, _ := newTestProvider(t, m)
+
+	if _, err := p.Metadata(context.Background()); !errors.Is(err, ErrDiscovery) {
+		t.Fatalf("err = %v, want %v", err, ErrDiscovery)
+	}
+	m.setMetadata("issuer", m.issuer())
+	if _, err := p.Metadata(context.Background()); err != nil {
+		t.Errorf("Metadata after the provider recovered: %v", err)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	m := newMockProvider(t)
+	p, _ := newTestProvider(t, m)
+
+	raw, err := p.AuthCodeURL(context.Background(), "state-1", testNonce)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != m.issuer()+"/authorize" {
+		t.Errorf("endpoint = %q, want %q", got, m.issuer()+"/authorize")
+	}
+	query := u.Query()
+	for name, want := range map[string]string{
+		"client_id":     testClientID,
+		"state":         "state-1",
+		"nonce":         testNonce,
+		"response_type": "code",
+		"scope":         "openid email profile",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	key := newECKey(t, "k1")
+	m := newMockProvider(t, key)
+	p, clock := newTestProvider(t, m)
+	m.idToken = m.sign(key, m.claims(clock.now()))
+
+	identity, err := p.Exchange(context.Background(), "good-code", testNonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{
+		Provider:      "acme",
+		Subject:       "248289761001",
+		Email:         "ada@acme.example",
+		EmailVerified: true,
+		Name:          "Ada Lovelace",
+		Picture:       "https://acme.example/ada.png",
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	if _, err := p.Exchange(context.Background(), "bad-code", testNonce); err == nil {
+		t.Error("Exchange accepted a code the provider refused")
+	}
+	if _, err := p.Exchange(context.Background(), "good-code", "other-nonce"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Exchange with another login's nonce: err = %v, want %v", err, ErrInvalidToken)
+	}
+
+	m.idToken = ""
+	if _, err := p.Exchange(context.Background(), "good-code", testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Exchange without an ID token: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestIdentityMapsCustomClaims(t *testing.T) {
+	m := newMockProvider(t)
+	p, _ := newTestProvider(t, m, func(c *Config) {
+		c.Claims = ClaimMapping{Subject: "oid", Email: "upn", EmailVerified: "upn_verified"}
+	})
+
+	identity, err := p.identity(map[string]interface{}{
+		"sub":          "pairwise-subject",
+		"oid":          json.Number("90210"),
+		"upn":          "  ada@acme.example ",
+		"upn_verified": true,
+		"name":         "Ada Lovelace",
+	})
+	if err != nil {
+		t.Fatalf("identity: %v", err)
+	}
+	if identity.Subject != "90210" {
+		t.Errorf("subject = %q, want the numeric oid claim", identity.Subject)
+	}
+	if identity.Email != "ada@acme.example" || !identity.EmailVerified {
+		t.Errorf("email = %q verified %v, want the trimmed upn claim, verified", identity.Email, identity.EmailVerified)
+	}
+	// unmapped fields keep the standard names
+	if identity.Name != "Ada Lovelace" {
+		t.Errorf("name = %q, want %q", identity.Name, "Ada Lovelace")
+	}
+}
+
+func TestIdentityRequiresSubject(t *testing.T) {
+	m := newMockProvider(t)
+	p, _ := newTestProvider(t, m)
+
+	if _, err := p.identity(map[string]interface{}{"email": "ada@acme.example", "sub": 42}); !errors.Is(err, ErrMissingClaims) {
+		t.Errorf("err = %v, want %v", err, ErrMissingClaims)
+	}
+}
+
+func TestIdentityEmailVerified(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      interface{}
+		verified   interface{}
+		trustEmail bool
+		want       bool
+	}{
+		{"verified", "ada@acme.example", true, false, true},
+		{"not verified", "ada@acme.example", false, true, false},
+		{"verified as string", "ada@acme.example", "true", false, true},
+		{"not verified as string", "ada@acme.example", "false", true, false},
+		{"unexpected type", "ada@acme.example", json.Number("1"), true, false},
+		{"missing, provider trusted", "ada@acme.example", nil, true, true},
+		{"missing", "ada@acme.example", nil, false, false},
+		{"no email", nil, true, true, false},
+		{"blank email", "  ", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			p, _ := newTestProvider(t, m, func(c *Config) { c.TrustEmail = tt.trustEmail })
+
+			claims := map[string]interface{}{"sub": "248289761001"}
+			if tt.email != nil {
+				claims["email"] = tt.email
+			}
+			if tt.verified != nil {
+				claims["email_verified"] = tt.verified
+			}
+			identity, err := p.identity(claims)
+			if err != nil {
+				t.Fatalf("identity: %v", err)
+			}
+			if identity.EmailVerified != tt.want {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.want)
+			}
+		})
+	}
+}
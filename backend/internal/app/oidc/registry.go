@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// GoogleIssuer is the issuer of Google accounts
+const GoogleIssuer = "https://accounts.google.com"
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Registry holds the identity providers users can sign in with
+type Registry struct {
+	providers map[string]*Provider
+	order     []*Provider
+}
+
+// NewRegistry sets up the providers. The client makes every request to them, tests point it at a
+// mock provider.
+func NewRegistry(client *http.Client, configs ...Config) (*Registry, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r := &Registry{providers: make(map[string]*Provider)}
+	for _, config := range configs {
+		if !providerName.MatchString(config.Name) {
+			return nil, fmt.Errorf("provider name %q must be lowercase letters, digits and dashes", config.Name)
+		}
+		if _, exists := r.providers[config.Name]; exists {
+			return nil, fmt.Errorf("provider %q is configured twice", config.Name)
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("provider %q needs an issuer, a client ID and a redirect URL", config.Name)
+		}
+		if err := secureURL(config.Issuer); err != nil {
+			return nil, fmt.Errorf("provider %q: %w", config.Name, err)
+		}
+
+		provider := newProvider(config, client)
+		r.providers[config.Name] = provider
+		r.order = append(r.order, provider)
+	}
+	return r, nil
+}
+
+// Provider returns the named provider
+func (r *Registry) Provider(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Providers lists the providers in the order they were configured
+func (r *Registry) Providers() []*Provider {
+	return r.order
+}
+
+// ConfigsFromEnv reads the providers from the environment. Google is configured with
+// GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL. Further providers are listed in
+// OIDC_PROVIDERS, e.g. "acme,contoso", each configured with OIDC_<NAME>_* variables. Incomplete
+// providers are logged and left out.
+func ConfigsFromEnv() []Config {
+	var configs []Config
+
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		configs = append(configs, Config{
+			Name:          "google",
+			DisplayName:   "Google",
+			Issuer:        GoogleIssuer,
+			IssuerAliases: []string{"accounts.google.com"},
+			ClientID:      clientID,
+			ClientSecret:  os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:   os.Getenv("GOOGLE_REDIRECT_URL"),
+			Scopes:        []string{"openid", "email", "profile"},
+		})
+	}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		env := func(key string) string {
+			return strings.TrimSpace(os.Getenv(prefix + key))
+		}
+
+		config := Config{
+			Name:         name,
+			DisplayName:  env("DISPLAY_NAME"),
+			Issuer:       env("ISSUER"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			RedirectURL:  env("REDIRECT_URL"),
+			Scopes:       strings.FieldsFunc(env("SCOPES"), func(r rune) bool { return r == ',' || r == ' ' }),
+			Claims: ClaimMapping{
+				Subject:       env("CLAIM_SUBJECT"),
+				Email:         env("CLAIM_EMAIL"),
+				EmailVerified: env("CLAIM_EMAIL_VERIFIED"),
+				Name:          env("CLAIM_NAME"),
+				Picture:       env("CLAIM_PICTURE"),
+			},
+			TrustEmail: env("TRUST_EMAIL") == "true",
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			log.Printf("Ignoring identity provider %q: %sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", name, prefix, prefix, prefix)
+			continue
+		}
+		configs = append(configs, config)
+	}
+
+	return configs
+}
@@ -0,0 +1,132 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// clockSkew is how far the provider's clock may be off from ours
+const clockSkew = time.Minute
+
+// signingAlgorithms are the asymmetric algorithms ID tokens may be signed with. Shared secret
+// algorithms are left out, the client secret is known to more parties than the provider.
+var signingAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// allowedAlgorithms are the signing algorithms the provider announces that we support. Providers
+// that announce none are held to RS256, which every provider must offer.
+func allowedAlgorithms(metadata *Metadata) []string {
+	var allowed []string
+	for _, alg := range metadata.SigningAlgorithms {
+		for _, supported := range signingAlgorithms {
+			if alg == supported {
+				allowed = append(allowed, alg)
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return []string{"RS256"}
+	}
+	return allowed
+}
+
+// Verify checks the ID token's signature against the provider's published keys and its issuer,
+// audience, lifetime and nonce, and returns its claims
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (map[string]interface{}, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{
+		ValidMethods:  allowedAlgorithms(metadata),
+		UseJSONNumber: true,
+		// the time claims are checked below with allowance for clock skew
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !p.validIssuer(stringClaim(claims, "iss")) {
+		return nil, fmt.Errorf("%w: issued by %q", ErrInvalidToken, stringClaim(claims, "iss"))
+	}
+	if !containsAudience(claims["aud"], p.Config.ClientID) {
+		return nil, fmt.Errorf("%w: not issued to this client", ErrInvalidToken)
+	}
+	// a token for several audiences names the one it was requested by
+	if azp := stringClaim(claims, "azp"); azp != "" && azp != p.Config.ClientID {
+		return nil, fmt.Errorf("%w: requested by another client", ErrInvalidToken)
+	}
+
+	now := p.now()
+	exp, ok := timeClaim(claims, "exp")
+	if !ok || now.After(exp.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if iat, ok := timeClaim(claims, "iat"); !ok || iat.After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if nbf, ok := timeClaim(claims, "nbf"); ok && nbf.After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	// the nonce ties the token to the login the browser started, a token from another login is refused
+	if subtle.ConstantTimeCompare([]byte(stringClaim(claims, "nonce")), []byte(nonce)) != 1 || nonce == "" {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) validIssuer(iss string) bool {
+	if iss == "" {
+		return false
+	}
+	if strings.TrimSuffix(iss, "/") == strings.TrimSuffix(p.Config.Issuer, "/") {
+		return true
+	}
+	for _, alias := range p.Config.IssuerAliases {
+		if iss == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAudience accepts the aud claim as a single string or a list
+func containsAudience(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func timeClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
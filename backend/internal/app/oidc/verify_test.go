@@ -0,0 +1,156 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestVerifyClaims(t *testing.T) {
+	key := newRSAKey(t, "k1", 2048)
+	m := newMockProvider(t, key)
+
+	tests := []struct {
+		name   string
+		change func(claims jwt.MapClaims, now time.Time)
+		nonce  string
+		ok     bool
+	}{
+		{"valid", func(jwt.MapClaims, time.Time) {}, testNonce, true},
+		{"issuer with trailing slash", func(c jwt.MapClaims, _ time.Time) { c["iss"] = m.issuer() + "/" }, testNonce, true},
+		{"issuer alias", func(c jwt.MapClaims, _ time.Time) { c["iss"] = "idp.acme.example" }, testNonce, true},
+		{"other issuer", func(c jwt.MapClaims, _ time.Time) { c["iss"] = "https://evil.example" }, testNonce, false},
+		{"no issuer", func(c jwt.MapClaims, _ time.Time) { delete(c, "iss") }, testNonce, false},
+		{"audience list", func(c jwt.MapClaims, _ time.Time) { c["aud"] = []string{"other-app", testClientID} }, testNonce, true},
+		{"other audience", func(c jwt.MapClaims, _ time.Time) { c["aud"] = "other-app" }, testNonce, false},
+		{"audience list without us", func(c jwt.MapClaims, _ time.Time) { c["aud"] = []string{"other-app"} }, testNonce, false},
+		{"no audience", func(c jwt.MapClaims, _ time.Time) { delete(c, "aud") }, testNonce, false},
+		{"authorized party", func(c jwt.MapClaims, _ time.Time) { c["azp"] = testClientID }, testNonce, true},
+		{"other authorized party", func(c jwt.MapClaims, _ time.Time) {
+			c["aud"] = []string{"other-app", testClientID}
+			c["azp"] = "other-app"
+		}, testNonce, false},
+		{"expired within clock skew", func(c jwt.MapClaims, now time.Time) { c["exp"] = now.Add(-30 * time.Second).Unix() }, testNonce, true},
+		{"expired", func(c jwt.MapClaims, now time.Time) { c["exp"] = now.Add(-2 * time.Minute).Unix() }, testNonce, false},
+		{"no expiry", func(c jwt.MapClaims, _ time.Time) { delete(c, "exp") }, testNonce, false},
+		{"issued in the future", func(c jwt.MapClaims, now time.Time) { c["iat"] = now.Add(5 * time.Minute).Unix() }, testNonce, false},
+		{"no issue time", func(c jwt.MapClaims, _ time.Time) { delete(c, "iat") }, testNonce, false},
+		{"not valid yet", func(c jwt.MapClaims, now time.Time) { c["nbf"] = now.Add(5 * time.Minute).Unix() }, testNonce, false},
+		{"valid from now", func(c jwt.MapClaims, now time.Time) { c["nbf"] = now.Unix() }, testNonce, true},
+		{"other nonce", func(jwt.MapClaims, time.Time) {}, "other-nonce", false},
+		{"no nonce", func(c jwt.MapClaims, _ time.Time) { delete(c, "nonce") }, testNonce, false},
+		{"no nonce expected", func(c jwt.MapClaims, _ time.Time) { c["nonce"] = "" }, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := newTestProvider(t, m, func(c *Config) { c.IssuerAliases = []string{"idp.acme.example"} })
+			claims := m.claims(clock.now())
+			tt.change(claims, clock.now())
+
+			got, err := p.Verify(context.Background(), m.sign(key, claims), tt.nonce)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if stringClaim(got, "sub") != "248289761001" {
+					t.Errorf("sub = %q, want %q", stringClaim(got, "sub"), "248289761001")
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestVerifyExpiresWithClock(t *testing.T) {
+	key := newRSAKey(t, "k1", 2048)
+	m := newMockProvider(t, key)
+	p, clock := newTestProvider(t, m)
+	raw := m.sign(key, m.claims(clock.now()))
+
+	if _, err := p.Verify(context.Background(), raw, testNonce); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	// the token lives ten minutes, plus the allowed clock skew
+	clock.advance(10*time.Minute + clockSkew + time.Second)
+	if _, err := p.Verify(context.Background(), raw, testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa", 2048)
+	ecKey := newECKey(t, "ec")
+	m := newMockProvider(t, rsaKey, ecKey)
+	p, clock := newTestProvider(t, m)
+	claims := m.claims(clock.now())
+
+	for _, key := range []signingKey{rsaKey, ecKey} {
+		if _, err := p.Verify(context.Background(), m.sign(key, claims), testNonce); err != nil {
+			t.Errorf("%s signed token: %v", key.method.Alg(), err)
+		}
+	}
+
+	// a key the provider never published, under the ID of one it did
+	impostor := newRSAKey(t, "rsa", 2048)
+	forged := map[string]string{
+		"another key":        m.sign(impostor, claims),
+		"changed claims":     tamper(t, m.sign(rsaKey, claims)),
+		"client secret HMAC": m.sign(signingKey{kid: "rsa", method: jwt.SigningMethodHS256, private: []byte("secret")}, claims),
+		"no signature":       m.sign(signingKey{kid: "rsa", method: jwt.SigningMethodNone, private: jwt.UnsafeAllowNoneSignatureType}, claims),
+	}
+	for name, raw := range forged {
+		if _, err := p.Verify(context.Background(), raw, testNonce); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestVerifyOnlyAllowsAnnouncedAlgorithms(t *testing.T) {
+	ecKey := newECKey(t, "ec")
+	m := newMockProvider(t, ecKey)
+	// shared secret algorithms are never accepted, which leaves the provider at RS256
+	m.setMetadata("id_token_signing_alg_values_supported", []string{"HS256"})
+	p, clock := newTestProvider(t, m)
+
+	raw := m.sign(ecKey, m.claims(clock.now()))
+	if _, err := p.Verify(context.Background(), raw, testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestVerifyFailsWithoutDiscovery(t *testing.T) {
+	key := newRSAKey(t, "k1", 2048)
+	m := newMockProvider(t, key)
+	m.setMetadata("issuer", "https://evil.example")
+	p, clock := newTestProvider(t, m)
+
+	if _, err := p.Verify(context.Background(), m.sign(key, m.claims(clock.now())), testNonce); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("err = %v, want %v", err, ErrDiscovery)
+	}
+}
+
+// tamper swaps the token's payload for one naming another subject, keeping the signature
+func tamper(t *testing.T, raw string) string {
+	t.Helper()
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q has %d parts", raw, len(parts))
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(raw, claims); err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	claims["sub"] = "someone-else"
+	other, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SigningString()
+	if err != nil {
+		t.Fatalf("encode claims: %v", err)
+	}
+	return parts[0] + "." + strings.Split(other, ".")[1] + "." + parts[2]
+}
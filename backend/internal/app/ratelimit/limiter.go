@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"backend/internal/domain/repository"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policies applied to the route groups
+const (
+	PolicyAuth     = "auth"
+	PolicySession  = "session"
+	PolicyCart     = "cart"
+	PolicyProducts = "products"
+	PolicyFeeds    = "feeds"
+)
+
+// Limit allows Requests per Per on average. The bucket holds Requests tokens, so a client that was
+// idle may spend them all in a burst.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) capacity() float64 {
+	return float64(l.Requests)
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// String renders the limit in the form ParseLimit reads
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// ParseLimit reads a limit written as requests/period, e.g. "10/1m" or "5/1s"
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q is not in the form requests/period", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid request count", s)
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid period", s)
+	}
+	return Limit{Requests: n, Per: per}, nil
+}
+
+// DefaultPolicies are strict for authentication, where requests are cheap to make and expensive
+// to serve, and relaxed for browsing the catalogue. Keeping a session alive and finishing a
+// provider login take no credentials, they get their own looser policy so that every open tab
+// refreshing does not lock the user out of logging in.
+func DefaultPolicies() map[string]Limit {
+	return map[string]Limit{
+		PolicyAuth:     {Requests: 10, Per: time.Minute},
+		PolicySession:  {Requests: 60, Per: time.Minute},
+		PolicyCart:     {Requests: 120, Per: time.Minute},
+		PolicyProducts: {Requests: 600, Per: time.Minute},
+		PolicyFeeds:    {Requests: 30, Per: time.Minute},
+	}
+}
+
+// PoliciesFromEnv returns the default policies, each overridable with RATE_LIMIT_<NAME>, e.g.
+// RATE_LIMIT_AUTH=20/1m
+func PoliciesFromEnv() map[string]Limit {
+	policies := DefaultPolicies()
+	for name := range policies {
+		env := "RATE_LIMIT_" + strings.ToUpper(name)
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			log.Printf("Ignoring %s: %v", env, err)
+			continue
+		}
+		policies[name] = limit
+	}
+	return policies
+}
+
+// Result is the outcome of one request against its bucket
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, set when the request was refused
+	RetryAfter time.Duration
+}
+
+// Limiter applies token bucket policies to clients
+type Limiter struct {
+	Store    repository.RateLimitRepository
+	Policies map[string]Limit
+
+	now func() time.Time
+}
+
+func NewLimiter(store repository.RateLimitRepository, policies map[string]Limit) *Limiter {
+	return &Limiter{Store: store, Policies: policies, now: time.Now}
+}
+
+// Policy returns the limit of the named policy
+func (l *Limiter) Policy(name string) (Limit, bool) {
+	limit, ok := l.Policies[name]
+	return limit, ok
+}
+
+// Allow takes a token from the client's bucket for the policy
+func (l *Limiter) Allow(policy, client string) (*Result, error) {
+	limit, ok := l.Policies[policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown rate limit policy %q", policy)
+	}
+
+	tokens, allowed, err := l.Store.Take(policy+":"+client, limit.capacity(), limit.rate(), l.now())
+	if err != nil {
+		return nil, err
+	}
+
+	rate := limit.rate()
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((limit.capacity() - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result, nil
+}
+
+// Prune drops buckets idle long enough to have refilled, they behave like new ones
+func (l *Limiter) Prune() error {
+	var longest time.Duration
+	for _, limit := range l.Policies {
+		if limit.Per > longest {
+			longest = limit.Per
+		}
+	}
+	n, err := l.Store.Prune(l.now().Add(-longest))
+	if err != nil {
+		return fmt.Errorf("failed to prune rate limit buckets: %w", err)
+	}
+	if n > 0 {
+		log.Printf("[JOB] Pruned %d idle rate limit buckets", n)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package recommend
+
+import (
+	"backend/internal/domain/repository"
+	"math"
+)
+
+// buildCoPurchase scores how often two products are bought in the same order.
+// The score is the cosine similarity of the products' order incidence vectors:
+// orders(a and b) / sqrt(orders(a) * orders(b)).
+func buildCoPurchase(lines []repository.OrderLine) map[uint]map[uint]float64 {
+	orders := make(map[uint]map[uint]bool)
+	for _, line := range lines {
+		if orders[line.OrderID] == nil {
+			orders[line.OrderID] = make(map[uint]bool)
+		}
+		orders[line.OrderID][line.ProductID] = true
+	}
+
+	productOrders := make(map[uint]int)
+	pairs := make(map[uint]map[uint]int)
+	for _, products := range orders {
+		ids := make([]uint, 0, len(products))
+		for id := range products {
+			ids = append(ids, id)
+			productOrders[id]++
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				addPair(pairs, a, b)
+				addPair(pairs, b, a)
+			}
+		}
+	}
+
+	scores := make(map[uint]map[uint]float64, len(pairs))
+	for a, others := range pairs {
+		scores[a] = make(map[uint]float64, len(others))
+		for b, together := range others {
+			scores[a][b] = float64(together) / math.Sqrt(float64(productOrders[a]*productOrders[b]))
+		}
+	}
+	return scores
+}
+
+func addPair(pairs map[uint]map[uint]int, a, b uint) {
+	if pairs[a] == nil {
+		pairs[a] = make(map[uint]int)
+	}
+	pairs[a][b]++
+}
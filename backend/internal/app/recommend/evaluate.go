@@ -0,0 +1,115 @@
+package recommend
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"time"
+)
+
+// EvalReport is the result of an offline evaluation of the personalised recommendations
+type EvalReport struct {
+	K                      int     `json:"k"`
+	Users                  int     `json:"users"`
+	ColdStartUsers         int     `json:"cold_start_users"`
+	PrecisionAtK           float64 `json:"precision_at_k"`
+	RecallAtK              float64 `json:"recall_at_k"`
+	HitRate                float64 `json:"hit_rate"`
+	PopularityPrecisionAtK float64 `json:"popularity_precision_at_k"`
+}
+
+// Evaluate holds out the latest completed order of every user, trains the model on what happened
+// before it, and measures how many of the held-out products appear in the top k recommendations.
+// A popularity-only baseline is reported alongside for comparison.
+func Evaluate(views []entity.ProductView, lines []repository.OrderLine, k int) EvalReport {
+	report := EvalReport{K: k}
+
+	// The held-out order is the user's most recent completed order
+	heldOut := make(map[uint]uint)
+	cutoff := make(map[uint]time.Time)
+	for _, line := range lines {
+		if !line.Completed {
+			continue
+		}
+		if line.OrderedAt.After(cutoff[line.UserID]) || (line.OrderedAt.Equal(cutoff[line.UserID]) && line.OrderID > heldOut[line.UserID]) {
+			cutoff[line.UserID] = line.OrderedAt
+			heldOut[line.UserID] = line.OrderID
+		}
+	}
+
+	target := make(map[uint]map[uint]bool)
+	for _, line := range lines {
+		if line.Completed && heldOut[line.UserID] == line.OrderID {
+			if target[line.UserID] == nil {
+				target[line.UserID] = make(map[uint]bool)
+			}
+			target[line.UserID][line.ProductID] = true
+		}
+	}
+
+	// Test users only contribute what happened before their held-out order
+	var training []Interaction
+	history := make(map[uint][]Interaction)
+	for _, in := range CollectInteractions(views, lines) {
+		if end, ok := cutoff[in.UserID]; ok && in.UserID != 0 && !in.At.Before(end) {
+			continue
+		}
+		training = append(training, in)
+		if in.UserID != 0 {
+			history[in.UserID] = append(history[in.UserID], in)
+		}
+	}
+
+	model := TrainCF(training)
+
+	var precision, recall, hits, popPrecision float64
+	for userID, products := range target {
+		userHistory := profile(history[userID])
+		exclude := purchased(history[userID])
+
+		recs, personalised := model.Recommend(userHistory, exclude, k)
+		if !personalised {
+			report.ColdStartUsers++
+		}
+
+		found := countHits(recs, products)
+		precision += float64(found) / float64(k)
+		recall += float64(found) / float64(len(products))
+		if found > 0 {
+			hits++
+		}
+
+		popPrecision += float64(countHits(model.Popular(exclude, k), products)) / float64(k)
+	}
+
+	report.Users = len(target)
+	if report.Users > 0 {
+		n := float64(report.Users)
+		report.PrecisionAtK = precision / n
+		report.RecallAtK = recall / n
+		report.HitRate = hits / n
+		report.PopularityPrecisionAtK = popPrecision / n
+	}
+
+	return report
+}
+
+// purchased returns the products the user has already bought
+func purchased(interactions []Interaction) map[uint]bool {
+	bought := make(map[uint]bool)
+	for _, in := range interactions {
+		if in.Weight >= purchaseWeight {
+			bought[in.ProductID] = true
+		}
+	}
+	return bought
+}
+
+func countHits(recs []Scored, relevant map[uint]bool) int {
+	found := 0
+	for _, r := range recs {
+		if relevant[r.ProductID] {
+			found++
+		}
+	}
+	return found
+}
@@ -0,0 +1,197 @@
+package recommend
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Interaction weights: a purchase says more about a user's taste than a cart add, which says more than a view
+const (
+	viewWeight     = 1.0
+	cartWeight     = 3.0
+	purchaseWeight = 5.0
+)
+
+// maxNeighbours is how many similar items are kept per item in the collaborative filtering model
+const maxNeighbours = 50
+
+// Interaction is a weighted signal that a user (or anonymous session) is interested in a product
+type Interaction struct {
+	Key       string // "u:<user id>" or "s:<session id>"
+	UserID    uint   // 0 for anonymous sessions
+	ProductID uint
+	Weight    float64
+	At        time.Time
+}
+
+func userKey(userID uint) string {
+	return "u:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// CollectInteractions turns views and cart lines into interactions.
+// Anonymous views are keyed by session so they still contribute co-view signal.
+func CollectInteractions(views []entity.ProductView, lines []repository.OrderLine) []Interaction {
+	interactions := make([]Interaction, 0, len(views)+len(lines))
+
+	for _, v := range views {
+		in := Interaction{ProductID: v.ProductID, Weight: viewWeight, At: v.CreatedAt}
+		switch {
+		case v.UserID != nil:
+			in.UserID = *v.UserID
+			in.Key = userKey(*v.UserID)
+		case v.SessionID != "":
+			in.Key = "s:" + v.SessionID
+		default:
+			continue
+		}
+		interactions = append(interactions, in)
+	}
+
+	for _, line := range lines {
+		weight := cartWeight
+		if line.Completed {
+			weight = purchaseWeight
+		}
+		interactions = append(interactions, Interaction{
+			Key:       userKey(line.UserID),
+			UserID:    line.UserID,
+			ProductID: line.ProductID,
+			Weight:    weight,
+			At:        line.OrderedAt,
+		})
+	}
+
+	return interactions
+}
+
+// profile returns the strongest interaction weight per product for one user
+func profile(interactions []Interaction) map[uint]float64 {
+	p := make(map[uint]float64)
+	for _, in := range interactions {
+		if in.Weight > p[in.ProductID] {
+			p[in.ProductID] = in.Weight
+		}
+	}
+	return p
+}
+
+// CFModel is an item-item collaborative filtering model
+type CFModel struct {
+	similar map[uint][]Scored
+	popular []Scored
+}
+
+// TrainCF computes item-item cosine similarity over the user-item interaction matrix
+func TrainCF(interactions []Interaction) *CFModel {
+	byUser := make(map[string][]Interaction)
+	for _, in := range interactions {
+		byUser[in.Key] = append(byUser[in.Key], in)
+	}
+
+	norms := make(map[uint]float64)
+	dots := make(map[uint]map[uint]float64)
+	popularity := make(map[uint]float64)
+
+	for _, ins := range byUser {
+		items := profile(ins)
+		ids := make([]uint, 0, len(items))
+		for id, w := range items {
+			ids = append(ids, id)
+			norms[id] += w * w
+			popularity[id] += w
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				product := items[a] * items[b]
+				addDot(dots, a, b, product)
+				addDot(dots, b, a, product)
+			}
+		}
+	}
+
+	model := &CFModel{similar: make(map[uint][]Scored, len(dots))}
+	for a, others := range dots {
+		neighbours := make([]Scored, 0, len(others))
+		for b, dot := range others {
+			neighbours = append(neighbours, Scored{ProductID: b, Score: dot / math.Sqrt(norms[a]*norms[b])})
+		}
+		sortScored(neighbours)
+		if len(neighbours) > maxNeighbours {
+			neighbours = neighbours[:maxNeighbours]
+		}
+		model.similar[a] = neighbours
+	}
+
+	for id, score := range popularity {
+		model.popular = append(model.popular, Scored{ProductID: id, Score: score})
+	}
+	sortScored(model.popular)
+
+	return model
+}
+
+func addDot(dots map[uint]map[uint]float64, a, b uint, v float64) {
+	if dots[a] == nil {
+		dots[a] = make(map[uint]float64)
+	}
+	dots[a][b] += v
+}
+
+// Recommend scores products similar to the ones in history (product -> weight), skipping excluded ones.
+// When fewer than k products can be scored, the rest is filled with popular products.
+// personalised is false when the result is popularity only (cold start).
+func (m *CFModel) Recommend(history map[uint]float64, exclude map[uint]bool, k int) (recs []Scored, personalised bool) {
+	scores := make(map[uint]float64)
+	for item, weight := range history {
+		for _, n := range m.similar[item] {
+			if !exclude[n.ProductID] {
+				scores[n.ProductID] += weight * n.Score
+			}
+		}
+	}
+
+	for id, score := range scores {
+		recs = append(recs, Scored{ProductID: id, Score: score})
+	}
+	sortScored(recs)
+	if len(recs) > k {
+		recs = recs[:k]
+	}
+	personalised = len(recs) > 0
+
+	if len(recs) < k {
+		seen := make(map[uint]bool, len(recs))
+		for _, r := range recs {
+			seen[r.ProductID] = true
+		}
+		for _, p := range m.popular {
+			if len(recs) >= k {
+				break
+			}
+			if !seen[p.ProductID] && !exclude[p.ProductID] {
+				recs = append(recs, Scored{ProductID: p.ProductID})
+			}
+		}
+	}
+
+	return recs, personalised
+}
+
+// Popular returns the k most interacted-with products, skipping excluded ones
+func (m *CFModel) Popular(exclude map[uint]bool, k int) []Scored {
+	recs, _ := (&CFModel{popular: m.popular}).Recommend(nil, exclude, k)
+	return recs
+}
+
+func sortScored(s []Scored) {
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Score != s[j].Score {
+			return s[i].Score > s[j].Score
+		}
+		return s[i].ProductID < s[j].ProductID
+	})
+}
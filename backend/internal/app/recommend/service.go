@@ -0,0 +1,193 @@
+package recommend
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRelated is how many related products are kept per product
+	maxRelated = 20
+	// maxTop is how many bestsellers are kept per window
+	maxTop = 50
+
+	// coPurchaseWeight and contentWeight blend the two related-product signals
+	coPurchaseWeight = 0.6
+	contentWeight    = 0.4
+
+	// coPurchaseHistory is how far back orders are used for "customers also bought"
+	coPurchaseHistory = 365 * 24 * time.Hour
+	// interactionHistory is how far back views and cart adds are used for personalised recommendations
+	interactionHistory = 180 * 24 * time.Hour
+)
+
+// TopWindows are the bestseller windows, in days, that are precomputed on refresh
+var TopWindows = []int{7, 30, 90, 365}
+
+// Scored is a product ID with its recommendation score
+type Scored struct {
+	ProductID uint    `json:"product_id"`
+	Score     float64 `json:"score"`
+}
+
+// Service computes related products and bestsellers from the local database
+// and keeps them in memory between refreshes.
+type Service struct {
+	ProductRepo repository.ProductRepository
+	CartRepo    repository.CartRepository
+	ViewRepo    repository.ProductViewRepository
+
+	mu        sync.RWMutex
+	related   map[uint][]Scored
+	top       map[int][]repository.ProductSales
+	cf        *CFModel
+	refreshed time.Time
+}
+
+func NewService(productRepo repository.ProductRepository, cartRepo repository.CartRepository, viewRepo repository.ProductViewRepository) *Service {
+	return &Service{
+		ProductRepo: productRepo,
+		CartRepo:    cartRepo,
+		ViewRepo:    viewRepo,
+		related:     map[uint][]Scored{},
+		top:         map[int][]repository.ProductSales{},
+		cf:          TrainCF(nil),
+	}
+}
+
+// Refresh recomputes every recommendation. It is run by a background job.
+func (s *Service) Refresh() error {
+	var products []entity.Product
+	if err := s.ProductRepo.GetAllProducts(&products); err != nil {
+		return fmt.Errorf("failed to load products: %w", err)
+	}
+
+	lines, err := s.CartRepo.GetCompletedOrderLines(time.Now().Add(-coPurchaseHistory))
+	if err != nil {
+		return fmt.Errorf("failed to load orders: %w", err)
+	}
+
+	related := computeRelated(products, buildTFIDF(products), buildCoPurchase(lines))
+
+	top := make(map[int][]repository.ProductSales, len(TopWindows))
+	for _, days := range TopWindows {
+		sales, err := s.CartRepo.GetTopSellingProducts(time.Now().AddDate(0, 0, -days), maxTop)
+		if err != nil {
+			return fmt.Errorf("failed to load bestsellers: %w", err)
+		}
+		top[days] = sales
+	}
+
+	since := time.Now().Add(-interactionHistory)
+	views, err := s.ViewRepo.GetViewsSince(since)
+	if err != nil {
+		return fmt.Errorf("failed to load product views: %w", err)
+	}
+	cartLines, err := s.CartRepo.GetCartLines(since)
+	if err != nil {
+		return fmt.Errorf("failed to load cart lines: %w", err)
+	}
+	cf := TrainCF(CollectInteractions(views, cartLines))
+
+	s.mu.Lock()
+	s.related = related
+	s.top = top
+	s.cf = cf
+	s.refreshed = time.Now()
+	s.mu.Unlock()
+
+	log.Printf("[RECOMMEND] Refreshed recommendations for %d products from %d order lines and %d views",
+		len(products), len(lines), len(views))
+	return nil
+}
+
+// ForUser returns up to k personalised recommendations based on the user's own views, cart adds
+// and purchases. Products the user already bought are skipped. personalised is false when the
+// user has no usable history and the result is popularity only.
+func (s *Service) ForUser(userID uint, k int) (recs []Scored, personalised bool, err error) {
+	views, err := s.ViewRepo.GetUserViews(userID, time.Now().Add(-interactionHistory))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load product views: %w", err)
+	}
+	lines, err := s.CartRepo.GetUserCartLines(userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load cart lines: %w", err)
+	}
+
+	interactions := CollectInteractions(views, lines)
+
+	s.mu.RLock()
+	cf := s.cf
+	s.mu.RUnlock()
+
+	recs, personalised = cf.Recommend(profile(interactions), purchased(interactions), k)
+	return recs, personalised, nil
+}
+
+// Related returns up to limit products related to productID, best first
+func (s *Service) Related(productID uint, limit int) []Scored {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	related := s.related[productID]
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+	return related
+}
+
+// Top returns up to limit bestsellers for a precomputed window in days.
+// ok is false when the window is not one of TopWindows.
+func (s *Service) Top(days, limit int) (sales []repository.ProductSales, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sales, ok = s.top[days]
+	if limit > 0 && len(sales) > limit {
+		sales = sales[:limit]
+	}
+	return sales, ok
+}
+
+// RefreshedAt returns when the recommendations were last computed
+func (s *Service) RefreshedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshed
+}
+
+// computeRelated blends co-purchase and content similarity for every pair of active products
+func computeRelated(products []entity.Product, vectors map[uint]vector, coPurchase map[uint]map[uint]float64) map[uint][]Scored {
+	related := make(map[uint][]Scored, len(products))
+
+	for _, a := range products {
+		var scores []Scored
+		for _, b := range products {
+			if a.ID == b.ID {
+				continue
+			}
+			score := coPurchaseWeight*coPurchase[a.ID][b.ID] + contentWeight*cosine(vectors[a.ID], vectors[b.ID])
+			if score > 0 {
+				scores = append(scores, Scored{ProductID: b.ID, Score: score})
+			}
+		}
+
+		sort.Slice(scores, func(i, j int) bool {
+			if scores[i].Score != scores[j].Score {
+				return scores[i].Score > scores[j].Score
+			}
+			return scores[i].ProductID < scores[j].ProductID
+		})
+		if len(scores) > maxRelated {
+			scores = scores[:maxRelated]
+		}
+		related[a.ID] = scores
+	}
+
+	return related
+}
@@ -0,0 +1,85 @@
+package recommend
+
+import (
+	"backend/internal/domain/entity"
+	"backend/pkg/utils"
+	"math"
+)
+
+// stopWords are common words that carry no meaning for similarity
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "this": true, "to": true, "with": true, "your": true, "you": true,
+}
+
+// nameWeight is how many times more a word in the name counts than one in the description
+const nameWeight = 2
+
+// vector is a sparse, L2-normalised TF-IDF vector
+type vector map[string]float64
+
+// tokenize folds accents and case like product slugs and drops stop words and single characters
+func tokenize(text string) []string {
+	var tokens []string
+	for _, token := range utils.FoldWords(text) {
+		if len(token) > 1 && !stopWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// buildTFIDF computes a TF-IDF vector from the name and description of every product
+func buildTFIDF(products []entity.Product) map[uint]vector {
+	termFreqs := make(map[uint]map[string]float64, len(products))
+	docFreq := make(map[string]int)
+
+	for _, p := range products {
+		tf := make(map[string]float64)
+		for _, token := range tokenize(p.Name) {
+			tf[token] += nameWeight
+		}
+		for _, token := range tokenize(p.Description) {
+			tf[token]++
+		}
+		for term := range tf {
+			docFreq[term]++
+		}
+		termFreqs[p.ID] = tf
+	}
+
+	n := float64(len(products))
+	vectors := make(map[uint]vector, len(products))
+	for id, tf := range termFreqs {
+		v := make(vector, len(tf))
+		var norm float64
+		for term, freq := range tf {
+			// smoothed idf so terms present in every document still count a little
+			weight := (1 + math.Log(freq)) * (math.Log((1+n)/(1+float64(docFreq[term]))) + 1)
+			v[term] = weight
+			norm += weight * weight
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for term := range v {
+				v[term] /= norm
+			}
+		}
+		vectors[id] = v
+	}
+
+	return vectors
+}
+
+// cosine returns the cosine similarity of two normalised vectors
+func cosine(a, b vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for term, weight := range a {
+		dot += weight * b[term]
+	}
+	return dot
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// popularHistory is how far back logged searches count towards popular queries
+	popularHistory = 30 * 24 * time.Hour
+	// maxPopular is how many popular queries are kept in memory for prefix matching
+	maxPopular = 1000
+	// minPrefixLength is the shortest prefix that is matched against product names
+	minPrefixLength = 2
+)
+
+// Suggestions is the autocomplete response for a prefix
+type Suggestions struct {
+	Query    string                         `json:"query"`
+	Products []repository.ProductSuggestion `json:"products"`
+	// Categories is always empty for now: the catalog has no categories yet
+	Categories []string `json:"categories"`
+	Queries    []string `json:"queries"`
+}
+
+// Suggester answers autocomplete requests. Product names are matched in the database
+// through a prefix index; popular queries are kept in memory between refreshes.
+type Suggester struct {
+	ProductRepo repository.ProductRepository
+	QueryRepo   repository.SearchQueryRepository
+
+	mu        sync.RWMutex
+	popular   []repository.QueryStat
+	refreshed time.Time
+}
+
+func NewSuggester(productRepo repository.ProductRepository, queryRepo repository.SearchQueryRepository) *Suggester {
+	return &Suggester{
+		ProductRepo: productRepo,
+		QueryRepo:   queryRepo,
+	}
+}
+
+// Refresh reloads the popular queries. It is run by a background job.
+func (s *Suggester) Refresh() error {
+	popular, err := s.QueryRepo.PopularQueries(time.Now().Add(-popularHistory), maxPopular)
+	if err != nil {
+		return fmt.Errorf("failed to load popular queries: %w", err)
+	}
+
+	s.mu.Lock()
+	s.popular = popular
+	s.refreshed = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Suggest returns up to limit products and limit popular queries matching the prefix
+func (s *Suggester) Suggest(prefix string, limit int) (*Suggestions, error) {
+	normalized := entity.NormalizeSearchQuery(prefix)
+	suggestions := &Suggestions{
+		Query:      normalized,
+		Products:   []repository.ProductSuggestion{},
+		Categories: []string{},
+		Queries:    []string{},
+	}
+	if normalized == "" {
+		return suggestions, nil
+	}
+
+	if len([]rune(normalized)) >= minPrefixLength {
+		products, err := s.ProductRepo.SuggestProducts(normalized, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match products: %w", err)
+		}
+		suggestions.Products = products
+	}
+
+	suggestions.Queries = s.popularWithPrefix(normalized, limit)
+	return suggestions, nil
+}
+
+// popularWithPrefix returns the most popular queries starting with prefix, excluding prefix itself
+func (s *Suggester) popularWithPrefix(prefix string, limit int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queries := []string{}
+	for _, stat := range s.popular {
+		if len(queries) >= limit {
+			break
+		}
+		if stat.Query != prefix && strings.HasPrefix(stat.Query, prefix) {
+			queries = append(queries, stat.Query)
+		}
+	}
+	return queries
+}
+
+// RefreshedAt returns when popular queries were last loaded
+func (s *Suggester) RefreshedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshed
+}
@@ -0,0 +1,62 @@
+package tokens
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// oauthStateTTL is how long the user has to sign in at the identity provider
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthState is what the login redirect remembers until the identity provider calls back. It is
+// kept in a signed cookie, so the callback needs no server-side storage.
+type OAuthState struct {
+	// Provider is the identity provider the login was started with
+	Provider string `json:"provider"`
+	// State is echoed by the provider and must match, which ties the callback to this browser
+	State string `json:"state"`
+	// Verifier is the PKCE code verifier the authorization code is redeemed with
+	Verifier string `json:"verifier"`
+	// Nonce must come back inside the ID token, which ties the token to this login
+	Nonce string `json:"nonce"`
+	// RedirectTo is where the user goes after logging in, already checked against the allowlist
+	RedirectTo string `json:"redirect_to,omitempty"`
+	// LinkUserID is set when a logged-in user is linking the provider account rather than logging in
+	LinkUserID uint `json:"link_user_id,omitempty"`
+	jwt.StandardClaims
+}
+
+// OAuthStateTTL is the lifetime of the state cookie
+func (s *Service) OAuthStateTTL() time.Duration {
+	return oauthStateTTL
+}
+
+// IssueOAuthState signs the state of a login redirect
+func (s *Service) IssueOAuthState(state OAuthState) (string, error) {
+	now := time.Now()
+	state.StandardClaims = jwt.StandardClaims{
+		Issuer:    s.issuer,
+		Audience:  s.challengeAudience("oauth"),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(oauthStateTTL).Unix(),
+	}
+	return s.sign(state)
+}
+
+// ParseOAuthState verifies a value issued by IssueOAuthState
+func (s *Service) ParseOAuthState(tokenString string) (*OAuthState, error) {
+	claims := &OAuthState{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid || claims.ExpiresAt == 0 || claims.Provider == "" || claims.State == "" || claims.Verifier == "" || claims.Nonce == "" {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(s.issuer, true) || !claims.VerifyAudience(s.challengeAudience("oauth"), true) {
+		return nil, fmt.Errorf("%w: not an OAuth state", ErrInvalidToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,260 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	defaultIssuer     = "hiddenscore-api"
+	defaultAudience   = "hiddenscore-web"
+	defaultKeyID      = "default"
+	defaultTTL        = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+
+	// challengeTTL is how long a user has to complete the second login step
+	challengeTTL = 5 * time.Minute
+
+	// secrets shorter than this are accepted but logged, HS256 wants at least 256 bits
+	minSecretLength = 32
+)
+
+var (
+	ErrMissingSecret = errors.New("JWT_SECRET_KEY is not set")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+// Claims is the payload of every access token issued by the API
+type Claims struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+	// SessionID is the refresh token family the access token was issued from
+	SessionID string `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+// Config holds the signing settings. PreviousSecret lets tokens signed before a key rotation stay
+// valid until they expire, they are told apart by the kid header.
+type Config struct {
+	Secret         string
+	KeyID          string
+	PreviousSecret string
+	PreviousKeyID  string
+	Issuer         string
+	Audience       string
+	TTL            time.Duration
+	RefreshTTL     time.Duration
+}
+
+// ConfigFromEnv reads the JWT_* settings. JWT_SECRET is still accepted for deployments that were
+// configured for the old utils token helper.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Secret:         os.Getenv("JWT_SECRET_KEY"),
+		KeyID:          os.Getenv("JWT_KEY_ID"),
+		PreviousSecret: os.Getenv("JWT_PREVIOUS_SECRET_KEY"),
+		PreviousKeyID:  os.Getenv("JWT_PREVIOUS_KEY_ID"),
+		Issuer:         os.Getenv("JWT_ISSUER"),
+		Audience:       os.Getenv("JWT_AUDIENCE"),
+	}
+	if cfg.Secret == "" {
+		cfg.Secret = os.Getenv("JWT_SECRET")
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("JWT_TTL_MINUTES")); err == nil && minutes > 0 {
+		cfg.TTL = time.Duration(minutes) * time.Minute
+	}
+	if days, err := strconv.Atoi(os.Getenv("JWT_REFRESH_TTL_DAYS")); err == nil && days > 0 {
+		cfg.RefreshTTL = time.Duration(days) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// Service signs and verifies access tokens. It is the only place in the API that knows the key.
+type Service struct {
+	keyID    string
+	keys     map[string][]byte
+	issuer   string
+	audience string
+	ttl      time.Duration
+	refresh  time.Duration
+}
+
+// NewService validates the config and returns an error when no secret is configured, so the
+// server refuses to start instead of signing tokens with an empty key.
+func NewService(cfg Config) (*Service, error) {
+	if cfg.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if len(cfg.Secret) < minSecretLength {
+		log.Printf("Warning: JWT secret is shorter than %d characters", minSecretLength)
+	}
+
+	if cfg.KeyID == "" {
+		cfg.KeyID = defaultKeyID
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultIssuer
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = defaultAudience
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = defaultRefreshTTL
+	}
+
+	s := &Service{
+		keyID:    cfg.KeyID,
+		keys:     map[string][]byte{cfg.KeyID: []byte(cfg.Secret)},
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+		refresh:  cfg.RefreshTTL,
+	}
+
+	if cfg.PreviousSecret != "" {
+		if cfg.PreviousKeyID == "" || cfg.PreviousKeyID == cfg.KeyID {
+			return nil, fmt.Errorf("JWT_PREVIOUS_KEY_ID must be set and differ from the current key ID")
+		}
+		s.keys[cfg.PreviousKeyID] = []byte(cfg.PreviousSecret)
+	}
+
+	return s, nil
+}
+
+// TTL is how long an issued access token stays valid
+func (s *Service) TTL() time.Duration {
+	return s.ttl
+}
+
+// RefreshTTL is how long a refresh token can be exchanged. Each rotation starts the period again.
+func (s *Service) RefreshTTL() time.Duration {
+	return s.refresh
+}
+
+// Issue signs a new access token for the user with the current key
+func (s *Service) Issue(userID uint, email string, sessionID string) (string, error) {
+	claims := s.claims(userID, email, s.audience, s.ttl)
+	claims.SessionID = sessionID
+	return s.sign(claims)
+}
+
+// Parse verifies the signature, expiry, issuer and audience of an access token and returns the claims
+func (s *Service) Parse(tokenString string) (*Claims, error) {
+	return s.parse(tokenString, s.audience)
+}
+
+// IssueChallenge signs a short-lived token proving that the first step of a multi-step login,
+// such as the password check, succeeded. Its audience names the purpose, so it is never
+// accepted as an access token or for another step.
+func (s *Service) IssueChallenge(userID uint, email string, purpose string) (string, error) {
+	return s.sign(s.claims(userID, email, s.challengeAudience(purpose), challengeTTL))
+}
+
+// ParseChallenge verifies a token issued by IssueChallenge for the purpose
+func (s *Service) ParseChallenge(tokenString string, purpose string) (*Claims, error) {
+	return s.parse(tokenString, s.challengeAudience(purpose))
+}
+
+func (s *Service) challengeAudience(purpose string) string {
+	return s.audience + "/" + purpose
+}
+
+func (s *Service) claims(userID uint, email string, audience string, ttl time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		UserID: userID,
+		Email:  email,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    s.issuer,
+			Audience:  audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	}
+}
+
+func (s *Service) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.keys[s.keyID])
+}
+
+// key picks the verification key by the kid header and only accepts HS256
+func (s *Service) key(token *jwt.Token) (interface{}, error) {
+	if token.Method != jwt.SigningMethodHS256 {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+func (s *Service) parse(tokenString string, audience string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// StandardClaims.Valid skips claims that are missing, these are required
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	if !claims.VerifyIssuer(s.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !claims.VerifyAudience(audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidToken, claims.Audience)
+	}
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("%w: missing user", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// NewRefreshToken returns an opaque refresh token and the hash to store for it
+func NewRefreshToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken is the lookup key of a refresh token. The token is random, so a plain SHA-256 is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewFamilyID identifies the chain of refresh tokens started by one login
+func NewFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,233 @@
+package twofactor
+
+import (
+	"backend/internal/domain/repository"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrNoEnrolment    = errors.New("two-factor enrolment has not been started")
+	ErrInvalidCode    = errors.New("invalid two-factor code")
+)
+
+// Status is what the settings page shows about a user's two-factor setup
+type Status struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// Enrolment is returned when enrolment starts. URI is rendered as a QR code, Secret is shown for
+// manual entry.
+type Enrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Service runs TOTP enrolment and verification on top of the repository
+type Service struct {
+	Repo   repository.TwoFactorRepository
+	Issuer string
+
+	// RequireForAdmins makes two-factor mandatory for users with the admin role
+	RequireForAdmins bool
+
+	sealer *sealer
+	now    func() time.Time
+}
+
+// NewService needs a key to encrypt the TOTP secrets with. issuer is the account name shown in
+// authenticator apps.
+func NewService(repo repository.TwoFactorRepository, encryptionKey, issuer string, requireForAdmins bool) (*Service, error) {
+	s, err := newSealer(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == "" {
+		issuer = "HiddenScore"
+	}
+	return &Service{
+		Repo:             repo,
+		Issuer:           issuer,
+		RequireForAdmins: requireForAdmins,
+		sealer:           s,
+		now:              time.Now,
+	}, nil
+}
+
+// Enabled reports whether logins of the user need a second factor
+func (s *Service) Enabled(userID uint) (bool, error) {
+	tf, err := s.Repo.FindByUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.Enabled, nil
+}
+
+func (s *Service) Status(userID uint) (*Status, error) {
+	tf, err := s.Repo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil || !tf.Enabled {
+		return &Status{}, nil
+	}
+
+	left, err := s.Repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{Enabled: true, EnabledAt: tf.EnabledAt, RecoveryCodesLeft: left}, nil
+}
+
+// BeginEnrolment generates a new secret. Starting again before confirming replaces the secret.
+func (s *Service) BeginEnrolment(userID uint, account string) (*Enrolment, error) {
+	tf, err := s.Repo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.sealer.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.SavePending(userID, sealed); err != nil {
+		return nil, err
+	}
+
+	return &Enrolment{Secret: secret, URI: ProvisioningURI(s.Issuer, account, secret)}, nil
+}
+
+// ConfirmEnrolment enables two-factor once the user enters a valid code and returns the recovery
+// codes. They are only ever shown here.
+func (s *Service) ConfirmEnrolment(userID uint, code string) ([]string, error) {
+	tf, err := s.Repo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrNoEnrolment
+	}
+	if tf.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := s.sealer.open(tf.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := Validate(secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyCode checks a TOTP code of an enabled user. A code is accepted once.
+func (s *Service) VerifyCode(userID uint, code string) error {
+	tf, err := s.Repo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return ErrNotEnabled
+	}
+
+	secret, err := s.sealer.open(tf.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := Validate(secret, code, s.now())
+	if !ok || step <= tf.LastUsedStep {
+		return ErrInvalidCode
+	}
+
+	// a concurrent request may have used the same code between the read and now
+	fresh, err := s.Repo.UseStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// VerifyRecoveryCode consumes one of the user's recovery codes
+func (s *Service) VerifyRecoveryCode(userID uint, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidCode
+	}
+	ok, err := s.Repo.UseRecoveryCode(userID, hashRecoveryCode(normalized))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// Verify accepts either a TOTP code or a recovery code, told apart by their length
+func (s *Service) Verify(userID uint, code string) error {
+	if len(normalizeRecoveryCode(code)) > digits {
+		return s.VerifyRecoveryCode(userID, code)
+	}
+	return s.VerifyCode(userID, code)
+}
+
+// RegenerateRecoveryCodes invalidates the remaining recovery codes and returns a new set
+func (s *Service) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrNotEnabled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor off. The caller re-authenticates the user first.
+func (s *Service) Disable(userID uint) error {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNotEnabled
+	}
+	return s.Repo.Disable(userID)
+}
+
+// Required reports whether the user must have two-factor enabled because of their role
+func (s *Service) Required(role string) bool {
+	return s.RequireForAdmins && role == "admin"
+}
@@ -0,0 +1,82 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent    byte = 0x01
+	flagUserVerified   byte = 0x04
+	flagBackupEligible byte = 0x08
+	flagBackupState    byte = 0x10
+	flagAttestedData   byte = 0x40
+	flagExtensionData  byte = 0x80
+)
+
+// authenticatorData is the binary structure signed by the authenticator in both ceremonies
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// set when the attested credential data flag is on, which is the case during registration
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+func (a *authenticatorData) has(flag byte) bool {
+	return a.Flags&flag != 0
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	// rpIdHash (32) + flags (1) + signCount (4)
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data is %d bytes", ErrInvalidResponse, len(data))
+	}
+
+	a := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if a.has(flagAttestedData) {
+		// aaguid (16) + credentialIdLength (2)
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data is truncated", ErrInvalidResponse)
+		}
+		a.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: invalid credential ID length %d", ErrInvalidResponse, idLen)
+		}
+		a.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// the public key is a CBOR map of unknown length, decoding it tells where it ends
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+		}
+		a.CredentialPublicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if a.has(flagExtensionData) {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extension data: %v", ErrInvalidResponse, err)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes in authenticator data", ErrInvalidResponse, len(rest))
+	}
+	return a, nil
+}
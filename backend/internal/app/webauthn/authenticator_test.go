@@ -0,0 +1,187 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// softAuthenticator is an ES256 authenticator in software. It builds the clientDataJSON and
+// authenticator data a browser and a security key would, and signs them with its own key.
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	rpID         string
+	origin       string
+	signCount    uint32
+	// attestation is "none" or "packed" (self attestation)
+	attestation string
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential ID: %v", err)
+	}
+	return &softAuthenticator{t: t, key: key, credentialID: credentialID, rpID: rpID, origin: origin, attestation: "none"}
+}
+
+// coseKey is the authenticator's public key as a COSE_Key
+func (a *softAuthenticator) coseKey() []byte {
+	x := a.key.PublicKey.X.FillBytes(make([]byte, 32))
+	y := a.key.PublicKey.Y.FillBytes(make([]byte, 32))
+	return cborEncode(cborMapOf(
+		int64(coseKty), int64(coseKtyEC2),
+		int64(coseAlg), AlgES256,
+		int64(-1), int64(coseCrvP256),
+		int64(-2), x,
+		int64(-3), y,
+	))
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	raw, err := json.Marshal(ClientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.origin,
+	})
+	if err != nil {
+		a.t.Fatalf("marshal client data: %v", err)
+	}
+	return raw
+}
+
+// authData builds the authenticator data; registrations carry the attested credential data
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	flags := flagUserPresent | flagUserVerified
+	if attested {
+		flags |= flagAttestedData
+	}
+
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *softAuthenticator) sign(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("sign: %v", err)
+	}
+	return sig
+}
+
+// register answers navigator.credentials.create() for the challenge
+func (a *softAuthenticator) register(challenge []byte) *RegistrationResponse {
+	clientDataJSON := a.clientData("webauthn.create", challenge)
+	authData := a.authData(true)
+
+	stmt := cborMapOf()
+	if a.attestation == "packed" {
+		stmt = cborMapOf("alg", AlgES256, "sig", a.sign(authData, clientDataJSON))
+	}
+	attestationObject := cborEncode(cborMapOf(
+		"fmt", a.attestation,
+		"attStmt", stmt,
+		"authData", authData,
+	))
+
+	return &RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+// login answers navigator.credentials.get() for the challenge, counting the signature
+func (a *softAuthenticator) login(challenge, userHandle []byte) *AssertionResponse {
+	a.signCount++
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authData := a.authData(false)
+
+	return &AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AssertionData{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         a.sign(authData, clientDataJSON),
+			UserHandle:        userHandle,
+		},
+	}
+}
+
+// cborPairs is a CBOR map whose keys are written in the given order
+type cborPairs [][2]interface{}
+
+func cborMapOf(keyValues ...interface{}) cborPairs {
+	pairs := make(cborPairs, 0, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		pairs = append(pairs, [2]interface{}{keyValues[i], keyValues[i+1]})
+	}
+	return pairs
+}
+
+// cborEncode writes the subset of CBOR authenticators emit: integers, byte and text strings, and maps
+func cborEncode(v interface{}) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case cborPairs:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, cborEncode(pair[0])...)
+			out = append(out, cborEncode(pair[1])...)
+		}
+		return out
+	}
+	panic("cborEncode: unsupported type")
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
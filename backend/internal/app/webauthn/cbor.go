@@ -0,0 +1,211 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// WebAuthn encodes attestation objects and public keys in CBOR (RFC 8949). Only the definite-length
+// subset authenticators are required to emit (CTAP2 canonical CBOR) is decoded here.
+
+const maxCBORDepth = 16
+
+var errCBOR = errors.New("malformed CBOR")
+
+// decodeCBOR decodes the first item in data and returns it with the number of bytes it used.
+// Integers are returned as int64, byte strings as []byte, text as string, arrays as []interface{}
+// and maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) remaining() int {
+	return len(d.data) - d.pos
+}
+
+// head reads the initial byte and its argument
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	if d.remaining() < 1 {
+		return 0, 0, 0, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+
+	var size int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: unsupported additional info %d", errCBOR, info)
+	}
+
+	if d.remaining() < size {
+		return 0, 0, 0, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+	raw := d.data[d.pos : d.pos+size]
+	d.pos += size
+	switch size {
+	case 1:
+		arg = uint64(raw[0])
+	case 2:
+		arg = uint64(binary.BigEndian.Uint16(raw))
+	case 4:
+		arg = uint64(binary.BigEndian.Uint32(raw))
+	case 8:
+		arg = binary.BigEndian.Uint64(raw)
+	}
+	return major, info, arg, nil
+}
+
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(d.remaining()) {
+		return nil, fmt.Errorf("%w: length %d exceeds data", errCBOR, n)
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", errCBOR)
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0: // unsigned integer
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return int64(arg), nil
+	case 1: // negative integer
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return -1 - int64(arg), nil
+	case 2: // byte string
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 3: // text string
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 4: // array
+		if arg > uint64(d.remaining()) {
+			return nil, fmt.Errorf("%w: array length %d exceeds data", errCBOR, arg)
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case 5: // map
+		if arg > uint64(d.remaining()) {
+			return nil, fmt.Errorf("%w: map length %d exceeds data", errCBOR, arg)
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key type %T", errCBOR, k)
+			}
+			if _, dup := m[k]; dup {
+				return nil, fmt.Errorf("%w: duplicate map key %v", errCBOR, k)
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case 6: // tag, the tagged value is returned as is
+		return d.decode(depth + 1)
+	default: // simple values and floats
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return nil, fmt.Errorf("%w: half-precision floats are not supported", errCBOR)
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+		return nil, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+}
+
+// cborMap decodes data that must hold exactly one CBOR map
+func cborMap(data []byte) (map[interface{}]interface{}, error) {
+	v, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", errCBOR, len(data)-n)
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a map, got %T", errCBOR, v)
+	}
+	return m, nil
+}
+
+func mapInt(m map[interface{}]interface{}, key interface{}) (int64, bool) {
+	v, ok := m[key].(int64)
+	return v, ok
+}
+
+func mapBytes(m map[interface{}]interface{}, key interface{}) ([]byte, bool) {
+	v, ok := m[key].([]byte)
+	return v, ok
+}
+
+func mapString(m map[interface{}]interface{}, key interface{}) (string, bool) {
+	v, ok := m[key].(string)
+	return v, ok
+}
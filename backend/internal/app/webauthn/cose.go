@@ -0,0 +1,130 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) offered to authenticators, in order of preference
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var supportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters
+const (
+	coseKty = 1
+	coseAlg = 3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+var errUnsupportedKey = errors.New("unsupported credential public key")
+
+// publicKey is a parsed COSE_Key
+type publicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// parsePublicKey reads a COSE_Key as found in the attested credential data
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	m, err := cborMap(cose)
+	if err != nil {
+		return nil, err
+	}
+
+	kty, _ := mapInt(m, int64(coseKty))
+	alg, ok := mapInt(m, int64(coseAlg))
+	if !ok {
+		return nil, fmt.Errorf("%w: missing algorithm", errUnsupportedKey)
+	}
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := mapInt(m, int64(-1))
+		x, okX := mapBytes(m, int64(-2))
+		y, okY := mapBytes(m, int64(-3))
+		if crv != coseCrvP256 || !okX || !okY || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid P-256 key", errUnsupportedKey)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on the curve", errUnsupportedKey)
+		}
+		return &publicKey{Algorithm: alg, Key: key}, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := mapInt(m, int64(-1))
+		x, ok := mapBytes(m, int64(-2))
+		if crv != coseCrvEd25519 || !ok || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key", errUnsupportedKey)
+		}
+		return &publicKey{Algorithm: alg, Key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, okN := mapBytes(m, int64(-1))
+		e, okE := mapBytes(m, int64(-2))
+		if !okN || !okE || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA key", errUnsupportedKey)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%w: RSA key shorter than 2048 bits", errUnsupportedKey)
+		}
+		return &publicKey{Algorithm: alg, Key: key}, nil
+	}
+
+	return nil, fmt.Errorf("%w: key type %d with algorithm %d", errUnsupportedKey, kty, alg)
+}
+
+// verifySignature checks sig over data with the key, using the hash and padding of alg
+func verifySignature(key crypto.PublicKey, alg int64, data, sig []byte) error {
+	switch alg {
+	case AlgES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: ES256 needs an ECDSA key", errUnsupportedKey)
+		}
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return ErrBadSignature
+		}
+		return nil
+	case AlgEdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: EdDSA needs an Ed25519 key", errUnsupportedKey)
+		}
+		if !ed25519.Verify(k, data, sig) {
+			return ErrBadSignature
+		}
+		return nil
+	case AlgRS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 needs an RSA key", errUnsupportedKey)
+		}
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return ErrBadSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: algorithm %d", errUnsupportedKey, alg)
+}
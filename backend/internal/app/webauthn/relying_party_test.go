@@ -0,0 +1,313 @@
+package webauthn
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	testRPID   = "shop.example"
+	testOrigin = "https://shop.example"
+)
+
+func newTestRelyingParty(t *testing.T) *RelyingParty {
+	t.Helper()
+	rp, err := NewRelyingParty(Config{RPID: testRPID, Origins: []string{testOrigin}})
+	if err != nil {
+		t.Fatalf("NewRelyingParty: %v", err)
+	}
+	return rp
+}
+
+func newTestChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return challenge
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	for _, format := range []string{"none", "packed"} {
+		t.Run(format, func(t *testing.T) {
+			rp := newTestRelyingParty(t)
+			auth := newSoftAuthenticator(t, testRPID, testOrigin)
+			auth.attestation = format
+
+			challenge := newTestChallenge(t)
+			cred, err := rp.VerifyRegistration(auth.register(challenge), challenge)
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if cred.Algorithm != AlgES256 {
+				t.Errorf("algorithm = %d, want %d", cred.Algorithm, AlgES256)
+			}
+			if cred.AttestationFormat != format {
+				t.Errorf("attestation format = %q, want %q", cred.AttestationFormat, format)
+			}
+			if string(cred.ID) != string(auth.credentialID) {
+				t.Error("credential ID differs from the authenticator's")
+			}
+			if !cred.UserVerified {
+				t.Error("user verification flag was lost")
+			}
+
+			stored := StoredCredential{PublicKey: cred.PublicKey, SignCount: cred.SignCount, UserHandle: UserHandle(7)}
+			for i := 1; i <= 2; i++ {
+				challenge = newTestChallenge(t)
+				assertion, err := rp.VerifyAssertion(auth.login(challenge, UserHandle(7)), challenge, stored)
+				if err != nil {
+					t.Fatalf("VerifyAssertion %d: %v", i, err)
+				}
+				if assertion.SignCount != uint32(i) {
+					t.Errorf("sign count = %d, want %d", assertion.SignCount, i)
+				}
+				stored.SignCount = assertion.SignCount
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsWrongChallenge(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+
+	_, err := rp.VerifyRegistration(auth.register(newTestChallenge(t)), newTestChallenge(t))
+	if !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("registration: err = %v, want %v", err, ErrChallengeMismatch)
+	}
+
+	challenge := newTestChallenge(t)
+	cred, err := rp.VerifyRegistration(auth.register(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	stored := StoredCredential{PublicKey: cred.PublicKey, UserHandle: UserHandle(1)}
+	_, err = rp.VerifyAssertion(auth.login(newTestChallenge(t), nil), newTestChallenge(t), stored)
+	if !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("assertion: err = %v, want %v", err, ErrChallengeMismatch)
+	}
+}
+
+func TestVerifyRejectsWrongOrigin(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	good := newSoftAuthenticator(t, testRPID, testOrigin)
+	challenge := newTestChallenge(t)
+	cred, err := rp.VerifyRegistration(good.register(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	stored := StoredCredential{PublicKey: cred.PublicKey, UserHandle: UserHandle(1)}
+
+	for _, origin := range []string{"https://evil.example", "http://shop.example", "https://shop.example:8443", "https://sub.shop.example"} {
+		t.Run(origin, func(t *testing.T) {
+			auth := newSoftAuthenticator(t, testRPID, origin)
+			challenge := newTestChallenge(t)
+			if _, err := rp.VerifyRegistration(auth.register(challenge), challenge); !errors.Is(err, ErrOriginNotAllowed) {
+				t.Errorf("registration: err = %v, want %v", err, ErrOriginNotAllowed)
+			}
+
+			// the registered key signing for a page on another origin
+			good.origin = origin
+			defer func() { good.origin = testOrigin }()
+			challenge = newTestChallenge(t)
+			if _, err := rp.VerifyAssertion(good.login(challenge, nil), challenge, stored); !errors.Is(err, ErrOriginNotAllowed) {
+				t.Errorf("assertion: err = %v, want %v", err, ErrOriginNotAllowed)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsWrongRPID(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	challenge := newTestChallenge(t)
+
+	// a credential scoped to another site, relayed through a page on our origin
+	auth := newSoftAuthenticator(t, "evil.example", testOrigin)
+	if _, err := rp.VerifyRegistration(auth.register(challenge), challenge); !errors.Is(err, ErrRPIDMismatch) {
+		t.Errorf("registration: err = %v, want %v", err, ErrRPIDMismatch)
+	}
+
+	good := newSoftAuthenticator(t, testRPID, testOrigin)
+	cred, err := rp.VerifyRegistration(good.register(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	stored := StoredCredential{PublicKey: cred.PublicKey, UserHandle: UserHandle(1)}
+
+	good.rpID = "evil.example"
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(good.login(challenge, nil), challenge, stored); !errors.Is(err, ErrRPIDMismatch) {
+		t.Errorf("assertion: err = %v, want %v", err, ErrRPIDMismatch)
+	}
+}
+
+func TestVerifyAssertionRejectsSignCountRegression(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+	challenge := newTestChallenge(t)
+	cred, err := rp.VerifyRegistration(auth.register(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		stored uint32
+		sent   uint32
+	}{
+		{name: "lower", stored: 10, sent: 4},
+		{name: "equal", stored: 10, sent: 10},
+		{name: "reset to zero", stored: 10, sent: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// login counts the signature before signing, wrapping around for the zero case
+			auth.signCount = tc.sent - 1
+			stored := StoredCredential{PublicKey: cred.PublicKey, SignCount: tc.stored, UserHandle: UserHandle(1)}
+			challenge := newTestChallenge(t)
+			if _, err := rp.VerifyAssertion(auth.login(challenge, nil), challenge, stored); !errors.Is(err, ErrSignCountRegression) {
+				t.Errorf("err = %v, want %v", err, ErrSignCountRegression)
+			}
+		})
+	}
+
+	// authenticators without a counter report zero every time
+	auth.signCount = ^uint32(0)
+	stored := StoredCredential{PublicKey: cred.PublicKey, SignCount: 0, UserHandle: UserHandle(1)}
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(auth.login(challenge, nil), challenge, stored); err != nil {
+		t.Errorf("counterless authenticator: %v", err)
+	}
+}
+
+func TestVerifyAssertionRejectsBadSignature(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+	challenge := newTestChallenge(t)
+	cred, err := rp.VerifyRegistration(auth.register(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	stored := StoredCredential{PublicKey: cred.PublicKey, UserHandle: UserHandle(1)}
+
+	// another authenticator's signature over the same data
+	other := newSoftAuthenticator(t, testRPID, testOrigin)
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(other.login(challenge, nil), challenge, stored); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("foreign key: err = %v, want %v", err, ErrBadSignature)
+	}
+
+	// a flag flipped after signing
+	challenge = newTestChallenge(t)
+	resp := auth.login(challenge, nil)
+	resp.Response.AuthenticatorData[32] |= flagBackupState
+	if _, err := rp.VerifyAssertion(resp, challenge, stored); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered data: err = %v, want %v", err, ErrBadSignature)
+	}
+
+	// a user handle that isn't the credential's
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(auth.login(challenge, UserHandle(2)), challenge, stored); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("user handle: err = %v, want %v", err, ErrInvalidResponse)
+	}
+}
+
+func TestVerifyRejectsMalformedCBOR(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+
+	authData := auth.authData(true)
+	// the COSE key ends the attested authenticator data
+	truncatedKey := authData[:len(authData)-5]
+	attestation := func(authData []byte) []byte {
+		return cborEncode(cborMapOf("fmt", "none", "attStmt", cborMapOf(), "authData", authData))
+	}
+	valid := attestation(authData)
+
+	for _, tc := range []struct {
+		name              string
+		attestationObject []byte
+	}{
+		{name: "empty", attestationObject: nil},
+		{name: "truncated", attestationObject: valid[:len(valid)-10]},
+		{name: "trailing bytes", attestationObject: append(append([]byte(nil), valid...), 0x00)},
+		{name: "not a map", attestationObject: cborEncode("none")},
+		{name: "indefinite length map", attestationObject: append([]byte{0xbf}, valid[1:]...)},
+		{name: "length beyond data", attestationObject: []byte{0x5a, 0xff, 0xff, 0xff, 0xff}},
+		{name: "duplicate key", attestationObject: cborEncode(cborMapOf("fmt", "none", "fmt", "none"))},
+		{name: "byte string key", attestationObject: cborEncode(cborMapOf([]byte("fmt"), "none"))},
+		{name: "truncated COSE key", attestationObject: attestation(truncatedKey)},
+		{name: "authenticator data with trailing bytes", attestationObject: attestation(append(append([]byte(nil), authData...), 0x01))},
+		{name: "short authenticator data", attestationObject: attestation(authData[:20])},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			challenge := newTestChallenge(t)
+			resp := auth.register(challenge)
+			resp.Response.AttestationObject = tc.attestationObject
+			if _, err := rp.VerifyRegistration(resp, challenge); !errors.Is(err, ErrInvalidResponse) {
+				t.Errorf("err = %v, want %v", err, ErrInvalidResponse)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORLimits(t *testing.T) {
+	nested := make([]byte, 0, maxCBORDepth+2)
+	for i := 0; i < maxCBORDepth+2; i++ {
+		nested = append(nested, 0x81) // array of one item
+	}
+	nested = append(nested, 0x00)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "nested too deeply", data: nested},
+		{name: "integer overflow", data: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "array longer than data", data: []byte{0x9a, 0x00, 0x01, 0x00, 0x00}},
+		{name: "missing argument", data: []byte{0x19, 0x01}},
+		{name: "half-precision float", data: []byte{0xf9, 0x3c, 0x00}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tc.data); !errors.Is(err, errCBOR) {
+				t.Errorf("err = %v, want %v", err, errCBOR)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeyRejectsInvalidKeys(t *testing.T) {
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+	x := auth.key.PublicKey.X.FillBytes(make([]byte, 32))
+
+	for _, tc := range []struct {
+		name string
+		key  []byte
+	}{
+		{name: "no algorithm", key: cborEncode(cborMapOf(int64(coseKty), int64(coseKtyEC2)))},
+		{name: "point off the curve", key: cborEncode(cborMapOf(
+			int64(coseKty), int64(coseKtyEC2), int64(coseAlg), AlgES256,
+			int64(-1), int64(coseCrvP256), int64(-2), x, int64(-3), x,
+		))},
+		{name: "wrong curve", key: cborEncode(cborMapOf(
+			int64(coseKty), int64(coseKtyEC2), int64(coseAlg), AlgES256,
+			int64(-1), int64(2), int64(-2), x, int64(-3), x,
+		))},
+		{name: "unsupported algorithm", key: cborEncode(cborMapOf(int64(coseKty), int64(coseKtyEC2), int64(coseAlg), int64(-35)))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parsePublicKey(tc.key); !errors.Is(err, errUnsupportedKey) {
+				t.Errorf("err = %v, want %v", err, errUnsupportedKey)
+			}
+		})
+	}
+
+	key, err := parsePublicKey(auth.coseKey())
+	if err != nil {
+		t.Fatalf("parsePublicKey: %v", err)
+	}
+	if key.Algorithm != AlgES256 {
+		t.Errorf("algorithm = %d, want %d", key.Algorithm, AlgES256)
+	}
+}
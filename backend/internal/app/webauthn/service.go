@@ -0,0 +1,288 @@
+package webauthn
+
+import (
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	purposeRegistration   = "registration"
+	purposeAuthentication = "authentication"
+)
+
+var (
+	ErrChallengeNotFound  = errors.New("the passkey request has expired, please try again")
+	ErrCredentialNotFound = errors.New("passkey is not registered")
+	ErrCredentialExists   = errors.New("passkey is already registered")
+)
+
+// ConfigFromEnv reads the WEBAUTHN_* settings. Without them the relying party is the host of the
+// storefront URL and the storefront is the only allowed origin.
+func ConfigFromEnv(frontendURL string) Config {
+	cfg := Config{
+		RPID:   os.Getenv("WEBAUTHN_RP_ID"),
+		RPName: os.Getenv("WEBAUTHN_RP_NAME"),
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = "HiddenScore"
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.Origins = append(cfg.Origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if len(cfg.Origins) == 0 && frontendURL != "" {
+		cfg.Origins = []string{strings.TrimSuffix(frontendURL, "/")}
+	}
+	if cfg.RPID == "" && len(cfg.Origins) > 0 {
+		if u, err := url.Parse(cfg.Origins[0]); err == nil {
+			cfg.RPID = u.Hostname()
+		}
+	}
+	cfg.RequireUserVerification, _ = strconv.ParseBool(os.Getenv("WEBAUTHN_REQUIRE_USER_VERIFICATION"))
+	return cfg
+}
+
+// Service runs the passkey ceremonies: it issues and consumes challenges and stores credentials
+// around the stateless RelyingParty
+type Service struct {
+	RP   *RelyingParty
+	Repo repository.PasskeyRepository
+}
+
+func NewService(rp *RelyingParty, repo repository.PasskeyRepository) *Service {
+	return &Service{RP: rp, Repo: repo}
+}
+
+// UserHandle is the user.id given to authenticators. It is the user ID as 8 big-endian bytes, which
+// carries no personal information as the specification requires.
+func UserHandle(userID uint) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userID))
+	return b
+}
+
+func (s *Service) issueChallenge(purpose string, userID *uint) ([]byte, error) {
+	challenge, err := NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	record := &entity.PasskeyChallenge{
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Purpose:   purpose,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.RP.Timeout()),
+	}
+	if err := s.Repo.CreateChallenge(record); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeChallenge finds the challenge the response was made for through its client data
+func (s *Service) consumeChallenge(clientDataJSON []byte, purpose string) (*entity.PasskeyChallenge, []byte, error) {
+	cd, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := strings.TrimRight(cd.Challenge, "=")
+	challenge, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return nil, nil, ErrChallengeMismatch
+	}
+
+	record, err := s.Repo.ConsumeChallenge(key, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil || time.Now().After(record.ExpiresAt) {
+		return nil, nil, ErrChallengeNotFound
+	}
+	return record, challenge, nil
+}
+
+func descriptors(passkeys []entity.Passkey) ([]CredentialDescriptor, error) {
+	out := make([]CredentialDescriptor, 0, len(passkeys))
+	for _, p := range passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(p.CredentialID)
+		if err != nil {
+			return nil, fmt.Errorf("passkey %d has a malformed credential ID: %w", p.ID, err)
+		}
+		d := CredentialDescriptor{Type: "public-key", ID: id}
+		if p.Transports != "" {
+			d.Transports = strings.Split(p.Transports, ",")
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// BeginRegistration issues the options for adding a passkey to the user's account
+func (s *Service) BeginRegistration(user *entity.User) (*CreationOptions, error) {
+	existing, err := s.Repo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := descriptors(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := user.ID
+	challenge, err := s.issueChallenge(purposeRegistration, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := user.Name
+	if displayName == "" {
+		displayName = user.Email
+	}
+	return s.RP.CreationOptions(challenge, UserEntity{ID: UserHandle(user.ID), Name: user.Email, DisplayName: displayName}, exclude), nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the new passkey
+func (s *Service) FinishRegistration(user *entity.User, name string, resp *RegistrationResponse) (*entity.Passkey, error) {
+	record, challenge, err := s.consumeChallenge(resp.Response.ClientDataJSON, purposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserID == nil || *record.UserID != user.ID {
+		return nil, ErrChallengeNotFound
+	}
+
+	cred, err := s.RP.VerifyRegistration(resp, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(cred.ID)
+	existing, err := s.Repo.FindByCredentialID(credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCredentialExists
+	}
+
+	if name = strings.TrimSpace(name); name == "" {
+		name = "Passkey"
+	}
+	passkey := &entity.Passkey{
+		UserID:         user.ID,
+		Name:           name,
+		CredentialID:   credentialID,
+		PublicKey:      cred.PublicKey,
+		Algorithm:      cred.Algorithm,
+		SignCount:      int64(cred.SignCount),
+		AAGUID:         formatAAGUID(cred.AAGUID),
+		Transports:     strings.Join(cred.Transports, ","),
+		BackupEligible: cred.BackupEligible,
+		BackupState:    cred.BackupState,
+	}
+	if err := s.Repo.Create(passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginLogin issues the options for signing in. With a user the browser is limited to their
+// passkeys, without one it offers every passkey it holds for the site.
+func (s *Service) BeginLogin(user *entity.User) (*RequestOptions, error) {
+	var allow []CredentialDescriptor
+	var userID *uint
+	if user != nil {
+		passkeys, err := s.Repo.ListByUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if allow, err = descriptors(passkeys); err != nil {
+			return nil, err
+		}
+		id := user.ID
+		userID = &id
+	}
+
+	challenge, err := s.issueChallenge(purposeAuthentication, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.RP.RequestOptions(challenge, allow), nil
+}
+
+// FinishLogin verifies the assertion and returns the passkey that signed it
+func (s *Service) FinishLogin(resp *AssertionResponse) (*entity.Passkey, *Assertion, error) {
+	record, challenge, err := s.consumeChallenge(resp.Response.ClientDataJSON, purposeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	passkey, err := s.Repo.FindByCredentialID(base64.RawURLEncoding.EncodeToString(resp.RawID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if passkey == nil {
+		return nil, nil, ErrCredentialNotFound
+	}
+	// a login started for one account cannot be finished with another account's passkey
+	if record.UserID != nil && *record.UserID != passkey.UserID {
+		return nil, nil, ErrCredentialNotFound
+	}
+
+	assertion, err := s.RP.VerifyAssertion(resp, challenge, StoredCredential{
+		PublicKey:  passkey.PublicKey,
+		SignCount:  uint32(passkey.SignCount),
+		UserHandle: UserHandle(passkey.UserID),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if err := s.Repo.RecordUse(passkey.ID, int64(assertion.SignCount), assertion.BackupState, now); err != nil {
+		log.Printf("Failed to record use of passkey %d: %v", passkey.ID, err)
+	}
+	passkey.SignCount = int64(assertion.SignCount)
+	passkey.LastUsedAt = &now
+	return passkey, assertion, nil
+}
+
+func (s *Service) List(userID uint) ([]entity.Passkey, error) {
+	return s.Repo.ListByUser(userID)
+}
+
+func (s *Service) Delete(userID uint, id uint) (bool, error) {
+	return s.Repo.Delete(userID, id)
+}
+
+// PruneChallenges drops ceremonies that were started but never finished
+func (s *Service) PruneChallenges() error {
+	n, err := s.Repo.PruneChallenges(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to prune passkey challenges: %w", err)
+	}
+	if n > 0 {
+		log.Printf("[JOB] Pruned %d expired passkey challenges", n)
+	}
+	return nil
+}
+
+// formatAAGUID renders the authenticator model ID in the usual UUID form
+func formatAAGUID(b []byte) string {
+	if len(b) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
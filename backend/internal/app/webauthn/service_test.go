@@ -0,0 +1,158 @@
+package webauthn
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPasskeyRepository keeps passkeys and challenges in memory, consuming a challenge deletes it
+// as the database one does
+type memoryPasskeyRepository struct {
+	mu         sync.Mutex
+	challenges map[string]entity.PasskeyChallenge
+	passkeys   []entity.Passkey
+}
+
+func newMemoryPasskeyRepository() *memoryPasskeyRepository {
+	return &memoryPasskeyRepository{challenges: make(map[string]entity.PasskeyChallenge)}
+}
+
+func (r *memoryPasskeyRepository) CreateChallenge(challenge *entity.PasskeyChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges[challenge.Challenge] = *challenge
+	return nil
+}
+
+func (r *memoryPasskeyRepository) ConsumeChallenge(challenge string, purpose string) (*entity.PasskeyChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.challenges[challenge]
+	if !ok || record.Purpose != purpose {
+		return nil, nil
+	}
+	delete(r.challenges, challenge)
+	return &record, nil
+}
+
+func (r *memoryPasskeyRepository) PruneChallenges(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryPasskeyRepository) Create(passkey *entity.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	passkey.ID = uint(len(r.passkeys) + 1)
+	r.passkeys = append(r.passkeys, *passkey)
+	return nil
+}
+
+func (r *memoryPasskeyRepository) FindByCredentialID(credentialID string) (*entity.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.passkeys {
+		if p.CredentialID == credentialID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryPasskeyRepository) ListByUser(userID uint) ([]entity.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []entity.Passkey
+	for _, p := range r.passkeys {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryPasskeyRepository) RecordUse(id uint, signCount int64, backupState bool, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.passkeys {
+		if r.passkeys[i].ID == id {
+			r.passkeys[i].SignCount = signCount
+			r.passkeys[i].BackupState = backupState
+			r.passkeys[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryPasskeyRepository) Delete(userID uint, id uint) (bool, error) {
+	return false, nil
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(newTestRelyingParty(t), newMemoryPasskeyRepository())
+}
+
+func TestServiceRejectsChallengeReuse(t *testing.T) {
+	service := newTestService(t)
+	user := &entity.User{Email: "ada@shop.example"}
+	user.ID = 42
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+
+	options, err := service.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	registration := auth.register(options.Challenge)
+	if _, err := service.FinishRegistration(user, "Laptop", registration); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if _, err := service.FinishRegistration(user, "Laptop", registration); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("replayed registration: err = %v, want %v", err, ErrChallengeNotFound)
+	}
+
+	request, err := service.BeginLogin(nil)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	assertion := auth.login(request.Challenge, UserHandle(user.ID))
+	passkey, _, err := service.FinishLogin(assertion)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if passkey.UserID != user.ID {
+		t.Errorf("passkey belongs to user %d, want %d", passkey.UserID, user.ID)
+	}
+	if _, _, err := service.FinishLogin(assertion); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("replayed login: err = %v, want %v", err, ErrChallengeNotFound)
+	}
+}
+
+func TestServiceRejectsChallengeOfAnotherCeremony(t *testing.T) {
+	service := newTestService(t)
+	user := &entity.User{Email: "ada@shop.example"}
+	user.ID = 42
+	auth := newSoftAuthenticator(t, testRPID, testOrigin)
+
+	// a login challenge can't complete a registration
+	request, err := service.BeginLogin(user)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if _, err := service.FinishRegistration(user, "Laptop", auth.register(request.Challenge)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrChallengeNotFound)
+	}
+
+	// nor is a registration challenge issued to one user usable by another
+	options, err := service.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	other := &entity.User{Email: "eve@shop.example"}
+	other.ID = 43
+	if _, err := service.FinishRegistration(other, "Laptop", auth.register(options.Challenge)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrChallengeNotFound)
+	}
+}
@@ -1,9 +1,53 @@
 package entity
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Order statuses, as shown in the admin order screens
+const (
+	OrderStatusProcessing = 1
+	OrderStatusConfirmed  = 2
+	OrderStatusShipping   = 3
+	OrderStatusDelivered  = 4
+	OrderStatusCancelled  = 5
+)
+
+// OrderStatusLabel returns the customer-facing name of an order status
+func OrderStatusLabel(status int) string {
+	switch status {
+	case OrderStatusProcessing:
+		return "Processing"
+	case OrderStatusConfirmed:
+		return "Confirmed"
+	case OrderStatusShipping:
+		return "Shipping"
+	case OrderStatusDelivered:
+		return "Delivered"
+	case OrderStatusCancelled:
+		return "Cancelled"
+	default:
+		return "Unknown"
+	}
+}
+
+// Address is a postal address captured at checkout
+type Address struct {
+	FullName   string `json:"fullName"`
+	Email      string `json:"email"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	Country    string `json:"country"`
+	PostalCode string `json:"zipCode"`
+}
+
+// IsEmpty reports whether no address was given
+func (a Address) IsEmpty() bool {
+	return a == Address{}
+}
+
 // Cart represents a user's shopping cart
 type Cart struct {
 	gorm.Model
@@ -12,6 +56,12 @@ type Cart struct {
 	CartItems []CartItem `json:"cart_items"`
 	Status    int        `json:"status" gorm:"default:0"`
 	Active    bool       `json:"active" gorm:"default:true"`
+	// OrderNumber is the customer-facing order reference, e.g. HS-2026-000123, assigned at checkout
+	OrderNumber *string `json:"order_number" gorm:"uniqueIndex;size:32"`
+	// OrderedAt is set at checkout; older orders only have updated_at
+	OrderedAt *time.Time `json:"ordered_at" gorm:"index"`
+	// ShippingAddress is set at checkout when the customer provides one
+	ShippingAddress Address `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
 }
 
 // CartItem represents a product in a cart with its quantity
@@ -21,4 +71,6 @@ type CartItem struct {
 	ProductID uint    `json:"product_id"`
 	Product   Product `json:"product" gorm:"foreignKey:ProductID"`
 	Quantity  int     `json:"quantity" gorm:"default:1"`
+	// UnitPrice is the product price captured at checkout, 0 for open carts and older orders
+	UnitPrice float64 `json:"unit_price" gorm:"default:0"`
 }
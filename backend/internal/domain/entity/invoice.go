@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice records the invoice issued for an order. Amounts are frozen when it is issued.
+type Invoice struct {
+	gorm.Model
+	Number   string    `json:"number" gorm:"uniqueIndex;size:32;not null"`
+	OrderID  uint      `json:"order_id" gorm:"uniqueIndex;not null"`
+	UserID   uint      `json:"user_id" gorm:"index"`
+	IssuedAt time.Time `json:"issued_at"`
+	Currency string    `json:"currency"`
+	Subtotal float64   `json:"subtotal"`
+	TaxRate  float64   `json:"tax_rate"`
+	Tax      float64   `json:"tax"`
+	Total    float64   `json:"total"`
+}
+
+// DocumentSequence is a named counter for gapless document numbers such as invoices
+type DocumentSequence struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	Value     int64  `gorm:"not null"`
+	UpdatedAt time.Time
+}
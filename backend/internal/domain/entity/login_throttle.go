@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginThrottle counts recent failed attempts for one key in one scope, e.g. the login failures
+// for an email address or the reset token guesses from an IP address
+type LoginThrottle struct {
+	ID    uint   `json:"-" gorm:"primaryKey"`
+	Scope string `json:"scope" gorm:"size:32;not null;uniqueIndex:idx_login_throttle_key"`
+	Key   string `json:"key" gorm:"size:255;not null;uniqueIndex:idx_login_throttle_key"`
+	// Failures counts the failures since the counter was last reset
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"index"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
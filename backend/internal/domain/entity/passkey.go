@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+)
+
+// Passkey is a WebAuthn credential registered by a user. CredentialID is the base64url credential
+// ID and PublicKey the COSE key the authenticator returned at registration.
+type Passkey struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	UserID       uint   `json:"-" gorm:"index;not null"`
+	Name         string `json:"name"`
+	CredentialID string `json:"-" gorm:"uniqueIndex;not null"`
+	PublicKey    []byte `json:"-" gorm:"not null"`
+	Algorithm    int64  `json:"-"`
+	// SignCount is the authenticator's signature counter, a value that stops growing means a clone
+	SignCount      int64      `json:"-"`
+	AAGUID         string     `json:"aaguid" gorm:"size:36"`
+	Transports     string     `json:"-"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backed_up"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+}
+
+// PasskeyChallenge is an outstanding WebAuthn ceremony. It is deleted when the response comes in,
+// so every challenge is answered at most once.
+type PasskeyChallenge struct {
+	ID        uint   `gorm:"primaryKey"`
+	Challenge string `gorm:"size:64;uniqueIndex;not null"`
+	// Purpose is "registration" or "authentication"
+	Purpose string `gorm:"size:16;not null"`
+	// UserID is set for registrations and for logins that named the account up front
+	UserID    *uint
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+}
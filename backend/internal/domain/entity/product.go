@@ -4,11 +4,54 @@ import (
 	"gorm.io/gorm"
 )
 
+// Product lifecycle states. Only active products are visible in the storefront.
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
 type Product struct {
 	gorm.Model
+	SKU         *string `json:"sku" gorm:"uniqueIndex:idx_products_sku,where:sku IS NOT NULL"` // Unique only when not null
+	Slug        string  `json:"slug" gorm:"index"`                                             // Uniqueness is enforced by product_slugs
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	ImageURL    string  `json:"image_url"`
 	Stock       int     `json:"stock"`
+	Status      string  `json:"status" gorm:"default:active;index"`
+	// ReorderThreshold is the stock level at or below which the product is considered low on stock
+	ReorderThreshold int `json:"reorder_threshold" gorm:"default:0"`
+
+	// CanonicalPath is the storefront path of the product, derived from the slug
+	CanonicalPath string `json:"canonical_path" gorm:"-"`
+}
+
+// ProductPath returns the canonical storefront path for a slug
+func ProductPath(slug string) string {
+	return "/products/" + slug
+}
+
+// AfterFind fills in the derived fields after loading a product
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	if p.Slug != "" {
+		p.CanonicalPath = ProductPath(p.Slug)
+	}
+	return nil
+}
+
+// IsLowStock reports whether the product is at or below its reorder threshold
+func (p Product) IsLowStock() bool {
+	return p.Stock <= p.ReorderThreshold
+}
+
+// IsAvailable reports whether the product can be shown and sold in the storefront
+func (p Product) IsAvailable() bool {
+	return p.Status == ProductStatusActive && !p.DeletedAt.Valid
+}
+
+// IsValidProductStatus reports whether s is a known lifecycle state
+func IsValidProductStatus(s string) bool {
+	return s == ProductStatusDraft || s == ProductStatusActive || s == ProductStatusArchived
 }
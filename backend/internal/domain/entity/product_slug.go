@@ -0,0 +1,12 @@
+package entity
+
+import (
+	"gorm.io/gorm"
+)
+
+// ProductSlug records every slug a product has had, so old URLs can redirect to the current one
+type ProductSlug struct {
+	gorm.Model
+	ProductID uint   `json:"product_id" gorm:"index;not null"`
+	Slug      string `json:"slug" gorm:"uniqueIndex;not null"`
+}
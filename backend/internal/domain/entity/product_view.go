@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"gorm.io/gorm"
+)
+
+// ProductView records that a product page was viewed, by a logged-in user or an anonymous visitor
+type ProductView struct {
+	gorm.Model
+	UserID    *uint  `json:"user_id" gorm:"index"`
+	SessionID string `json:"session_id" gorm:"index"`
+	ProductID uint   `json:"product_id" gorm:"index;not null"`
+}
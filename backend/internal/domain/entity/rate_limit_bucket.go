@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"time"
+)
+
+// RateLimitBucket is the token bucket of one client for one rate limit policy
+type RateLimitBucket struct {
+	Key    string  `gorm:"primaryKey;size:255"`
+	Tokens float64 `gorm:"not null"`
+	// Allowed is whether the last request that touched the bucket got a token
+	Allowed   bool
+	UpdatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+)
+
+// RecentView is an entry in a bounded "recently viewed" list. The list belongs to a user,
+// or to an anonymous session until the visitor logs in and the entries are merged.
+// Entries are hard-deleted when trimmed or pruned, so there is no soft delete.
+type RecentView struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    *uint     `json:"user_id" gorm:"uniqueIndex:idx_recent_views_user_product,where:user_id IS NOT NULL"`
+	SessionID string    `json:"-" gorm:"index;uniqueIndex:idx_recent_views_session_product,where:user_id IS NULL"`
+	ProductID uint      `json:"product_id" gorm:"not null;uniqueIndex:idx_recent_views_user_product,where:user_id IS NOT NULL;uniqueIndex:idx_recent_views_session_product,where:user_id IS NULL"`
+	ViewedAt  time.Time `json:"viewed_at" gorm:"index;not null"`
+}
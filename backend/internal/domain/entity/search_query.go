@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SearchQuery is a logged product search, used for suggestions and search analytics.
+// SearchID is handed to the client so a click on a result can be attributed to the search.
+type SearchQuery struct {
+	gorm.Model
+	SearchID    string  `json:"search_id" gorm:"uniqueIndex;size:32"`
+	Query       string  `json:"query" gorm:"not null"`
+	Normalized  string  `json:"normalized" gorm:"index;not null"`
+	Description string  `json:"description"`
+	MinPrice    float64 `json:"min_price"`
+	MaxPrice    float64 `json:"max_price"`
+	UserID      *uint   `json:"user_id" gorm:"index"`
+	SessionID   string  `json:"-"`
+	ResultCount int     `json:"result_count"`
+
+	ClickedProductID *uint      `json:"clicked_product_id"`
+	ClickedAt        *time.Time `json:"clicked_at"`
+}
+
+// NormalizeSearchQuery lower-cases the query and collapses whitespace so that equivalent
+// searches are counted together
+func NormalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
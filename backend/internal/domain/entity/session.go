@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// Session is a login on one device. Its ID is the refresh token family ID and is carried in
+// the sid claim of every access token issued for it, so revoking the session cuts off both.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:64"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	Device     string     `json:"device"`
+	IP         string     `json:"ip" gorm:"size:64"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"index"`
+	RevokedAt  *time.Time `json:"-" gorm:"index"`
+}
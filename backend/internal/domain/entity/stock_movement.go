@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+)
+
+// Stock movement types recorded in the inventory ledger
+const (
+	StockMovementRestock     = "restock"
+	StockMovementSale        = "sale"
+	StockMovementReturn      = "return"
+	StockMovementAdjustment  = "adjustment"
+	StockMovementReservation = "reservation"
+)
+
+// StockMovement is an append-only ledger entry describing a change to a product's stock.
+// Quantity is signed: positive values add stock, negative values remove it. Entries are never
+// updated or deleted, so there is no UpdatedAt or DeletedAt.
+type StockMovement struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	ProductID  uint      `json:"product_id" gorm:"index;not null"`
+	Product    Product   `json:"-" gorm:"foreignKey:ProductID"`
+	Type       string    `json:"type" gorm:"not null"`
+	Quantity   int       `json:"quantity" gorm:"not null"`
+	StockAfter int       `json:"stock_after"`
+	ActorID    *uint     `json:"actor_id"`
+	Reason     string    `json:"reason"`
+	Reference  string    `json:"reference" gorm:"index"`
+}
+
+// IsValidStockMovementType reports whether t is a known movement type
+func IsValidStockMovementType(t string) bool {
+	switch t {
+	case StockMovementRestock, StockMovementSale, StockMovementReturn,
+		StockMovementAdjustment, StockMovementReservation:
+		return true
+	}
+	return false
+}
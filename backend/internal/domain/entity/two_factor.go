@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserTwoFactor holds a user's TOTP enrolment. The row exists as soon as enrolment starts and
+// Enabled is set once the user proved the authenticator works by entering a code.
+type UserTwoFactor struct {
+	UserID uint `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	// Secret is the base32 shared secret, encrypted
+	Secret    string     `json:"-" gorm:"not null"`
+	Enabled   bool       `json:"enabled" gorm:"default:false"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	// LastUsedStep is the time step of the last accepted code, codes for it or earlier are rejected
+	LastUsedStep int64     `json:"-" gorm:"default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the authenticator is lost.
+// Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_recovery_codes_user_hash"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex:idx_recovery_codes_user_hash"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
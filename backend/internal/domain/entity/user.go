@@ -6,11 +6,12 @@ import (
 
 type User struct {
 	gorm.Model
-	GoogleID *string `json:"google_id" gorm:"uniqueIndex:idx_google_id,where:google_id IS NOT NULL"` // Unique only when not null
-	Email    string  `json:"email" gorm:"unique"`
-	Password string  `json:"password"`
-	Name     string  `json:"name"`
-	Picture  string  `json:"picture"`
-	Status   string  `json:"status" gorm:"default:pending"`
-	Role     string  `json:"role" gorm:"default:user"`
+	Email    string `json:"email" gorm:"unique"`
+	Password string `json:"-"`
+	Name     string `json:"name"`
+	Picture  string `json:"picture"`
+	Status   string `json:"status" gorm:"default:pending"`
+	Role     string `json:"role" gorm:"default:user"`
+	// Identities are the external accounts the user signs in with
+	Identities []UserIdentity `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
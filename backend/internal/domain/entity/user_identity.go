@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to an account at an external identity provider. Subject is the
+// provider's stable ID of that account; the email may change there and is kept for display.
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"-" gorm:"index;not null"`
+	Provider    string     `json:"provider" gorm:"size:64;not null;uniqueIndex:idx_user_identity_subject"`
+	Subject     string     `json:"-" gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
@@ -8,9 +8,8 @@ import (
 
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	GoogleID  string         `json:"google_id,omitempty" gorm:"uniqueIndex"`
 	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"password,omitempty"`
+	Password  string         `json:"-"`
 	Name      string         `json:"name" gorm:"not null"`
 	Status    string         `json:"status" gorm:"not null;default:pending"`
 	Picture   string         `json:"picture,omitempty"`
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+)
+
+// Analytics bucket sizes
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// IsValidBucket reports whether b is a supported analytics bucket size
+func IsValidBucket(b string) bool {
+	return b == BucketDay || b == BucketWeek || b == BucketMonth
+}
+
+// RevenueBucket is the revenue of the orders placed in one day, week or month
+type RevenueBucket struct {
+	Period  time.Time `json:"period"`
+	Revenue float64   `json:"revenue"`
+	Orders  int64     `json:"orders"`
+	// AOV is the average order value
+	AOV float64 `json:"aov"`
+}
+
+// ProductRevenue aggregates what one product sold over a period
+type ProductRevenue struct {
+	ProductID uint    `json:"product_id"`
+	Name      string  `json:"name"`
+	Units     int64   `json:"units"`
+	Orders    int64   `json:"orders"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// CustomerSplit compares customers ordering for the first time with returning customers
+type CustomerSplit struct {
+	NewCustomers       int64   `json:"new_customers"`
+	ReturningCustomers int64   `json:"returning_customers"`
+	NewRevenue         float64 `json:"new_revenue"`
+	ReturningRevenue   float64 `json:"returning_revenue"`
+}
+
+// CartConversion measures how many carts started in a period went through checkout
+type CartConversion struct {
+	CartsWithItems int64   `json:"carts_with_items"`
+	CheckedOut     int64   `json:"checked_out"`
+	Rate           float64 `json:"rate"`
+}
+
+// AnalyticsRepository reports on completed orders over [from, to)
+type AnalyticsRepository interface {
+	Revenue(from, to time.Time, bucket string) ([]RevenueBucket, error)
+	TopProductsByRevenue(from, to time.Time, limit int) ([]ProductRevenue, error)
+	TopProductsByUnits(from, to time.Time, limit int) ([]ProductRevenue, error)
+	Customers(from, to time.Time) (*CustomerSplit, error)
+	CartConversion(from, to time.Time) (*CartConversion, error)
+}
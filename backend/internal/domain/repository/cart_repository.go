@@ -2,8 +2,13 @@ package repository
 
 import (
 	"backend/internal/domain/entity"
+	"errors"
+	"time"
 )
 
+// ErrCartNotActive is returned when a cart has already been checked out
+var ErrCartNotActive = errors.New("cart is not active")
+
 // CartItem with product details
 type CartItemWithProduct struct {
 	ID       uint           `json:"id"`
@@ -11,6 +16,25 @@ type CartItemWithProduct struct {
 	Product  entity.Product `json:"product"`
 	Quantity int            `json:"quantity"`
 	Subtotal float64        `json:"subtotal"`
+	// Available is false when the product has been archived since it was added
+	Available bool `json:"available"`
+}
+
+// OrderLine is one product line of a cart or completed order
+type OrderLine struct {
+	OrderID   uint      `json:"order_id"`
+	UserID    uint      `json:"user_id"`
+	ProductID uint      `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Completed bool      `json:"completed"`
+	OrderedAt time.Time `json:"ordered_at"`
+}
+
+// ProductSales aggregates the sales of a product over a period
+type ProductSales struct {
+	ProductID uint  `json:"product_id"`
+	Units     int64 `json:"units"`
+	Orders    int64 `json:"orders"`
 }
 
 type CartRepository interface {
@@ -26,11 +50,29 @@ type CartRepository interface {
 	GetCartItemsWithProductDetails(cartID uint) ([]CartItemWithProduct, error)
 
 	// Checkout process
-	CloseCart(cartID uint) error
+	// CloseCart takes the sold stock and completes the checkout atomically, returning the assigned
+	// order number. It fails with ErrCartNotActive when the cart was already checked out.
+	CloseCart(cartID uint, shipping entity.Address, sales []entity.StockMovement) (string, error)
+	GetCompletedCartsByUserID(userID uint) ([]entity.Cart, error)
 
 	// Admin methods
 	GetAllCompletedCarts() ([]entity.Cart, error)
+	// SearchOrders returns the completed orders whose order number contains query
+	SearchOrders(query string) ([]entity.Cart, error)
+	FindOrderByNumber(number string) (*entity.Cart, error)
+	BackfillOrderNumbers() (int, error)
 	GetCartWithItems(cartID uint) (*entity.Cart, error)
 	UpdateCartStatus(cartID uint, status int) error
 	CountCompletedOrders() (int64, error)
+
+	// Sales data
+	GetCompletedOrderLines(since time.Time) ([]OrderLine, error)
+	GetCartLines(since time.Time) ([]OrderLine, error)
+	GetUserCartLines(userID uint) ([]OrderLine, error)
+	GetTopSellingProducts(since time.Time, limit int) ([]ProductSales, error)
+
+	// Exports
+	CountOrdersForExport(filter ExportFilter) (int64, error)
+	// StreamOrders walks completed orders with their user and items in ID order
+	StreamOrders(filter ExportFilter, batchSize int, fn func(orders []entity.Cart) error) error
 }
@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// ExportFilter narrows an admin export. Zero values mean "no filter".
+type ExportFilter struct {
+	// From and To bound the creation time (order time for orders) to [From, To)
+	From *time.Time
+	To   *time.Time
+	// Status is the order status number, or the user or product status
+	Status string
+	// Role only applies to users
+	Role string
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+)
+
+// ErrGoogleIDsNotMoved is returned when users.google_id still holds accounts missing from user_identities
+var ErrGoogleIDsNotMoved = errors.New("google accounts not copied to user identities")
+
+type IdentityRepository interface {
+	Create(identity *entity.UserIdentity) error
+	// FindBySubject returns nil when no user is linked to the provider account
+	FindBySubject(provider string, subject string) (*entity.UserIdentity, error)
+	ListByUser(userID uint) ([]entity.UserIdentity, error)
+	// RecordLogin stores the email the provider reported at a successful login
+	RecordLogin(id uint, email string, at time.Time) error
+	// Delete removes the user's identity and returns false when the user has no identity with the ID
+	Delete(userID uint, id uint) (bool, error)
+	// BackfillGoogleIDs copies accounts linked before identities had their own table
+	BackfillGoogleIDs() (int64, error)
+	// DropGoogleIDColumn removes the old column once BackfillGoogleIDs has copied all of it
+	DropGoogleIDColumn() error
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "backend/internal/domain/entity"
+
+type InvoiceRepository interface {
+	// FindByOrderID returns the order's invoice, or nil when none was issued yet
+	FindByOrderID(orderID uint) (*entity.Invoice, error)
+	// Issue gives the invoice the next number of its year and stores it. When the order already
+	// has an invoice, that one is returned instead, so issuing is idempotent.
+	Issue(invoice *entity.Invoice) (*entity.Invoice, error)
+}
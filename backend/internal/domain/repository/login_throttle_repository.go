@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+)
+
+// LoginThrottleRepository stores failed attempt counters. It has a Postgres implementation for
+// deployments with several instances and an in-memory one in the lockout package.
+type LoginThrottleRepository interface {
+	// Find returns nil when there were no recent failures for the key
+	Find(scope, key string) (*entity.LoginThrottle, error)
+	// RecordFailure counts a failure at the given time and returns the updated counter. The count
+	// starts over when the last failure is older than window or a lock has run out.
+	RecordFailure(scope, key string, at time.Time, window time.Duration) (*entity.LoginThrottle, error)
+	// Lock refuses further attempts for the key until the given time
+	Lock(scope, key string, until time.Time) error
+	// Reset forgets the failures and lifts any lock
+	Reset(scope, key string) error
+	// ListLocked returns the keys in the scope that are locked at the given time
+	ListLocked(scope string, now time.Time) ([]entity.LoginThrottle, error)
+	// Prune deletes counters that are not locked and last failed before the given time
+	Prune(before time.Time) (int64, error)
+}
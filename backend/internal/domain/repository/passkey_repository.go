@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+)
+
+type PasskeyRepository interface {
+	CreateChallenge(challenge *entity.PasskeyChallenge) error
+	// ConsumeChallenge deletes the challenge and returns it, or nil when there is no such challenge
+	ConsumeChallenge(challenge string, purpose string) (*entity.PasskeyChallenge, error)
+	// PruneChallenges deletes challenges that expired before the given time without an answer
+	PruneChallenges(before time.Time) (int64, error)
+
+	Create(passkey *entity.Passkey) error
+	// FindByCredentialID returns nil when no passkey has the credential ID
+	FindByCredentialID(credentialID string) (*entity.Passkey, error)
+	ListByUser(userID uint) ([]entity.Passkey, error)
+	// RecordUse stores the counter and backup state reported by a successful login
+	RecordUse(id uint, signCount int64, backupState bool, usedAt time.Time) error
+	// Delete removes the user's passkey and returns false when the user has no passkey with the ID
+	Delete(userID uint, id uint) (bool, error)
+}
@@ -0,0 +1,42 @@
+package repository
+
+// ProductImportRow is one row of a bulk product import.
+// Rows are matched by ID first, then by SKU; unmatched rows create a new product.
+// Nil fields are left unchanged when updating an existing product.
+type ProductImportRow struct {
+	Row              int      `json:"row"`
+	ID               uint     `json:"id,omitempty"`
+	SKU              string   `json:"sku,omitempty"`
+	Name             *string  `json:"name,omitempty"`
+	Description      *string  `json:"description,omitempty"`
+	Price            *float64 `json:"price,omitempty"`
+	ImageURL         *string  `json:"image_url,omitempty"`
+	Stock            *int     `json:"stock,omitempty"`
+	ReorderThreshold *int     `json:"reorder_threshold,omitempty"`
+	Status           *string  `json:"status,omitempty"`
+}
+
+// ProductImportError describes why a row was rejected
+type ProductImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProductImportResult describes what happened (or would happen on a dry run) to a row
+type ProductImportResult struct {
+	Row       int    `json:"row"`
+	Action    string `json:"action"` // "create" or "update"
+	ProductID uint   `json:"product_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+}
+
+// ProductImportReport summarises a bulk import
+type ProductImportReport struct {
+	DryRun  bool                  `json:"dry_run"`
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Results []ProductImportResult `json:"results"`
+	Errors  []ProductImportError  `json:"errors"`
+}
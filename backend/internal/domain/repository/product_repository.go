@@ -3,20 +3,62 @@ package repository
 import (
 	"backend/internal/domain/entity"
 	"backend/internal/infras/interfaces"
+	"time"
 )
 
+// ProductSuggestion is a product name offered by search autocomplete
+type ProductSuggestion struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
 type ProductRepository interface {
 	SearchProducts(product *[]entity.Product, filter interfaces.ProductFilter) error
 	GetAllProducts(products *[]entity.Product) error
 	Create(product *entity.Product) error
 	FindByID(id uint) (*entity.Product, error)
 	FindAll() ([]entity.Product, error)
+	FindActiveByIDs(ids []uint) ([]entity.Product, error)
 	Update(product *entity.Product) error
 	Delete(id uint) error
 
 	// Thêm các phương thức cho admin
-	CreateProduct(product entity.Product) (*entity.Product, error)
-	UpdateProduct(product entity.Product) (*entity.Product, error)
+	// CreateProduct records the optional initial stock movement in the same transaction
+	CreateProduct(product entity.Product, initialStock *entity.StockMovement) (*entity.Product, error)
+	// UpdateProduct sets the stock to targetStock, when given, booking the change as the adjustment
+	// in the same transaction
+	UpdateProduct(product entity.Product, targetStock *int, adjustment entity.StockMovement) (*entity.Product, error)
 	DeleteProduct(id uint) error
 	CountProducts() (int64, error)
+
+	// Lifecycle
+	FindByStatus(status string) ([]entity.Product, error)
+	FindArchived() ([]entity.Product, error)
+	UpdateStatus(id uint, status string) error
+	Restore(id uint) (*entity.Product, error)
+
+	// Inventory alerts
+	FindLowStockProducts() ([]entity.Product, error)
+	CountLowStockProducts() (int64, error)
+	CountOutOfStockProducts() (int64, error)
+
+	// SuggestProducts returns active products whose name, or a word in it, starts with prefix
+	SuggestProducts(prefix string, limit int) ([]ProductSuggestion, error)
+
+	// Slugs
+	FindBySlug(slug string) (*entity.Product, error)
+	BackfillSlugs() (int, error)
+
+	// CatalogVersion returns the latest product change time and product count, used to detect catalog changes
+	CatalogVersion() (time.Time, int64, error)
+
+	// Bulk import and export
+	FindBySKU(sku string) (*entity.Product, error)
+	ImportProducts(rows []ProductImportRow, actorID *uint, dryRun bool) (*ProductImportReport, error)
+	StreamProducts(batchSize int, fn func(products []entity.Product) error) error
+
+	// Admin report exports; unlike StreamProducts these include archived products unless filtered by status
+	CountProductsForExport(filter ExportFilter) (int64, error)
+	StreamProductsForExport(filter ExportFilter, batchSize int, fn func(products []entity.Product) error) error
 }
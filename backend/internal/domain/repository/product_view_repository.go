@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+)
+
+type ProductViewRepository interface {
+	RecordView(view *entity.ProductView) error
+	GetViewsSince(since time.Time) ([]entity.ProductView, error)
+	GetUserViews(userID uint, since time.Time) ([]entity.ProductView, error)
+	// AssignSessionToUser attributes a session's anonymous views to the user who just logged in
+	AssignSessionToUser(sessionID string, userID uint) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"time"
+)
+
+// RateLimitRepository stores token buckets. Take must refill and spend in one atomic step, so a
+// shared store such as Postgres or Redis gives every instance the same view of a client.
+type RateLimitRepository interface {
+	// Take refills the bucket for the time since its last use, up to capacity, and takes a token
+	// when there is one. It returns the tokens left and whether a token was taken. Unknown keys
+	// start with a full bucket.
+	Take(key string, capacity, ratePerSecond float64, now time.Time) (tokens float64, allowed bool, err error)
+	// Prune deletes buckets untouched since the given time, which have refilled completely
+	Prune(before time.Time) (int64, error)
+}
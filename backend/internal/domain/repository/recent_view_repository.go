@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+)
+
+// MaxRecentViews is how many products a recently-viewed list keeps
+const MaxRecentViews = 20
+
+type RecentViewRepository interface {
+	// Touch moves the product to the top of the user's list, or the session's when userID is nil
+	Touch(userID *uint, sessionID string, productID uint) error
+	List(userID *uint, sessionID string, limit int) ([]entity.RecentView, error)
+	Clear(userID *uint, sessionID string) error
+	// MergeSession moves an anonymous session's entries to the user after login
+	MergeSession(sessionID string, userID uint) error
+	// Prune deletes entries last viewed before the given time
+	Prune(before time.Time) (int64, error)
+}
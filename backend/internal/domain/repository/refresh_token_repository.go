@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenReused is returned by Rotate when the token was already exchanged or revoked
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
+// RefreshTokenRepository stores the refresh tokens of each session. Revoking them goes through
+// SessionRepository so a session and its tokens always end together.
+type RefreshTokenRepository interface {
+	Create(token *entity.RefreshToken) error
+	// FindByHash returns nil when no token has the hash
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	// Rotate marks current as used and stores next in one transaction. It fails with
+	// ErrRefreshTokenReused when current was used or revoked concurrently.
+	Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error
+	// Prune deletes tokens that expired before the given time
+	Prune(before time.Time) (int64, error)
+}
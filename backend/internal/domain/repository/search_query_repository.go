@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+)
+
+// ErrSearchNotFound is returned when a click refers to an unknown search ID
+var ErrSearchNotFound = errors.New("search not found")
+
+// QueryStat aggregates the logged searches for one normalized query
+type QueryStat struct {
+	Query        string    `json:"query"`
+	Searches     int64     `json:"searches"`
+	LastSearched time.Time `json:"last_searched"`
+}
+
+// QueryReportRow is one normalized query in the search analytics report
+type QueryReportRow struct {
+	Query              string  `json:"query"`
+	Searches           int64   `json:"searches"`
+	ZeroResultSearches int64   `json:"zero_result_searches"`
+	Clicks             int64   `json:"clicks"`
+	AvgResults         float64 `json:"avg_results"`
+	CTR                float64 `json:"ctr"`
+}
+
+// SearchTotals summarises every logged search in a date range
+type SearchTotals struct {
+	Searches           int64   `json:"searches"`
+	UniqueQueries      int64   `json:"unique_queries"`
+	ZeroResultSearches int64   `json:"zero_result_searches"`
+	Clicks             int64   `json:"clicks"`
+	CTR                float64 `json:"ctr"`
+}
+
+type SearchQueryRepository interface {
+	LogQuery(query *entity.SearchQuery) error
+	// RecordClick marks the product a customer opened from the search's results.
+	// Only the first click of a search is kept.
+	RecordClick(searchID string, productID uint) error
+	// PopularQueries returns the most frequent queries since the given time that found at least one product.
+	// Queries only a few different visitors searched for are left out.
+	PopularQueries(since time.Time, limit int) ([]QueryStat, error)
+	// ZeroResultQueries returns the most frequent queries since the given time that found nothing
+	ZeroResultQueries(since time.Time, limit int) ([]QueryStat, error)
+
+	// Analytics over [from, to)
+	TopQueries(from, to time.Time, limit int) ([]QueryReportRow, error)
+	TopZeroResultQueries(from, to time.Time, limit int) ([]QueryReportRow, error)
+	Totals(from, to time.Time) (*SearchTotals, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+)
+
+type SessionRepository interface {
+	Create(session *entity.Session) error
+	// FindByID returns nil when the session does not exist
+	FindByID(id string) (*entity.Session, error)
+	// ListActive returns the user's sessions that are not revoked, most recently seen first
+	ListActive(userID uint) ([]entity.Session, error)
+	// Touch records activity on the session
+	Touch(id string, ip string, seenAt time.Time) error
+	// Revoke ends the session and revokes its refresh tokens
+	Revoke(id string) error
+	// RevokeAll ends every session of the user except the one with exceptID, which may be empty
+	RevokeAll(userID uint, exceptID string) (int64, error)
+	// Prune deletes sessions revoked or last seen before the given time
+	Prune(before time.Time) (int64, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+)
+
+// ErrInsufficientStock is returned when a movement would take stock below zero
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrProductNotFound is returned when a movement refers to an unknown product
+var ErrProductNotFound = errors.New("product not found")
+
+// StockReconciliation compares the stored stock with the sum of the ledger
+type StockReconciliation struct {
+	ProductID   uint `json:"product_id"`
+	StoredStock int  `json:"stored_stock"`
+	LedgerStock int  `json:"ledger_stock"`
+	Difference  int  `json:"difference"`
+	InSync      bool `json:"in_sync"`
+}
+
+type StockRepository interface {
+	// RecordMovement applies a single movement to the product stock and appends it to the ledger
+	RecordMovement(movement *entity.StockMovement) error
+	// RecordMovements applies several movements atomically
+	RecordMovements(movements []entity.StockMovement) error
+
+	GetMovementsByProductID(productID uint) ([]entity.StockMovement, error)
+	Reconcile(productID uint) (*StockReconciliation, error)
+	// SyncLedger appends an adjustment so the ledger matches the stored stock, without changing the stock itself
+	SyncLedger(productID uint, actorID *uint) (*entity.StockMovement, error)
+}
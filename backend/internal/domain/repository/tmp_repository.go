@@ -2,6 +2,7 @@ package repository
 
 import (
 	"backend/internal/domain/models"
+	"time"
 )
 
 type TmpRepository interface {
@@ -9,5 +10,8 @@ type TmpRepository interface {
 	Create(tmp *models.TmpUser) error
 	FindByToken(token string) (*models.TmpUser, error)
 	Update(tmp *models.TmpUser) error
+	// Spend moves the record with the token from status to spentStatus if it was created after
+	// the given time, and returns false when it is not there, has another status or is too old
+	Spend(token string, status string, spentStatus string, createdAfter time.Time) (bool, error)
 	FindByUserID(userID uint, tmp *models.TmpUser) error
 }
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"backend/internal/domain/entity"
+)
+
+type TwoFactorRepository interface {
+	// FindByUser returns nil when the user never started enrolment
+	FindByUser(userID uint) (*entity.UserTwoFactor, error)
+	// SavePending stores a new, not yet enabled secret, replacing an unfinished enrolment
+	SavePending(userID uint, secret string) error
+	// Enable turns two-factor on, records the step of the confirming code and replaces the recovery codes
+	Enable(userID uint, step int64, recoveryHashes []string) error
+	// Disable deletes the enrolment and the recovery codes
+	Disable(userID uint) error
+	// UseStep records an accepted code's step. It returns false when the step, or a later one, was already used.
+	UseStep(userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	// UseRecoveryCode marks the code used and returns false when it does not exist or was used before
+	UseRecoveryCode(userID uint, hash string) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
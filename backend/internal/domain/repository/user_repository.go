@@ -7,9 +7,9 @@ type UserRepository interface {
 	CreateUser(user entity.User) (entity.User, error)
 	FindByEmail(email string) (*entity.User, error)
 	FindByID(id uint) (*entity.User, error)
-	FindUserByGoogleID(googleID string) (entity.User, error)
 	GetUserByID(id uint) (entity.User, error)
 	GetAllUsers() ([]entity.User, error)
+	GetUsersByRole(role string) ([]entity.User, error)
 
 	// User Updates
 	UpdateUser(user entity.User) error
@@ -18,4 +18,8 @@ type UserRepository interface {
 
 	// Statistics
 	CountUsers() (int64, error)
+
+	// Exports
+	CountUsersForExport(filter ExportFilter) (int64, error)
+	StreamUsers(filter ExportFilter, batchSize int, fn func(users []entity.User) error) error
 }
@@ -59,9 +59,14 @@ func Connect() (*gorm.DB, error) {
 		return nil, fmt.Errorf("database ping error: %w", err)
 	}
 
-	if err := db.AutoMigrate(&entity.User{}); err != nil {
+	if err := db.AutoMigrate(&entity.User{}, &entity.Product{}, &entity.Cart{}, &entity.CartItem{}, &entity.ProductSlug{}, &entity.StockMovement{}, &entity.ProductView{}, &entity.RecentView{}, &entity.SearchQuery{}, &entity.Invoice{}, &entity.DocumentSequence{}, &entity.Session{}, &entity.RefreshToken{}, &entity.UserTwoFactor{}, &entity.RecoveryCode{}, &entity.Passkey{}, &entity.PasskeyChallenge{}, &entity.LoginThrottle{}, &entity.RateLimitBucket{}, &entity.UserIdentity{}); err != nil {
 		log.Printf("Error auto migrating: %v", err)
 	}
 
+	// prefix index for search autocomplete
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_products_name_prefix ON products (LOWER(name) text_pattern_ops)").Error; err != nil {
+		log.Printf("Error creating product name index: %v", err)
+	}
+
 	return db, nil
 }
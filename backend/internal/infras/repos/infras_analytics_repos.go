@@ -0,0 +1,118 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AnalyticsRepository struct {
+	DB *gorm.DB
+}
+
+const (
+	// orderedAtExpr is when an order was placed; orders from before ordered_at existed fall back to updated_at
+	orderedAtExpr = "COALESCE(carts.ordered_at, carts.updated_at)"
+	// lineRevenueExpr is what a line earned, using today's price for orders placed before prices were captured
+	lineRevenueExpr = "cart_items.quantity * COALESCE(NULLIF(cart_items.unit_price, 0), products.price)"
+)
+
+// orderLines selects the lines of the orders placed in [from, to) and not cancelled. Archived and
+// deleted products are still joined so their sales keep counting.
+func (r *AnalyticsRepository) orderLines(from, to time.Time) *gorm.DB {
+	return r.DB.Table("cart_items").
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Joins("JOIN products ON products.id = cart_items.product_id").
+		Scopes(placedOrders).
+		Where("cart_items.deleted_at IS NULL").
+		Where(orderedAtExpr+" >= ? AND "+orderedAtExpr+" < ?", from, to)
+}
+
+func (r *AnalyticsRepository) Revenue(from, to time.Time, bucket string) ([]domainrepo.RevenueBucket, error) {
+	var buckets []domainrepo.RevenueBucket
+	err := r.orderLines(from, to).
+		Select("date_trunc(?, "+orderedAtExpr+" AT TIME ZONE 'UTC') AS period, "+
+			"SUM("+lineRevenueExpr+") AS revenue, COUNT(DISTINCT carts.id) AS orders", bucket).
+		Group("period").
+		Order("period ASC").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range buckets {
+		if buckets[i].Orders > 0 {
+			buckets[i].AOV = buckets[i].Revenue / float64(buckets[i].Orders)
+		}
+	}
+	return buckets, nil
+}
+
+func (r *AnalyticsRepository) TopProductsByRevenue(from, to time.Time, limit int) ([]domainrepo.ProductRevenue, error) {
+	return r.topProducts(from, to, limit, "revenue DESC, units DESC")
+}
+
+func (r *AnalyticsRepository) TopProductsByUnits(from, to time.Time, limit int) ([]domainrepo.ProductRevenue, error) {
+	return r.topProducts(from, to, limit, "units DESC, revenue DESC")
+}
+
+func (r *AnalyticsRepository) topProducts(from, to time.Time, limit int, order string) ([]domainrepo.ProductRevenue, error) {
+	var products []domainrepo.ProductRevenue
+	err := r.orderLines(from, to).
+		Select("products.id AS product_id, products.name, SUM(cart_items.quantity) AS units, " +
+			"COUNT(DISTINCT carts.id) AS orders, SUM(" + lineRevenueExpr + ") AS revenue").
+		Group("products.id, products.name").
+		Order(order + ", products.id ASC").
+		Limit(limit).
+		Scan(&products).Error
+	return products, err
+}
+
+// Customers splits the customers who ordered in [from, to) by whether their first ever order falls
+// in the range. Cancelled orders count for neither.
+func (r *AnalyticsRepository) Customers(from, to time.Time) (*domainrepo.CustomerSplit, error) {
+	firstOrders := r.DB.Table("carts").
+		Select("carts.user_id, MIN(" + orderedAtExpr + ") AS first_order").
+		Scopes(placedOrders).
+		Group("carts.user_id")
+
+	spend := r.orderLines(from, to).
+		Select("carts.user_id, SUM(" + lineRevenueExpr + ") AS revenue").
+		Group("carts.user_id")
+
+	var split domainrepo.CustomerSplit
+	err := r.DB.Table("(?) AS spend", spend).
+		Joins("JOIN (?) AS firsts ON firsts.user_id = spend.user_id", firstOrders).
+		Select(`COUNT(*) FILTER (WHERE firsts.first_order >= ?) AS new_customers,
+			COUNT(*) FILTER (WHERE firsts.first_order < ?) AS returning_customers,
+			COALESCE(SUM(spend.revenue) FILTER (WHERE firsts.first_order >= ?), 0) AS new_revenue,
+			COALESCE(SUM(spend.revenue) FILTER (WHERE firsts.first_order < ?), 0) AS returning_revenue`,
+			from, from, from, from).
+		Scan(&split).Error
+	if err != nil {
+		return nil, err
+	}
+	return &split, nil
+}
+
+// CartConversion counts the carts created in [from, to) that received items, and how many of them were
+// checked out and not cancelled since
+func (r *AnalyticsRepository) CartConversion(from, to time.Time) (*domainrepo.CartConversion, error) {
+	var conversion domainrepo.CartConversion
+	err := r.DB.Table("carts").
+		Select("COUNT(*) AS carts_with_items, COUNT(*) FILTER (WHERE carts.active = false AND carts.status <> ?) AS checked_out",
+			entity.OrderStatusCancelled).
+		Where("carts.deleted_at IS NULL AND carts.created_at >= ? AND carts.created_at < ?", from, to).
+		Where("EXISTS (SELECT 1 FROM cart_items WHERE cart_items.cart_id = carts.id AND cart_items.deleted_at IS NULL)").
+		Scan(&conversion).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if conversion.CartsWithItems > 0 {
+		conversion.Rate = float64(conversion.CheckedOut) / float64(conversion.CartsWithItems)
+	}
+	return &conversion, nil
+}
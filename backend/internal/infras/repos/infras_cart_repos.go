@@ -4,13 +4,19 @@ import (
 	"backend/internal/domain/entity"
 	domainrepo "backend/internal/domain/repository"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CartRepository struct {
 	DB *gorm.DB
+	// OrderPrefix starts every order number; "HS" when empty
+	OrderPrefix string
 }
 
 // FindActiveCartByUserID finds the active cart for a user
@@ -91,20 +97,90 @@ func (r *CartRepository) RemoveItem(cartItemID uint) error {
 	return r.DB.Delete(&entity.CartItem{}, cartItemID).Error
 }
 
+// unscopedProduct preloads products even when they were soft-deleted, so carts and orders keep rendering
+func unscopedProduct(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
 // GetCartItems gets all items in a cart
 func (r *CartRepository) GetCartItems(cartID uint) ([]entity.CartItem, error) {
 	var items []entity.CartItem
-	err := r.DB.Preload("Product").Where("cart_id = ?", cartID).Find(&items).Error
+	err := r.DB.Preload("Product", unscopedProduct).Where("cart_id = ?", cartID).Find(&items).Error
 	return items, err
 }
 
-// CloseCart marks a cart as inactive (completed order)
-func (r *CartRepository) CloseCart(cartID uint) error {
-	return r.DB.Model(&entity.Cart{}).Where("id = ?", cartID).Updates(map[string]interface{}{
-		"active":     false,
-		"status":     2, // 2 = completed
-		"updated_at": time.Now(),
-	}).Error
+// CloseCart completes the checkout in one transaction: the cart is locked while still active, the
+// sale movements take the stock and the cart becomes an order with a new order number. A second
+// submit of the same checkout finds the cart closed and changes nothing.
+func (r *CartRepository) CloseCart(cartID uint, shipping entity.Address, sales []entity.StockMovement) (string, error) {
+	var number string
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var cart entity.Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND active = true", cartID).
+			First(&cart).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domainrepo.ErrCartNotActive
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range sales {
+			if err := applyMovement(tx, &sales[i]); err != nil {
+				return err
+			}
+		}
+
+		// Capture the prices paid so later price changes don't rewrite order history
+		err = tx.Exec(`UPDATE cart_items SET unit_price = products.price
+			FROM products
+			WHERE products.id = cart_items.product_id AND cart_items.cart_id = ? AND cart_items.deleted_at IS NULL`, cartID).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		number, err = r.nextOrderNumber(tx, now)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.Cart{}).Where("id = ? AND active = true", cartID).Updates(map[string]interface{}{
+			"order_number":         number,
+			"active":               false,
+			"status":               entity.OrderStatusConfirmed,
+			"ordered_at":           now,
+			"updated_at":           now,
+			"shipping_full_name":   shipping.FullName,
+			"shipping_email":       shipping.Email,
+			"shipping_address":     shipping.Address,
+			"shipping_city":        shipping.City,
+			"shipping_country":     shipping.Country,
+			"shipping_postal_code": shipping.PostalCode,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainrepo.ErrCartNotActive
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return number, nil
+}
+
+// GetCompletedCartsByUserID returns a user's orders with their items, newest first
+func (r *CartRepository) GetCompletedCartsByUserID(userID uint) ([]entity.Cart, error) {
+	var carts []entity.Cart
+	err := r.DB.Where("user_id = ? AND active = false", userID).
+		Preload("CartItems.Product", unscopedProduct).
+		Order("COALESCE(ordered_at, updated_at) DESC").
+		Find(&carts).Error
+	return carts, err
 }
 
 // GetCartItemsWithProductDetails gets cart items with product details
@@ -113,22 +189,27 @@ func (r *CartRepository) GetCartItemsWithProductDetails(cartID uint) ([]domainre
 	var result []domainrepo.CartItemWithProduct
 
 	// Get cart items with their associated products
-	if err := r.DB.Where("cart_id = ?", cartID).Preload("Product").Find(&cartItems).Error; err != nil {
+	if err := r.DB.Where("cart_id = ?", cartID).Preload("Product", unscopedProduct).Find(&cartItems).Error; err != nil {
 		return nil, err
 	}
 
 	// Map to the domain repository type
 	for _, item := range cartItems {
-		// Calculate subtotal
-		subtotal := float64(item.Quantity) * item.Product.Price
+		// Archived products stay in the cart but no longer count towards the total
+		available := item.Product.IsAvailable()
+		subtotal := 0.0
+		if available {
+			subtotal = float64(item.Quantity) * item.Product.Price
+		}
 
 		// Create cart item with product
 		itemWithProduct := domainrepo.CartItemWithProduct{
-			ID:       item.ID,
-			CartID:   item.CartID,
-			Product:  item.Product,
-			Quantity: item.Quantity,
-			Subtotal: subtotal,
+			ID:        item.ID,
+			CartID:    item.CartID,
+			Product:   item.Product,
+			Quantity:  item.Quantity,
+			Subtotal:  subtotal,
+			Available: available,
 		}
 
 		result = append(result, itemWithProduct)
@@ -147,7 +228,7 @@ func (r *CartRepository) GetAllCompletedCarts() ([]entity.Cart, error) {
 // GetCartWithItems trả về thông tin chi tiết của một đơn hàng bao gồm các sản phẩm
 func (r *CartRepository) GetCartWithItems(cartID uint) (*entity.Cart, error) {
 	var cart entity.Cart
-	err := r.DB.Preload("User").Preload("CartItems.Product").First(&cart, cartID).Error
+	err := r.DB.Preload("User").Preload("CartItems.Product", unscopedProduct).First(&cart, cartID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -165,3 +246,192 @@ func (r *CartRepository) CountCompletedOrders() (int64, error) {
 	result := r.DB.Model(&entity.Cart{}).Where("active = false").Count(&count)
 	return count, result.Error
 }
+
+// placedOrders limits a query joining carts to checked-out orders that were not cancelled since.
+// Sales figures, bestsellers and analytics all use it so they count the same orders.
+func placedOrders(db *gorm.DB) *gorm.DB {
+	return db.Where("carts.active = false AND carts.deleted_at IS NULL AND carts.status <> ?", entity.OrderStatusCancelled)
+}
+
+// GetCompletedOrderLines returns the product lines of every order completed since the given time.
+// Cancelled orders are left out.
+func (r *CartRepository) GetCompletedOrderLines(since time.Time) ([]domainrepo.OrderLine, error) {
+	var lines []domainrepo.OrderLine
+	err := r.DB.Table("cart_items").
+		Select("carts.id AS order_id, carts.user_id, cart_items.product_id, cart_items.quantity, true AS completed, "+orderedAtExpr+" AS ordered_at").
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Scopes(placedOrders).
+		Where("cart_items.deleted_at IS NULL").
+		Where(orderedAtExpr+" >= ?", since).
+		Order("carts.id ASC").
+		Scan(&lines).Error
+	return lines, err
+}
+
+// GetTopSellingProducts returns the products with the most units sold since the given time, not
+// counting cancelled orders
+func (r *CartRepository) GetTopSellingProducts(since time.Time, limit int) ([]domainrepo.ProductSales, error) {
+	var sales []domainrepo.ProductSales
+	err := r.DB.Table("cart_items").
+		Select("cart_items.product_id, SUM(cart_items.quantity) AS units, COUNT(DISTINCT carts.id) AS orders").
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Scopes(placedOrders).
+		Where("cart_items.deleted_at IS NULL").
+		Where(orderedAtExpr+" >= ?", since).
+		Group("cart_items.product_id").
+		Order("units DESC, orders DESC, cart_items.product_id ASC").
+		Limit(limit).
+		Scan(&sales).Error
+	return sales, err
+}
+
+// cartLineColumns selects cart items as order lines. For open carts, ordered_at is when the item was added.
+const cartLineColumns = "carts.id AS order_id, carts.user_id, cart_items.product_id, cart_items.quantity, " +
+	"NOT carts.active AS completed, " +
+	"CASE WHEN carts.active THEN cart_items.created_at ELSE " + orderedAtExpr + " END AS ordered_at"
+
+// GetCartLines returns the items added to any cart, open or completed, since the given time
+func (r *CartRepository) GetCartLines(since time.Time) ([]domainrepo.OrderLine, error) {
+	var lines []domainrepo.OrderLine
+	err := r.DB.Table("cart_items").
+		Select(cartLineColumns).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("carts.deleted_at IS NULL AND cart_items.deleted_at IS NULL").
+		Where("cart_items.created_at >= ?", since).
+		Order("cart_items.created_at ASC").
+		Scan(&lines).Error
+	return lines, err
+}
+
+// GetUserCartLines returns every item the user has added to a cart, open or completed
+func (r *CartRepository) GetUserCartLines(userID uint) ([]domainrepo.OrderLine, error) {
+	var lines []domainrepo.OrderLine
+	err := r.DB.Table("cart_items").
+		Select(cartLineColumns).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("carts.user_id = ? AND carts.deleted_at IS NULL AND cart_items.deleted_at IS NULL", userID).
+		Order("cart_items.created_at ASC").
+		Scan(&lines).Error
+	return lines, err
+}
+
+func orderExportScope(filter domainrepo.ExportFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("active = false")
+		if filter.From != nil {
+			db = db.Where("COALESCE(ordered_at, updated_at) >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("COALESCE(ordered_at, updated_at) < ?", *filter.To)
+		}
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		return db
+	}
+}
+
+// CountOrdersForExport counts the completed orders matching an export filter
+func (r *CartRepository) CountOrdersForExport(filter domainrepo.ExportFilter) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entity.Cart{}).Scopes(orderExportScope(filter)).Count(&count).Error
+	return count, err
+}
+
+// StreamOrders walks the completed orders matching an export filter in ID order, handing each batch to fn
+func (r *CartRepository) StreamOrders(filter domainrepo.ExportFilter, batchSize int, fn func(orders []entity.Cart) error) error {
+	var batch []entity.Cart
+	return r.DB.Scopes(orderExportScope(filter)).
+		Preload("User").
+		Preload("CartItems.Product", unscopedProduct).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// orderSequences remembers which yearly order number sequences are known to exist
+var orderSequences sync.Map
+
+// nextOrderNumber draws the next number of the year's Postgres sequence, e.g. HS-2026-000123.
+// Sequences never block concurrent checkouts; a rolled back checkout leaves a gap, which is fine
+// for order numbers (invoice numbers are the gapless ones).
+func (r *CartRepository) nextOrderNumber(tx *gorm.DB, at time.Time) (string, error) {
+	year := at.Year()
+	sequence := fmt.Sprintf("order_number_%d_seq", year)
+
+	if _, ok := orderSequences.Load(sequence); !ok {
+		// Run outside tx: a failed CREATE would abort the checkout transaction.
+		// Two instances creating the sequence at once can clash, so retry once.
+		err := r.DB.Exec("CREATE SEQUENCE IF NOT EXISTS " + sequence).Error
+		if err != nil {
+			err = r.DB.Exec("CREATE SEQUENCE IF NOT EXISTS " + sequence).Error
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create order number sequence: %w", err)
+		}
+		orderSequences.Store(sequence, true)
+	}
+
+	var seq int64
+	if err := tx.Raw("SELECT nextval(?::regclass)", sequence).Scan(&seq).Error; err != nil {
+		return "", err
+	}
+
+	prefix := r.OrderPrefix
+	if prefix == "" {
+		prefix = "HS"
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, seq), nil
+}
+
+// SearchOrders returns the completed orders whose order number contains query, newest first
+func (r *CartRepository) SearchOrders(query string) ([]entity.Cart, error) {
+	var carts []entity.Cart
+	pattern := "%" + escapeLike(strings.ToUpper(strings.TrimSpace(query))) + "%"
+	err := r.DB.Where("active = false AND UPPER(order_number) LIKE ?", pattern).
+		Preload("User").
+		Order("COALESCE(ordered_at, updated_at) DESC").
+		Find(&carts).Error
+	return carts, err
+}
+
+// FindOrderByNumber returns the completed order with the given number, with its user and items
+func (r *CartRepository) FindOrderByNumber(number string) (*entity.Cart, error) {
+	var cart entity.Cart
+	err := r.DB.Where("active = false AND UPPER(order_number) = ?", strings.ToUpper(strings.TrimSpace(number))).
+		Preload("User").
+		Preload("CartItems.Product", unscopedProduct).
+		First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// BackfillOrderNumbers numbers the orders placed before order numbers existed, oldest first
+func (r *CartRepository) BackfillOrderNumbers() (int, error) {
+	var orders []entity.Cart
+	err := r.DB.Where("active = false AND order_number IS NULL").
+		Order("COALESCE(ordered_at, updated_at) ASC, id ASC").
+		Find(&orders).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, order := range orders {
+		orderedAt := order.UpdatedAt
+		if order.OrderedAt != nil {
+			orderedAt = *order.OrderedAt
+		}
+
+		number, err := r.nextOrderNumber(r.DB, orderedAt)
+		if err != nil {
+			return i, err
+		}
+		if err := r.DB.Model(&entity.Cart{}).Where("id = ?", order.ID).UpdateColumn("order_number", number).Error; err != nil {
+			return i, fmt.Errorf("order %d: %w", order.ID, err)
+		}
+	}
+
+	return len(orders), nil
+}
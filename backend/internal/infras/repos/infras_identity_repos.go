@@ -0,0 +1,117 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdentityRepository struct {
+	DB *gorm.DB
+}
+
+func (r *IdentityRepository) Create(identity *entity.UserIdentity) error {
+	return r.DB.Create(identity).Error
+}
+
+func (r *IdentityRepository) FindBySubject(provider string, subject string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	err := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *IdentityRepository) ListByUser(userID uint) ([]entity.UserIdentity, error) {
+	var identities []entity.UserIdentity
+	err := r.DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+func (r *IdentityRepository) RecordLogin(id uint, email string, at time.Time) error {
+	return r.DB.Model(&entity.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
+
+// Delete also clears a deleted Google account from users.google_id while that column is kept,
+// so neither the backfill nor an older release links it again
+func (r *IdentityRepository) Delete(userID uint, id uint) (bool, error) {
+	var deleted bool
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var identity entity.UserIdentity
+		err := tx.Where("id = ? AND user_id = ?", id, userID).First(&identity).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&identity).Error; err != nil {
+			return err
+		}
+		deleted = true
+
+		if identity.Provider != "google" || !tx.Migrator().HasColumn("users", "google_id") {
+			return nil
+		}
+		return tx.Exec("UPDATE users SET google_id = NULL WHERE id = ? AND google_id = ?", userID, identity.Subject).Error
+	})
+	return deleted, err
+}
+
+// BackfillGoogleIDs copies the Google accounts linked through the old users.google_id column into
+// user_identities. The column is left in place; DropGoogleIDColumn removes it once every account
+// has been copied.
+func (r *IdentityRepository) BackfillGoogleIDs() (int64, error) {
+	if !r.DB.Migrator().HasColumn("users", "google_id") {
+		return 0, nil
+	}
+
+	result := r.DB.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+		SELECT id, 'google', google_id, email, NOW() FROM users
+		WHERE google_id IS NOT NULL AND google_id <> '' AND deleted_at IS NULL
+		ON CONFLICT (provider, subject) DO NOTHING
+	`)
+	return result.RowsAffected, result.Error
+}
+
+// unmovedGoogleIDs matches the users whose google_id is not linked to the same user in user_identities
+const unmovedGoogleIDs = `FROM users u
+	WHERE u.google_id IS NOT NULL AND u.google_id <> '' AND u.deleted_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM user_identities i
+		WHERE i.provider = 'google' AND i.subject = u.google_id AND i.user_id = u.id
+	)`
+
+// DropGoogleIDColumn drops users.google_id after checking every Google account in it was copied
+// to user_identities. The column and its index stay when any was not.
+func (r *IdentityRepository) DropGoogleIDColumn() error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if !tx.Migrator().HasColumn("users", "google_id") {
+			return nil
+		}
+
+		// keep new Google IDs from being written between the check and the drop
+		if err := tx.Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var unmoved int64
+		if err := tx.Raw("SELECT COUNT(*) " + unmovedGoogleIDs).Scan(&unmoved).Error; err != nil {
+			return err
+		}
+		if unmoved > 0 {
+			return fmt.Errorf("%w: %d users", domainrepo.ErrGoogleIDsNotMoved, unmoved)
+		}
+		return tx.Exec("ALTER TABLE users DROP COLUMN google_id").Error
+	})
+}
@@ -0,0 +1,70 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository struct {
+	DB *gorm.DB
+}
+
+func (r *InvoiceRepository) FindByOrderID(orderID uint) (*entity.Invoice, error) {
+	var invoice entity.Invoice
+	err := r.DB.Where("order_id = ?", orderID).First(&invoice).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// Issue numbers the invoice INV-<year>-<sequence>. The counter row stays locked until the
+// transaction commits and its increment is rolled back with a failed insert, so numbers have no gaps.
+func (r *InvoiceRepository) Issue(invoice *entity.Invoice) (*entity.Invoice, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialise concurrent issues for the same order
+		var order entity.Cart
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&order, invoice.OrderID).Error; err != nil {
+			return err
+		}
+
+		var existing entity.Invoice
+		err := tx.Where("order_id = ?", invoice.OrderID).First(&existing).Error
+		if err == nil {
+			*invoice = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		year := invoice.IssuedAt.Year()
+		seq, err := nextSequence(tx, fmt.Sprintf("invoice-%d", year))
+		if err != nil {
+			return err
+		}
+		invoice.Number = fmt.Sprintf("INV-%d-%06d", year, seq)
+
+		return tx.Create(invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// nextSequence increments the named counter inside tx and returns its new value
+func nextSequence(tx *gorm.DB, name string) (int64, error) {
+	var value int64
+	err := tx.Raw(`INSERT INTO document_sequences (name, value, updated_at) VALUES (?, 1, NOW())
+		ON CONFLICT (name) DO UPDATE SET value = document_sequences.value + 1, updated_at = NOW()
+		RETURNING value`, name).Scan(&value).Error
+	return value, err
+}
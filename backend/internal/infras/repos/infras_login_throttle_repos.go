@@ -0,0 +1,74 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginThrottleRepository struct {
+	DB *gorm.DB
+}
+
+func (r *LoginThrottleRepository) Find(scope, key string) (*entity.LoginThrottle, error) {
+	var throttle entity.LoginThrottle
+	err := r.DB.Where("scope = ? AND key = ?", scope, key).First(&throttle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// RecordFailure upserts the counter in one statement so concurrent failures are all counted
+func (r *LoginThrottleRepository) RecordFailure(scope, key string, at time.Time, window time.Duration) (*entity.LoginThrottle, error) {
+	var throttle entity.LoginThrottle
+	err := r.DB.Raw(`
+		INSERT INTO login_throttles (scope, key, failures, last_failure_at, created_at)
+		VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < ? OR login_throttles.locked_until <= ? THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			locked_until = CASE
+				WHEN login_throttles.locked_until <= ? THEN NULL
+				ELSE login_throttles.locked_until
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING *`,
+		scope, key, at, at, at.Add(-window), at, at,
+	).Scan(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *LoginThrottleRepository) Lock(scope, key string, until time.Time) error {
+	return r.DB.Model(&entity.LoginThrottle{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Update("locked_until", until).Error
+}
+
+func (r *LoginThrottleRepository) Reset(scope, key string) error {
+	return r.DB.Where("scope = ? AND key = ?", scope, key).Delete(&entity.LoginThrottle{}).Error
+}
+
+func (r *LoginThrottleRepository) ListLocked(scope string, now time.Time) ([]entity.LoginThrottle, error) {
+	var throttles []entity.LoginThrottle
+	err := r.DB.Where("scope = ? AND locked_until > ?", scope, now).
+		Order("locked_until DESC").
+		Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *LoginThrottleRepository) Prune(before time.Time) (int64, error) {
+	result := r.DB.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&entity.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
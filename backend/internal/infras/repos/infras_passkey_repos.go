@@ -0,0 +1,71 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasskeyRepository struct {
+	DB *gorm.DB
+}
+
+func (r *PasskeyRepository) CreateChallenge(challenge *entity.PasskeyChallenge) error {
+	return r.DB.Create(challenge).Error
+}
+
+func (r *PasskeyRepository) ConsumeChallenge(challenge string, purpose string) (*entity.PasskeyChallenge, error) {
+	var consumed []entity.PasskeyChallenge
+	// deleting with RETURNING makes the lookup and the removal one step, a replayed response finds nothing
+	result := r.DB.Clauses(clause.Returning{}).
+		Where("challenge = ? AND purpose = ?", challenge, purpose).
+		Delete(&consumed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(consumed) == 0 {
+		return nil, nil
+	}
+	return &consumed[0], nil
+}
+
+func (r *PasskeyRepository) PruneChallenges(before time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", before).Delete(&entity.PasskeyChallenge{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *PasskeyRepository) Create(passkey *entity.Passkey) error {
+	return r.DB.Create(passkey).Error
+}
+
+func (r *PasskeyRepository) FindByCredentialID(credentialID string) (*entity.Passkey, error) {
+	var passkey entity.Passkey
+	err := r.DB.Where("credential_id = ?", credentialID).First(&passkey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+func (r *PasskeyRepository) ListByUser(userID uint) ([]entity.Passkey, error) {
+	var passkeys []entity.Passkey
+	err := r.DB.Where("user_id = ?", userID).Order("created_at").Find(&passkeys).Error
+	return passkeys, err
+}
+
+func (r *PasskeyRepository) RecordUse(id uint, signCount int64, backupState bool, usedAt time.Time) error {
+	return r.DB.Model(&entity.Passkey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"sign_count": signCount, "backup_state": backupState, "last_used_at": usedAt}).Error
+}
+
+func (r *PasskeyRepository) Delete(userID uint, id uint) (bool, error) {
+	result := r.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&entity.Passkey{})
+	return result.RowsAffected == 1, result.Error
+}
@@ -2,19 +2,26 @@ package repos
 
 import (
 	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
 	"backend/internal/infras/interfaces"
+	"backend/pkg/utils"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository struct {
 	DB *gorm.DB
 }
 
+// GetAllProducts returns the products visible in the storefront
 func (r *ProductRepository) GetAllProducts(products *[]entity.Product) error {
-	return r.DB.Find(products).Error
+	return r.DB.Where("status = ?", entity.ProductStatusActive).Find(products).Error
 }
 
 func (r *ProductRepository) Create(product *entity.Product) error {
@@ -25,7 +32,7 @@ func (r *ProductRepository) Create(product *entity.Product) error {
 }
 
 func (r *ProductRepository) SearchProducts(products *[]entity.Product, filter interfaces.ProductFilter) error {
-	query := r.DB
+	query := r.DB.Where("status = ?", entity.ProductStatusActive)
 
 	if filter.Name != "" {
 		query = query.Where("name LIKE ?", "%"+filter.Name+"%")
@@ -46,6 +53,27 @@ func (r *ProductRepository) SearchProducts(products *[]entity.Product, filter in
 	return query.Find(products).Error
 }
 
+// SuggestProducts returns active products whose name, or a word in it, starts with prefix.
+// Names that start with the prefix are listed first.
+func (r *ProductRepository) SuggestProducts(prefix string, limit int) ([]domainrepo.ProductSuggestion, error) {
+	pattern := escapeLike(strings.ToLower(prefix))
+
+	var suggestions []domainrepo.ProductSuggestion
+	err := r.DB.Model(&entity.Product{}).
+		Select("id, name, slug").
+		Where("status = ?", entity.ProductStatusActive).
+		Where("LOWER(name) LIKE ? OR LOWER(name) LIKE ?", pattern+"%", "% "+pattern+"%").
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "LOWER(name) LIKE ? DESC, name ASC", Vars: []interface{}{pattern + "%"}}}).
+		Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *ProductRepository) FindByID(id uint) (*entity.Product, error) {
 	var product entity.Product
 	result := r.DB.First(&product, id)
@@ -66,6 +94,31 @@ func (r *ProductRepository) FindAll() ([]entity.Product, error) {
 	return products, err
 }
 
+// FindActiveByIDs returns the active products among ids, in the same order as ids
+func (r *ProductRepository) FindActiveByIDs(ids []uint) ([]entity.Product, error) {
+	if len(ids) == 0 {
+		return []entity.Product{}, nil
+	}
+
+	var found []entity.Product
+	if err := r.DB.Where("id IN ? AND status = ?", ids, entity.ProductStatusActive).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]entity.Product, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	products := make([]entity.Product, 0, len(found))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
 func (r *ProductRepository) Update(product *entity.Product) error {
 	product.UpdatedAt = time.Now()
 	return r.DB.Save(product).Error
@@ -76,11 +129,28 @@ func (r *ProductRepository) Delete(id uint) error {
 }
 
 // CreateProduct tạo mới một sản phẩm
-func (r *ProductRepository) CreateProduct(product entity.Product) (*entity.Product, error) {
+// The initial stock, if any, is booked through the ledger together with the product.
+func (r *ProductRepository) CreateProduct(product entity.Product, initialStock *entity.StockMovement) (*entity.Product, error) {
 	product.CreatedAt = time.Now()
 	product.UpdatedAt = time.Now()
 
-	err := r.DB.Create(&product).Error
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if err := assignSlug(tx, &product); err != nil {
+			return err
+		}
+		if initialStock == nil {
+			return nil
+		}
+		initialStock.ProductID = product.ID
+		if err := applyMovement(tx, initialStock); err != nil {
+			return err
+		}
+		product.Stock = initialStock.StockAfter
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -88,11 +158,30 @@ func (r *ProductRepository) CreateProduct(product entity.Product) (*entity.Produ
 	return &product, nil
 }
 
-// UpdateProduct cập nhật thông tin sản phẩm
-func (r *ProductRepository) UpdateProduct(product entity.Product) (*entity.Product, error) {
+// UpdateProduct cập nhật thông tin sản phẩm.
+// Stock is not saved; when a target stock is given, the difference to the locked row is booked
+// as the adjustment through the stock ledger in the same transaction.
+func (r *ProductRepository) UpdateProduct(product entity.Product, targetStock *int, adjustment entity.StockMovement) (*entity.Product, error) {
 	product.UpdatedAt = time.Now()
 
-	err := r.DB.Save(&product).Error
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("stock", "slug").Save(&product).Error; err != nil {
+			return err
+		}
+		if err := assignSlug(tx, &product); err != nil {
+			return err
+		}
+		if targetStock == nil {
+			return nil
+		}
+		adjustment.ProductID = product.ID
+		stock, err := applyStockTarget(tx, *targetStock, &adjustment)
+		if err != nil {
+			return err
+		}
+		product.Stock = stock
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -100,9 +189,9 @@ func (r *ProductRepository) UpdateProduct(product entity.Product) (*entity.Produ
 	return &product, nil
 }
 
-// DeleteProduct xóa sản phẩm theo ID
+// DeleteProduct archives the product instead of deleting it, so carts and past orders keep their references
 func (r *ProductRepository) DeleteProduct(id uint) error {
-	return r.DB.Delete(&entity.Product{}, id).Error
+	return r.UpdateStatus(id, entity.ProductStatusArchived)
 }
 
 // CountProducts đếm tổng số sản phẩm
@@ -111,3 +200,398 @@ func (r *ProductRepository) CountProducts() (int64, error) {
 	result := r.DB.Model(&entity.Product{}).Count(&count)
 	return count, result.Error
 }
+
+// ----- Lifecycle -----
+
+// FindByStatus returns all products in the given lifecycle state
+func (r *ProductRepository) FindByStatus(status string) ([]entity.Product, error) {
+	var products []entity.Product
+	err := r.DB.Where("status = ?", status).Order("updated_at DESC").Find(&products).Error
+	return products, err
+}
+
+// FindArchived returns archived products, including those removed with the old soft delete
+func (r *ProductRepository) FindArchived() ([]entity.Product, error) {
+	var products []entity.Product
+	err := r.DB.Unscoped().
+		Where("status = ? OR deleted_at IS NOT NULL", entity.ProductStatusArchived).
+		Order("updated_at DESC").
+		Find(&products).Error
+	return products, err
+}
+
+// UpdateStatus moves a product to another lifecycle state
+func (r *ProductRepository) UpdateStatus(id uint, status string) error {
+	result := r.DB.Model(&entity.Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("product not found")
+	}
+	return nil
+}
+
+// Restore makes an archived or soft-deleted product active again
+func (r *ProductRepository) Restore(id uint) (*entity.Product, error) {
+	result := r.DB.Unscoped().Model(&entity.Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     entity.ProductStatusActive,
+		"deleted_at": nil,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("product not found")
+	}
+	return r.FindByID(id)
+}
+
+// FindLowStockProducts returns products at or below their reorder threshold, emptiest first
+func (r *ProductRepository) FindLowStockProducts() ([]entity.Product, error) {
+	var products []entity.Product
+	err := r.DB.Where("status = ? AND stock <= reorder_threshold", entity.ProductStatusActive).
+		Order("stock ASC, id ASC").Find(&products).Error
+	return products, err
+}
+
+// CountLowStockProducts counts products at or below their reorder threshold
+func (r *ProductRepository) CountLowStockProducts() (int64, error) {
+	var count int64
+	result := r.DB.Model(&entity.Product{}).Where("status = ? AND stock <= reorder_threshold", entity.ProductStatusActive).Count(&count)
+	return count, result.Error
+}
+
+// CountOutOfStockProducts counts products with no stock left
+func (r *ProductRepository) CountOutOfStockProducts() (int64, error) {
+	var count int64
+	result := r.DB.Model(&entity.Product{}).Where("status = ? AND stock <= 0", entity.ProductStatusActive).Count(&count)
+	return count, result.Error
+}
+
+// FindBySKU finds a product by its SKU
+func (r *ProductRepository) FindBySKU(sku string) (*entity.Product, error) {
+	var product entity.Product
+	if err := r.DB.Where("sku = ?", sku).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// errImportRollback aborts the import transaction on dry runs and validation failures
+var errImportRollback = errors.New("import rolled back")
+
+// ImportProducts upserts the given rows in a single transaction.
+// On a dry run, or when any row is rejected, the transaction is rolled back and only the report is returned.
+// Stock changes are written to the stock ledger as adjustments.
+func (r *ProductRepository) ImportProducts(rows []domainrepo.ProductImportRow, actorID *uint, dryRun bool) (*domainrepo.ProductImportReport, error) {
+	report := &domainrepo.ProductImportReport{
+		DryRun:  dryRun,
+		Total:   len(rows),
+		Results: []domainrepo.ProductImportResult{},
+		Errors:  []domainrepo.ProductImportError{},
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			rowErr, err := importRow(tx, row, actorID, report)
+			if err != nil {
+				return err
+			}
+			if rowErr != nil {
+				report.Errors = append(report.Errors, *rowErr)
+			}
+		}
+
+		if dryRun || len(report.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// importRow creates or updates the product for one row. Rejected rows return an import error
+// before anything is written, so the transaction stays usable.
+func importRow(tx *gorm.DB, row domainrepo.ProductImportRow, actorID *uint, report *domainrepo.ProductImportReport) (*domainrepo.ProductImportError, error) {
+	var product entity.Product
+	exists := false
+
+	switch {
+	case row.ID != 0:
+		err := tx.First(&product, row.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &domainrepo.ProductImportError{Row: row.Row, Field: "id", Message: "product not found"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		exists = true
+	case row.SKU != "":
+		err := tx.Where("sku = ?", row.SKU).First(&product).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		exists = err == nil
+	}
+
+	// The SKU must not belong to another product, including deleted ones
+	if row.SKU != "" {
+		var owner entity.Product
+		err := tx.Unscoped().Where("sku = ?", row.SKU).First(&owner).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil && (!exists || owner.ID != product.ID) {
+			return &domainrepo.ProductImportError{Row: row.Row, Field: "sku", Message: "SKU already used by another product"}, nil
+		}
+	}
+
+	if !exists && (row.Name == nil || *row.Name == "") {
+		return &domainrepo.ProductImportError{Row: row.Row, Field: "name", Message: "name is required for new products"}, nil
+	}
+	if !exists && row.Price == nil {
+		return &domainrepo.ProductImportError{Row: row.Row, Field: "price", Message: "price is required for new products"}, nil
+	}
+
+	if row.SKU != "" {
+		sku := row.SKU
+		product.SKU = &sku
+	}
+	if row.Name != nil {
+		product.Name = *row.Name
+	}
+	if row.Description != nil {
+		product.Description = *row.Description
+	}
+	if row.Price != nil {
+		product.Price = *row.Price
+	}
+	if row.ImageURL != nil {
+		product.ImageURL = *row.ImageURL
+	}
+	if row.ReorderThreshold != nil {
+		product.ReorderThreshold = *row.ReorderThreshold
+	}
+	if row.Status != nil {
+		product.Status = *row.Status
+	}
+
+	now := time.Now()
+	product.UpdatedAt = now
+	action := "update"
+	if exists {
+		if err := tx.Omit("stock", "slug").Save(&product).Error; err != nil {
+			return nil, err
+		}
+		report.Updated++
+	} else {
+		action = "create"
+		product.CreatedAt = now
+		product.Stock = 0
+		if err := tx.Create(&product).Error; err != nil {
+			return nil, err
+		}
+		report.Created++
+	}
+
+	if err := assignSlug(tx, &product); err != nil {
+		return nil, err
+	}
+
+	if row.Stock != nil && *row.Stock != product.Stock {
+		reason := "bulk import"
+		movementType := entity.StockMovementAdjustment
+		if !exists {
+			reason = "initial stock"
+			movementType = entity.StockMovementRestock
+		}
+		movement := &entity.StockMovement{
+			ProductID: product.ID,
+			Type:      movementType,
+			Quantity:  *row.Stock - product.Stock,
+			ActorID:   actorID,
+			Reason:    reason,
+		}
+		if err := applyMovement(tx, movement); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Results = append(report.Results, domainrepo.ProductImportResult{
+		Row:       row.Row,
+		Action:    action,
+		ProductID: product.ID,
+		SKU:       row.SKU,
+	})
+
+	return nil, nil
+}
+
+// StreamProducts walks the catalog in ID order, handing each batch to fn
+func (r *ProductRepository) StreamProducts(batchSize int, fn func(products []entity.Product) error) error {
+	var batch []entity.Product
+	return r.DB.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func productExportScope(filter domainrepo.ExportFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.From != nil {
+			db = db.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("created_at < ?", *filter.To)
+		}
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		return db
+	}
+}
+
+// CountProductsForExport counts the products matching an export filter
+func (r *ProductRepository) CountProductsForExport(filter domainrepo.ExportFilter) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entity.Product{}).Scopes(productExportScope(filter)).Count(&count).Error
+	return count, err
+}
+
+// StreamProductsForExport walks the products matching an export filter in ID order, handing each batch to fn
+func (r *ProductRepository) StreamProductsForExport(filter domainrepo.ExportFilter, batchSize int, fn func(products []entity.Product) error) error {
+	var batch []entity.Product
+	return r.DB.Scopes(productExportScope(filter)).FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// CatalogVersion returns the latest product change time and product count.
+// Archiving, stock movements and edits all bump updated_at, so any catalog change alters the result.
+func (r *ProductRepository) CatalogVersion() (time.Time, int64, error) {
+	var version struct {
+		LastUpdated *time.Time
+		Total       int64
+	}
+	err := r.DB.Unscoped().Model(&entity.Product{}).
+		Select("MAX(updated_at) AS last_updated, COUNT(*) AS total").
+		Scan(&version).Error
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	if version.LastUpdated == nil {
+		return time.Time{}, version.Total, nil
+	}
+	return *version.LastUpdated, version.Total, nil
+}
+
+// ----- Slugs -----
+
+// FindBySlug finds a product by its current or any previous slug.
+// Callers compare the result's Slug with the requested one to decide whether to redirect.
+func (r *ProductRepository) FindBySlug(slug string) (*entity.Product, error) {
+	var history entity.ProductSlug
+	if err := r.DB.Where("slug = ?", slug).First(&history).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var product entity.Product
+	if err := r.DB.First(&product, history.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// BackfillSlugs assigns slugs to products created before slugs existed, and new ones to products
+// whose slug is a number or a static route and so can't be reached
+func (r *ProductRepository) BackfillSlugs() (int, error) {
+	var products []entity.Product
+	err := r.DB.Unscoped().
+		Where("slug = '' OR slug IS NULL OR slug ~ '^[0-9]+$' OR slug IN ?", utils.ReservedSlugs()).
+		Order("id ASC").
+		Find(&products).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range products {
+		err := r.DB.Transaction(func(tx *gorm.DB) error {
+			return assignSlug(tx, &products[i])
+		})
+		if err != nil {
+			return i, fmt.Errorf("product %d: %w", products[i].ID, err)
+		}
+	}
+
+	return len(products), nil
+}
+
+// assignSlug gives the product a unique slug derived from its name.
+// The current slug is kept while it still matches the name; otherwise a new one is
+// picked and the old one stays in product_slugs so it can redirect.
+func assignSlug(tx *gorm.DB, product *entity.Product) error {
+	base := utils.Slugify(product.Name)
+	if slugMatchesBase(product.Slug, base) {
+		return nil
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		var owner entity.ProductSlug
+		err := tx.Unscoped().Where("slug = ?", slug).First(&owner).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Create(&entity.ProductSlug{ProductID: product.ID, Slug: slug}).Error; err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// A slug the product used before can be taken back
+		if owner.ProductID == product.ID {
+			break
+		}
+		slug = base + "-" + strconv.Itoa(n)
+	}
+
+	if err := tx.Model(&entity.Product{}).Where("id = ?", product.ID).Update("slug", slug).Error; err != nil {
+		return err
+	}
+
+	product.Slug = slug
+	product.CanonicalPath = entity.ProductPath(slug)
+	return nil
+}
+
+// slugMatchesBase reports whether slug is base or base with a numeric de-duplication suffix
+func slugMatchesBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
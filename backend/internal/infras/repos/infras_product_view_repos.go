@@ -0,0 +1,41 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProductViewRepository struct {
+	DB *gorm.DB
+}
+
+// RecordView stores a product view
+func (r *ProductViewRepository) RecordView(view *entity.ProductView) error {
+	return r.DB.Create(view).Error
+}
+
+// GetViewsSince returns every view recorded since the given time, oldest first
+func (r *ProductViewRepository) GetViewsSince(since time.Time) ([]entity.ProductView, error) {
+	var views []entity.ProductView
+	err := r.DB.Where("created_at >= ?", since).Order("created_at ASC").Find(&views).Error
+	return views, err
+}
+
+// GetUserViews returns the views of one user since the given time, oldest first
+func (r *ProductViewRepository) GetUserViews(userID uint, since time.Time) ([]entity.ProductView, error) {
+	var views []entity.ProductView
+	err := r.DB.Where("user_id = ? AND created_at >= ?", userID, since).Order("created_at ASC").Find(&views).Error
+	return views, err
+}
+
+// AssignSessionToUser attributes a session's anonymous views to the user who just logged in
+func (r *ProductViewRepository) AssignSessionToUser(sessionID string, userID uint) error {
+	if sessionID == "" {
+		return nil
+	}
+	return r.DB.Model(&entity.ProductView{}).
+		Where("session_id = ? AND user_id IS NULL", sessionID).
+		Update("user_id", userID).Error
+}
@@ -0,0 +1,41 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RateLimitRepository struct {
+	DB *gorm.DB
+}
+
+// Take runs the refill and the spend in a single upsert. The SET expressions all see the row as it
+// was, so the refilled amount is computed once per branch.
+func (r *RateLimitRepository) Take(key string, capacity, ratePerSecond float64, now time.Time) (float64, bool, error) {
+	var bucket entity.RateLimitBucket
+	err := r.DB.Raw(`
+		INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+		VALUES (@key, @capacity - 1, true, @now)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST(@capacity, rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - rate_limit_buckets.updated_at))::float8) * @rate) >= 1
+				THEN LEAST(@capacity, rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - rate_limit_buckets.updated_at))::float8) * @rate) - 1
+				ELSE LEAST(@capacity, rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - rate_limit_buckets.updated_at))::float8) * @rate)
+			END,
+			allowed = LEAST(@capacity, rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - rate_limit_buckets.updated_at))::float8) * @rate) >= 1,
+			updated_at = @now
+		RETURNING key, tokens, allowed, updated_at`,
+		map[string]interface{}{"key": key, "capacity": capacity, "rate": ratePerSecond, "now": now},
+	).Scan(&bucket).Error
+	if err != nil {
+		return 0, false, err
+	}
+	return bucket.Tokens, bucket.Allowed, nil
+}
+
+func (r *RateLimitRepository) Prune(before time.Time) (int64, error) {
+	result := r.DB.Where("updated_at < ?", before).Delete(&entity.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecentViewRepository struct {
+	DB *gorm.DB
+}
+
+// ownerScope restricts a query to the user's entries, or the anonymous session's when userID is nil
+func ownerScope(userID *uint, sessionID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userID != nil {
+			return db.Where("user_id = ?", *userID)
+		}
+		return db.Where("user_id IS NULL AND session_id = ?", sessionID)
+	}
+}
+
+// Touch moves the product to the top of the list and trims the list to MaxRecentViews entries
+func (r *RecentViewRepository) Touch(userID *uint, sessionID string, productID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&entity.RecentView{}).Scopes(ownerScope(userID, sessionID)).
+			Where("product_id = ?", productID).
+			Update("viewed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			entry := &entity.RecentView{
+				UserID:    userID,
+				SessionID: sessionID,
+				ProductID: productID,
+				ViewedAt:  now,
+			}
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+		}
+
+		return trimRecentViews(tx, userID, sessionID)
+	})
+}
+
+// trimRecentViews deletes everything past the newest MaxRecentViews entries of an owner
+func trimRecentViews(tx *gorm.DB, userID *uint, sessionID string) error {
+	keep := tx.Model(&entity.RecentView{}).Scopes(ownerScope(userID, sessionID)).
+		Select("id").
+		Order("viewed_at DESC").
+		Limit(domainrepo.MaxRecentViews)
+
+	return tx.Scopes(ownerScope(userID, sessionID)).
+		Where("id NOT IN (?)", keep).
+		Delete(&entity.RecentView{}).Error
+}
+
+// List returns the owner's entries, most recent first
+func (r *RecentViewRepository) List(userID *uint, sessionID string, limit int) ([]entity.RecentView, error) {
+	if limit <= 0 || limit > domainrepo.MaxRecentViews {
+		limit = domainrepo.MaxRecentViews
+	}
+
+	var entries []entity.RecentView
+	err := r.DB.Scopes(ownerScope(userID, sessionID)).
+		Order("viewed_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// Clear empties the owner's list
+func (r *RecentViewRepository) Clear(userID *uint, sessionID string) error {
+	return r.DB.Scopes(ownerScope(userID, sessionID)).Delete(&entity.RecentView{}).Error
+}
+
+// MergeSession moves an anonymous session's entries to the user. When both lists contain
+// the same product, the most recent view wins.
+func (r *RecentViewRepository) MergeSession(sessionID string, userID uint) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var entries []entity.RecentView
+		if err := tx.Scopes(ownerScope(nil, sessionID)).Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		for _, entry := range entries {
+			result := tx.Model(&entity.RecentView{}).
+				Where("user_id = ? AND product_id = ? AND viewed_at < ?", userID, entry.ProductID, entry.ViewedAt).
+				Update("viewed_at", entry.ViewedAt)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			var exists int64
+			if err := tx.Model(&entity.RecentView{}).
+				Where("user_id = ? AND product_id = ?", userID, entry.ProductID).
+				Count(&exists).Error; err != nil {
+				return err
+			}
+			if exists == 0 {
+				uid := userID
+				merged := &entity.RecentView{UserID: &uid, SessionID: sessionID, ProductID: entry.ProductID, ViewedAt: entry.ViewedAt}
+				if err := tx.Create(merged).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Scopes(ownerScope(nil, sessionID)).Delete(&entity.RecentView{}).Error; err != nil {
+			return err
+		}
+
+		uid := userID
+		return trimRecentViews(tx, &uid, "")
+	})
+}
+
+// Prune deletes entries last viewed before the given time
+func (r *RecentViewRepository) Prune(before time.Time) (int64, error) {
+	result := r.DB.Where("viewed_at < ?", before).Delete(&entity.RecentView{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	DB *gorm.DB
+}
+
+func (r *RefreshTokenRepository) Create(token *entity.RefreshToken) error {
+	return r.DB.Create(token).Error
+}
+
+func (r *RefreshTokenRepository) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := r.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *RefreshTokenRepository) Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// the used_at check makes the update the arbiter when two requests race with the same token
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainrepo.ErrRefreshTokenReused
+		}
+		return tx.Create(next).Error
+	})
+}
+
+func (r *RefreshTokenRepository) Prune(before time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", before).Delete(&entity.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
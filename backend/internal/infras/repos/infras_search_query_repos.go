@@ -0,0 +1,117 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SearchQueryRepository struct {
+	DB *gorm.DB
+}
+
+func (r *SearchQueryRepository) LogQuery(query *entity.SearchQuery) error {
+	return r.DB.Create(query).Error
+}
+
+// RecordClick sets the click in one statement; a search that already has a click keeps it
+func (r *SearchQueryRepository) RecordClick(searchID string, productID uint) error {
+	result := r.DB.Model(&entity.SearchQuery{}).
+		Where("search_id = ?", searchID).
+		Updates(map[string]interface{}{
+			"clicked_product_id": gorm.Expr("COALESCE(clicked_product_id, ?)", productID),
+			"clicked_at":         gorm.Expr("COALESCE(clicked_at, ?)", time.Now()),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainrepo.ErrSearchNotFound
+	}
+	return nil
+}
+
+// minPopularSearchers is how many different visitors must have searched a query before it is
+// suggested to others, so one visitor can't put a query into everyone's autocomplete
+const minPopularSearchers = 3
+
+// searchersExpr counts the different visitors behind a query's searches
+const searchersExpr = "COUNT(DISTINCT COALESCE(user_id::text, NULLIF(session_id, '')))"
+
+func (r *SearchQueryRepository) PopularQueries(since time.Time, limit int) ([]domainrepo.QueryStat, error) {
+	return r.queryStats(since, limit, "result_count > 0", minPopularSearchers)
+}
+
+func (r *SearchQueryRepository) ZeroResultQueries(since time.Time, limit int) ([]domainrepo.QueryStat, error) {
+	return r.queryStats(since, limit, "result_count = 0", 0)
+}
+
+func (r *SearchQueryRepository) queryStats(since time.Time, limit int, condition string, minSearchers int) ([]domainrepo.QueryStat, error) {
+	var stats []domainrepo.QueryStat
+	db := r.DB.Model(&entity.SearchQuery{}).
+		Select("normalized AS query, COUNT(*) AS searches, MAX(created_at) AS last_searched").
+		Where("created_at >= ? AND normalized <> ''", since).
+		Where(condition).
+		Group("normalized")
+	if minSearchers > 0 {
+		db = db.Having(searchersExpr+" >= ?", minSearchers)
+	}
+	err := db.Order("searches DESC, last_searched DESC").
+		Limit(limit).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// reportColumns aggregates one normalized query; CTR is the share of searches with a click
+const reportColumns = `normalized AS query,
+	COUNT(*) AS searches,
+	COUNT(*) FILTER (WHERE result_count = 0) AS zero_result_searches,
+	COUNT(clicked_product_id) AS clicks,
+	COALESCE(AVG(result_count), 0) AS avg_results,
+	COUNT(clicked_product_id)::float / COUNT(*) AS ctr`
+
+func (r *SearchQueryRepository) TopQueries(from, to time.Time, limit int) ([]domainrepo.QueryReportRow, error) {
+	var rows []domainrepo.QueryReportRow
+	err := r.DB.Model(&entity.SearchQuery{}).
+		Select(reportColumns).
+		Where("created_at >= ? AND created_at < ? AND normalized <> ''", from, to).
+		Group("normalized").
+		Order("searches DESC, query ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *SearchQueryRepository) TopZeroResultQueries(from, to time.Time, limit int) ([]domainrepo.QueryReportRow, error) {
+	var rows []domainrepo.QueryReportRow
+	err := r.DB.Model(&entity.SearchQuery{}).
+		Select(reportColumns).
+		Where("created_at >= ? AND created_at < ? AND normalized <> ''", from, to).
+		Group("normalized").
+		Having("COUNT(*) FILTER (WHERE result_count = 0) > 0").
+		Order("zero_result_searches DESC, query ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *SearchQueryRepository) Totals(from, to time.Time) (*domainrepo.SearchTotals, error) {
+	var totals domainrepo.SearchTotals
+	err := r.DB.Model(&entity.SearchQuery{}).
+		Select(`COUNT(*) AS searches,
+			COUNT(DISTINCT NULLIF(normalized, '')) AS unique_queries,
+			COUNT(*) FILTER (WHERE result_count = 0) AS zero_result_searches,
+			COUNT(clicked_product_id) AS clicks`).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if totals.Searches > 0 {
+		totals.CTR = float64(totals.Clicks) / float64(totals.Searches)
+	}
+	return &totals, nil
+}
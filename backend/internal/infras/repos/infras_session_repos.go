@@ -0,0 +1,89 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	DB *gorm.DB
+}
+
+func (r *SessionRepository) Create(session *entity.Session) error {
+	return r.DB.Create(session).Error
+}
+
+func (r *SessionRepository) FindByID(id string) (*entity.Session, error) {
+	var session entity.Session
+	err := r.DB.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) ListActive(userID uint) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) Touch(id string, ip string, seenAt time.Time) error {
+	return r.DB.Model(&entity.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "ip": ip}).Error
+}
+
+func (r *SessionRepository) Revoke(id string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, []string{id})
+	})
+}
+
+func (r *SessionRepository) RevokeAll(userID uint, exceptID string) (int64, error) {
+	var revoked int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&entity.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+		if exceptID != "" {
+			query = query.Where("id <> ?", exceptID)
+		}
+
+		var ids []string
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		revoked = int64(len(ids))
+		return revokeSessions(tx, ids)
+	})
+	return revoked, err
+}
+
+// revokeSessions marks the sessions revoked together with their refresh tokens
+func revokeSessions(tx *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+
+	if err := tx.Model(&entity.Session{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&entity.RefreshToken{}).
+		Where("family_id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", now).Error
+}
+
+func (r *SessionRepository) Prune(before time.Time) (int64, error) {
+	result := r.DB.Where("revoked_at < ? OR last_seen_at < ?", before, before).Delete(&entity.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,159 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	domainrepo "backend/internal/domain/repository"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockRepository struct {
+	DB *gorm.DB
+}
+
+// RecordMovement applies a single movement to the product stock and appends it to the ledger
+func (r *StockRepository) RecordMovement(movement *entity.StockMovement) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return applyMovement(tx, movement)
+	})
+}
+
+// RecordMovements applies several movements in one transaction; either all succeed or none do
+func (r *StockRepository) RecordMovements(movements []entity.StockMovement) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range movements {
+			if err := applyMovement(tx, &movements[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// applyMovement locks the product row, updates its stock and writes the ledger entry
+func applyMovement(tx *gorm.DB, movement *entity.StockMovement) error {
+	var product entity.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, movement.ProductID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domainrepo.ErrProductNotFound
+		}
+		return err
+	}
+
+	newStock := product.Stock + movement.Quantity
+	if newStock < 0 {
+		return domainrepo.ErrInsufficientStock
+	}
+
+	if err := tx.Model(&entity.Product{}).Where("id = ?", product.ID).Update("stock", newStock).Error; err != nil {
+		return err
+	}
+
+	movement.StockAfter = newStock
+	return tx.Create(movement).Error
+}
+
+// applyStockTarget locks the product row and books the difference between its stock and target as
+// the movement, so changes made since the caller read the product are not overwritten. Nothing is
+// booked when the stock already is the target.
+func applyStockTarget(tx *gorm.DB, target int, movement *entity.StockMovement) (int, error) {
+	var product entity.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, movement.ProductID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, domainrepo.ErrProductNotFound
+		}
+		return 0, err
+	}
+	if product.Stock == target {
+		return product.Stock, nil
+	}
+
+	movement.Quantity = target - product.Stock
+	if err := applyMovement(tx, movement); err != nil {
+		return 0, err
+	}
+	return movement.StockAfter, nil
+}
+
+// GetMovementsByProductID returns the ledger for a product, newest first
+func (r *StockRepository) GetMovementsByProductID(productID uint) ([]entity.StockMovement, error) {
+	var movements []entity.StockMovement
+	err := r.DB.Where("product_id = ?", productID).Order("created_at DESC, id DESC").Find(&movements).Error
+	return movements, err
+}
+
+// Reconcile compares the product's stored stock with the sum of its ledger entries
+func (r *StockRepository) Reconcile(productID uint) (*domainrepo.StockReconciliation, error) {
+	var product entity.Product
+	if err := r.DB.First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainrepo.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	var ledgerStock int
+	err := r.DB.Model(&entity.StockMovement{}).
+		Where("product_id = ?", productID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&ledgerStock).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainrepo.StockReconciliation{
+		ProductID:   productID,
+		StoredStock: product.Stock,
+		LedgerStock: ledgerStock,
+		Difference:  product.Stock - ledgerStock,
+		InSync:      product.Stock == ledgerStock,
+	}, nil
+}
+
+// SyncLedger appends an adjustment so the ledger matches the stored stock.
+// Used to seed the ledger for products that existed before it was introduced.
+func (r *StockRepository) SyncLedger(productID uint, actorID *uint) (*entity.StockMovement, error) {
+	var movement *entity.StockMovement
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var product entity.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domainrepo.ErrProductNotFound
+			}
+			return err
+		}
+
+		var ledgerStock int
+		err := tx.Model(&entity.StockMovement{}).
+			Where("product_id = ?", productID).
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&ledgerStock).Error
+		if err != nil {
+			return err
+		}
+
+		if ledgerStock == product.Stock {
+			return nil
+		}
+
+		movement = &entity.StockMovement{
+			ProductID:  productID,
+			Type:       entity.StockMovementAdjustment,
+			Quantity:   product.Stock - ledgerStock,
+			StockAfter: product.Stock,
+			ActorID:    actorID,
+			Reason:     "ledger reconciliation",
+		}
+		return tx.Create(movement).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
@@ -0,0 +1,103 @@
+package repos
+
+import (
+	"backend/internal/domain/entity"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepository struct {
+	DB *gorm.DB
+}
+
+func (r *TwoFactorRepository) FindByUser(userID uint) (*entity.UserTwoFactor, error) {
+	var tf entity.UserTwoFactor
+	err := r.DB.Where("user_id = ?", userID).First(&tf).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (r *TwoFactorRepository) SavePending(userID uint, secret string) error {
+	tf := entity.UserTwoFactor{UserID: userID, Secret: secret}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "enabled": false, "enabled_at": nil, "last_used_step": 0, "updated_at": time.Now()}),
+		// an enabled enrolment is only replaced after disabling it
+		Where: clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Table: "user_two_factors", Name: "enabled"}, Value: false}}},
+	}).Create(&tf).Error
+}
+
+func (r *TwoFactorRepository) Enable(userID uint, step int64, recoveryHashes []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&entity.UserTwoFactor{}).
+			Where("user_id = ? AND enabled = ?", userID, false).
+			Updates(map[string]interface{}{"enabled": true, "enabled_at": now, "last_used_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryHashes)
+	})
+}
+
+func (r *TwoFactorRepository) Disable(userID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&entity.UserTwoFactor{}).Error
+	})
+}
+
+func (r *TwoFactorRepository) UseStep(userID uint, step int64) (bool, error) {
+	result := r.DB.Model(&entity.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, hashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	codes := make([]entity.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = entity.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userID uint, hash string) (bool, error) {
+	result := r.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -26,18 +26,6 @@ func (r *PostgresUserRepository) GetAllUsers() ([]entity.User, error) {
 	return users, err
 }
 
-func (r *PostgresUserRepository) FindUserByGoogleID(googleID string) (entity.User, error) {
-	var user entity.User
-	err := r.DB.Where("google_id = ?", googleID).First(&user).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return entity.User{}, errors.New("user not found")
-		}
-		return entity.User{}, err
-	}
-	return user, nil
-}
-
 func (r *PostgresUserRepository) CreateUser(user entity.User) (entity.User, error) {
 	now := time.Now()
 
@@ -2,6 +2,7 @@ package repos
 
 import (
 	"backend/internal/domain/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -43,6 +44,14 @@ func (r *TmpRepository) Update(tmp *models.TmpUser) error {
 	return r.DB.Save(tmp).Error
 }
 
+// Spend changes the status in a single update, so of two requests spending the same token only one succeeds
+func (r *TmpRepository) Spend(token string, status string, spentStatus string, createdAfter time.Time) (bool, error) {
+	result := r.DB.Model(&models.TmpUser{}).
+		Where("token_remain = ? AND status = ? AND created_at > ?", token, status, createdAfter).
+		Update("status", spentStatus)
+	return result.RowsAffected == 1, result.Error
+}
+
 // FindByUserID finds a temporary user record by user ID
 func (r *TmpRepository) FindByUserID(userID uint, tmp *models.TmpUser) error {
 	err := r.DB.Where("user_id = ?", userID).First(tmp).Error
@@ -3,6 +3,7 @@ package repos
 import (
 	"backend/internal/domain/entity"
 	"backend/internal/domain/models"
+	domainrepo "backend/internal/domain/repository"
 	"errors"
 	"fmt"
 	"time"
@@ -81,20 +82,6 @@ func (r *UserRepository) FindByEmail(email string) (*entity.User, error) {
 	return &user, nil
 }
 
-// FindUserByGoogleID finds a user by Google ID
-func (r *UserRepository) FindUserByGoogleID(googleID string) (entity.User, error) {
-	var user entity.User
-	err := r.DB.Where("google_id = ?", googleID).First(&user).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return entity.User{}, errors.New("user not found")
-		}
-		return entity.User{}, err
-	}
-
-	return user, nil
-}
-
 // GetAllUsers gets all users
 func (r *UserRepository) GetAllUsers() ([]entity.User, error) {
 	var users []entity.User
@@ -102,6 +89,13 @@ func (r *UserRepository) GetAllUsers() ([]entity.User, error) {
 	return users, err
 }
 
+// GetUsersByRole gets all users with the given role
+func (r *UserRepository) GetUsersByRole(role string) ([]entity.User, error) {
+	var users []entity.User
+	err := r.DB.Where("role = ?", role).Find(&users).Error
+	return users, err
+}
+
 // ----- User Updates -----
 
 // UpdateUser updates a user entity
@@ -132,3 +126,38 @@ func (r *UserRepository) CountUsers() (int64, error) {
 	result := r.DB.Model(&entity.User{}).Count(&count)
 	return count, result.Error
 }
+
+// ----- Exports -----
+
+func userExportScope(filter domainrepo.ExportFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.From != nil {
+			db = db.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("created_at < ?", *filter.To)
+		}
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		if filter.Role != "" {
+			db = db.Where("role = ?", filter.Role)
+		}
+		return db
+	}
+}
+
+// CountUsersForExport counts the users matching an export filter
+func (r *UserRepository) CountUsersForExport(filter domainrepo.ExportFilter) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entity.User{}).Scopes(userExportScope(filter)).Count(&count).Error
+	return count, err
+}
+
+// StreamUsers walks the users matching an export filter in ID order, handing each batch to fn
+func (r *UserRepository) StreamUsers(filter domainrepo.ExportFilter, batchSize int, fn func(users []entity.User) error) error {
+	var batch []entity.User
+	return r.DB.Scopes(userExportScope(filter)).Preload("Identities").FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// SendEmail sends an HTML email using the SMTP settings from the environment
+func SendEmail(to []string, subject, htmlBody string) error {
+	return SendEmailWithAttachments(to, subject, htmlBody, nil)
+}
+
+// SendEmailWithAttachments sends an HTML email with files attached
+func SendEmailWithAttachments(to []string, subject, htmlBody string, attachments []Attachment) error {
+	from := os.Getenv("EMAIL")
+	password := os.Getenv("EMAIL_PASSWORD")
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+
+	if from == "" || password == "" || host == "" || port == "" {
+		return fmt.Errorf("missing email configuration environment variables")
+	}
+
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	message, err := buildMessage(from, to, subject, htmlBody, attachments)
+	if err != nil {
+		return err
+	}
+
+	// the server certificate is verified against the system roots, the login is sent over this connection
+	tlsConfig := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	conn, err := tls.Dial("tcp", host+":"+port, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("SMTP connection error: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("SMTP client error: %w", err)
+	}
+	defer client.Close()
+
+	if err = client.Auth(smtp.PlainAuth("", from, password, host)); err != nil {
+		return fmt.Errorf("SMTP authentication error: %w", err)
+	}
+
+	if err = client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP sender error: %w", err)
+	}
+
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return fmt.Errorf("SMTP recipient error: %w", err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP data error: %w", err)
+	}
+
+	if _, err = writer.Write([]byte(message)); err != nil {
+		return fmt.Errorf("SMTP write error: %w", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("SMTP close error: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage assembles the email: plain HTML, or multipart/mixed when there are attachments
+func buildMessage(from string, to []string, subject, htmlBody string, attachments []Attachment) (string, error) {
+	headers := "From: Hidden Score <" + from + ">\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n"
+
+	if len(attachments) == 0 {
+		return headers + "Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n" + htmlBody, nil
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate MIME boundary: %w", err)
+	}
+	boundary := "hs-" + hex.EncodeToString(b)
+
+	var sb strings.Builder
+	sb.WriteString(headers)
+	sb.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n\r\n")
+
+	sb.WriteString("--" + boundary + "\r\n")
+	sb.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n")
+	sb.WriteString(htmlBody + "\r\n")
+
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		filename := mime.QEncoding.Encode("utf-8", a.Filename)
+
+		sb.WriteString("--" + boundary + "\r\n")
+		sb.WriteString("Content-Type: " + contentType + "; name=\"" + filename + "\"\r\n")
+		sb.WriteString("Content-Disposition: attachment; filename=\"" + filename + "\"\r\n")
+		sb.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			sb.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		sb.WriteString(encoded + "\r\n")
+	}
+	sb.WriteString("--" + boundary + "--\r\n")
+
+	return sb.String(), nil
+}
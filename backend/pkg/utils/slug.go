@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSlugLength = 80
+
+// reservedSlugs are the static routes under /products that a product slug would shadow
+var reservedSlugs = map[string]bool{
+	"top":     true,
+	"suggest": true,
+	"detail":  true,
+	"search":  true,
+}
+
+// Slugify turns a product name into a lowercase, hyphen-separated ASCII slug.
+// Accents are stripped (e.g. "Bàn phím cơ" becomes "ban-phim-co"). Slugs that would be read as a
+// product ID or a static route get a "-product" suffix (e.g. "1984" becomes "1984-product").
+func Slugify(name string) string {
+	slug := fold(name)
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.Trim(slug, "-")
+	}
+
+	if slug == "" {
+		return "product"
+	}
+	if isNumeric(slug) || reservedSlugs[slug] {
+		return slug + "-product"
+	}
+	return slug
+}
+
+// FoldWords splits text into lowercase ASCII words with the accents stripped, as Slugify does
+func FoldWords(text string) []string {
+	folded := fold(text)
+	if folded == "" {
+		return nil
+	}
+	return strings.Split(folded, "-")
+}
+
+// fold strips accents, lowercases and joins the runs of letters and digits with hyphens
+func fold(text string) string {
+	var b strings.Builder
+	lastHyphen := true
+
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining accent left over from decomposition
+			continue
+		case r == 'đ' || r == 'Đ':
+			r = 'd'
+		}
+
+		r = unicode.ToLower(r)
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastHyphen = false
+		} else if !lastHyphen {
+			b.WriteByte('-')
+			lastHyphen = true
+		}
+	}
+
+	return strings.Trim(b.String(), "-")
+}
+
+// ReservedSlugs lists the words a slug can't be on its own
+func ReservedSlugs() []string {
+	words := make([]string, 0, len(reservedSlugs))
+	for word := range reservedSlugs {
+		words = append(words, word)
+	}
+	return words
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
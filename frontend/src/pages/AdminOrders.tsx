@@ -16,6 +16,7 @@ interface CartItem {
 
 interface Order {
   id: number;
+  order_number?: string | null;
   user_id: number;
   user: {
     id: number;
@@ -164,7 +165,7 @@ const AdminOrders: React.FC = () => {
                 <tbody>
                   {orders.map((order) => (
                     <tr key={order.id} className={`hover:bg-gray-50 ${selectedOrder?.id === order.id ? 'bg-blue-50' : ''}`}>
-                      <td className="py-3 px-4 border-b">{order.id}</td>
+                      <td className="py-3 px-4 border-b">{order.order_number || order.id}</td>
                       <td className="py-3 px-4 border-b">
                         <div className="font-medium">{order.user.name}</div>
                         <div className="text-sm text-gray-500">{order.user.email}</div>
@@ -202,7 +203,7 @@ const AdminOrders: React.FC = () => {
             <div className="lg:col-span-2">
               <div className="bg-gray-50 p-6 rounded-lg border border-gray-200">
                 <div className="flex justify-between items-center mb-4">
-                  <h2 className="text-xl font-semibold">Chi tiết đơn hàng #{selectedOrder.id}</h2>
+                  <h2 className="text-xl font-semibold">Chi tiết đơn hàng {selectedOrder.order_number || `#${selectedOrder.id}`}</h2>
                   <button 
                     onClick={() => setSelectedOrder(null)}
                     className="text-gray-500 hover:text-gray-700"
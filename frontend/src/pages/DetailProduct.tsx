@@ -85,14 +85,10 @@ const ProductDetail = () => {
     useEffect(() => {
         const fetchRelatedProducts = async () => {
             try {
-                // Try to get all products
-                const response = await api.get('/products');
-                if (response.data && Array.isArray(response.data)) {
-                    // Filter out the current product and limit to 4 items
-                    const filtered = response.data
-                        .filter((p: Product) => p.id !== parseInt(productId || '0'))
-                        .slice(0, 4);
-                    setRelatedProducts(filtered);
+                // Related products are computed by the backend recommendation service
+                const response = await api.get(`/products/${productId}/related?limit=4`);
+                if (response.data && Array.isArray(response.data.products)) {
+                    setRelatedProducts(response.data.products);
                 }
             } catch (err) {
                 console.error('Error fetching related products:', err);
@@ -12,8 +12,8 @@ const GoogleCallback = () => {
     const handleCallback = async () => {
       try {
         const searchParams = new URLSearchParams(location.search);
-        const token = searchParams.get('token');
-        const code = searchParams.get('code');
+        const loginCode = searchParams.get('login_code');
+        const redirectTo = searchParams.get('redirect_to') || '/';
         const errorParam = searchParams.get('error');
         
         if (errorParam) {
@@ -21,47 +21,31 @@ const GoogleCallback = () => {
           navigate(`/login?error=${encodeURIComponent(errorParam)}`);
           return;
         }
-        
-        if (token) {
-          // Save token immediately
-          localStorage.setItem('auth_token', token);
-          
-          // Clear any previous errors
-          localStorage.removeItem('auth_error');
-          
-          // Navigate immediately without delay
-          navigate('/');
+
+        if (!loginCode) {
+          setError('Missing authentication data');
+          navigate('/login?error=missing_auth_data');
           return;
         }
-        
-        // If we have a code but no token, we need to exchange it
-        if (code) {
-          try {
-            const response = await fetch(`${import.meta.env.VITE_API_URL || ''}/auth/google/callback?code=${code}`, {
-              method: 'GET',
-              credentials: 'include',
-            });
-            
-            if (!response.ok) {
-              throw new Error('Failed to exchange code for token');
-            }
-            
-            const data = await response.json();
-            
-            if (data.token) {
-              localStorage.setItem('auth_token', data.token);
-              navigate('/');
-            } else {
-              throw new Error('No token received from server');
-            }
-          } catch (exchangeError: any) {
-            console.error('Error exchanging code for token:', exchangeError);
-            setError(`Authentication error: ${exchangeError.message}`);
-            navigate('/login?error=auth_failed');
-          }
+
+        // The backend redirects with a one-time code instead of a token, so no credentials end up
+        // in the browser history or in server logs
+        const { authAPI } = await import('../utils/api');
+        const data = await authAPI.exchangeLoginCode(loginCode, redirectTo);
+
+        // Accounts with two-factor authentication enter their code on the login page
+        if (data?.code === 'TWO_FACTOR_REQUIRED' && data.mfa_token) {
+          navigate(`/login?mfa_token=${encodeURIComponent(data.mfa_token)}`, { replace: true });
+          return;
+        }
+
+        localStorage.removeItem('auth_error');
+
+        // the exchange would have failed if redirect_to were not on the allowlist
+        if (redirectTo.startsWith('/')) {
+          navigate(redirectTo, { replace: true });
         } else {
-          setError('Missing authentication data');
-          navigate('/login?error=missing_auth_data');
+          window.location.assign(redirectTo);
         }
       } catch (error: any) {
         console.error('Authentication error:', error);
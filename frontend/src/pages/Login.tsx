@@ -36,14 +36,24 @@ export default function Login() {
     });
     const { register, handleSubmit } = useForm<FormData>();
     const [error, setError] = useState('');
+    // Set when the password was accepted and the account asks for a two-factor code
+    const [mfaToken, setMfaToken] = useState('');
+    const [twoFactorCode, setTwoFactorCode] = useState('');
 
     useEffect(() => {
         // Check for error in URL parameters
         const searchParams = new URLSearchParams(location.search);
         const errorType = searchParams.get('error');
+
+        const pendingMfaToken = searchParams.get('mfa_token');
+        if (pendingMfaToken) {
+            setMfaToken(pendingMfaToken);
+        }
         
         if (errorType === 'email_exists') {
-            setError('This email is already registered with a password. Please use your email and password to log in.');
+            setError('An account with this email already exists. Please log in with it, then link this sign-in method from your settings.');
+        } else if (errorType === 'email_unverified') {
+            setError('Your identity provider has not verified your email address. Please verify it there and try again.');
         } else if (errorType === 'auth_failed') {
             setError('Authentication failed. Please try again.');
         }
@@ -134,7 +144,13 @@ export default function Login() {
         try {
             // Use the authAPI login method without any logging
             const { authAPI } = await import('../utils/api');
-            await authAPI.login(formData.email, formData.password);
+            const result = await authAPI.login(formData.email, formData.password);
+
+            if (result?.code === 'TWO_FACTOR_REQUIRED') {
+                setMfaToken(result.mfa_token);
+                setError('');
+                return;
+            }
             
             // If we get here, login was successful - redirect to home
             navigate('/');
@@ -146,8 +162,8 @@ export default function Login() {
                 
                 if (errorData) {
                     switch (errorData.code) {
-                        case 'GOOGLE_ACCOUNT':
-                            setError(errorData.message || "This account uses Google Sign-In. Please use the Google button below.");
+                        case 'EXTERNAL_ACCOUNT':
+                            setError(errorData.message || "This account uses Google or your company sign-in. Please use the matching button below.");
                             setErrors({...errors, general: ''});
                             break;
                         case 'EMAIL_NOT_CONFIRMED':
@@ -179,6 +195,30 @@ export default function Login() {
         }
     };
 
+    const onSubmitTwoFactor = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setIsLoading(true);
+        setError('');
+
+        try {
+            const { authAPI } = await import('../utils/api');
+            await authAPI.loginTwoFactor(mfaToken, twoFactorCode);
+            navigate('/');
+        } catch (error) {
+            if (axios.isAxiosError(error) && error.response?.data) {
+                const errorData = error.response.data;
+                if (errorData.code === 'MFA_TOKEN_INVALID') {
+                    setMfaToken('');
+                }
+                setError(errorData.message || errorData.error || 'Invalid code.');
+            } else {
+                setError('An unexpected error occurred. Please try again.');
+            }
+        } finally {
+            setIsLoading(false);
+        }
+    };
+
     return (
         <>
             <Helmet>
@@ -214,6 +254,40 @@ export default function Login() {
                         </div>
                     )}
 
+                    {mfaToken ? (
+                    <form onSubmit={onSubmitTwoFactor} className="space-y-6">
+                        <div>
+                            <label htmlFor="twoFactorCode" className="block text-sm/6 font-medium text-gray-900">
+                                Authentication code
+                            </label>
+                            <p className="mt-1 text-sm text-gray-500">
+                                Enter the 6-digit code from your authenticator app, or one of your recovery codes.
+                            </p>
+                            <div className="mt-2">
+                                <input
+                                    id="twoFactorCode"
+                                    name="twoFactorCode"
+                                    type="text"
+                                    inputMode="numeric"
+                                    autoComplete="one-time-code"
+                                    value={twoFactorCode}
+                                    onChange={(e) => setTwoFactorCode(e.target.value)}
+                                    required
+                                    className="block w-full rounded-md bg-white px-3 py-1.5 text-base text-gray-900 outline-1 -outline-offset-1 outline-gray-300 placeholder:text-gray-400 focus:outline-2 focus:-outline-offset-2 focus:outline-indigo-600 sm:text-sm/6"
+                                />
+                            </div>
+                        </div>
+                        <div>
+                            <button
+                                type="submit"
+                                disabled={isLoading}
+                                className="flex w-full justify-center rounded-md bg-indigo-600 px-3 py-1.5 text-sm font-semibold leading-6 text-white shadow-sm hover:bg-indigo-500 focus-visible:outline focus-visible:outline-2 focus-visible:outline-offset-2 focus-visible:outline-indigo-600"
+                            >
+                                {isLoading ? 'Verifying...' : 'Verify'}
+                            </button>
+                        </div>
+                    </form>
+                    ) : (
                     <form onSubmit={handleSubmit(onSubmit)} className="space-y-6">
                         <div>
                             <label htmlFor="email" className="block text-sm/6 font-medium text-gray-900">
@@ -260,6 +334,7 @@ export default function Login() {
                             </button>
                         </div>
                     </form>
+                    )}
 
                     <div className="mt-6">
                         <div className="relative">
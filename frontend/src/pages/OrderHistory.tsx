@@ -16,10 +16,12 @@ type OrderItem = {
 
 type Order = {
   id: number;
+  order_number?: string | null;
   created_at: string;
   status: string;
   total: number;
   items: OrderItem[];
+  invoice_url?: string;
 };
 
 export default function OrderHistory() {
@@ -64,6 +66,24 @@ export default function OrderHistory() {
     }).format(date);
   };
 
+  // Download the invoice PDF through the authenticated API client
+  const downloadInvoice = async (order: Order) => {
+    if (!order.invoice_url) return;
+    try {
+      const response = await api.get(order.invoice_url, { responseType: 'blob' });
+      const disposition: string = response.headers['content-disposition'] || '';
+      const match = disposition.match(/filename="([^"]+)"/);
+      const url = window.URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = match ? match[1] : `invoice-${order.id}.pdf`;
+      link.click();
+      window.URL.revokeObjectURL(url);
+    } catch (err) {
+      setError('Failed to download invoice');
+    }
+  };
+
   // Toggle order details
   const toggleOrderDetails = (orderId: number) => {
     if (expandedOrder === orderId) {
@@ -156,7 +176,7 @@ export default function OrderHistory() {
                 <div key={order.id} className="bg-white p-6 rounded-lg shadow-sm border border-gray-200">
                   <div className="flex flex-col sm:flex-row sm:items-center justify-between mb-4">
                     <div>
-                      <h2 className="text-lg font-medium text-gray-900">Order #{order.id}</h2>
+                      <h2 className="text-lg font-medium text-gray-900">Order {order.order_number || `#${order.id}`}</h2>
                       <p className="text-sm text-gray-500">{formatDate(order.created_at)}</p>
                     </div>
                     <div className="mt-2 sm:mt-0 flex items-center">
@@ -181,6 +201,14 @@ export default function OrderHistory() {
                       <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
                     </svg>
                   </button>
+                  {order.invoice_url && (
+                    <button
+                      onClick={() => downloadInvoice(order)}
+                      className="mt-2 text-indigo-600 hover:text-indigo-800 text-sm font-medium"
+                    >
+                      Download Invoice
+                    </button>
+                  )}
                   
                   {expandedOrder === order.id && (
                     <div className="mt-4 border-t border-gray-200 pt-4">
@@ -10,12 +10,44 @@ const api = axios.create({
   withCredentials: true, 
 });
 
+// Refresh requests issued while one is already in flight share it, since each refresh token can only be used once
+let refreshPromise: Promise<string> | null = null;
+
+// Tabs share the refresh cookie, so they take turns refreshing under a Web Lock. Two tabs spending the
+// same refresh token would look like token theft to the server and end the session.
+const refreshLockName = 'auth_refresh';
+
+const refreshAccessToken = (staleToken?: string | null): Promise<string> => {
+  if (!refreshPromise) {
+    const refresh = async (): Promise<string> => {
+      // Another tab may have refreshed while this one waited for the lock
+      const current = localStorage.getItem('auth_token');
+      if (current && staleToken && current !== staleToken) {
+        return current;
+      }
+
+      const response = await axios.post(`${apiUrl}/auth/refresh`, {}, { withCredentials: true });
+      const token = response.data.token;
+      localStorage.setItem('auth_token', token);
+      return token;
+    };
+
+    const run = navigator.locks
+      ? navigator.locks.request(refreshLockName, refresh)
+      : refresh();
+    refreshPromise = run.finally(() => {
+      refreshPromise = null;
+    });
+  }
+  return refreshPromise;
+};
+
 api.interceptors.response.use(
   (response) => {
     return response;
   },
   async (error) => {
-    // Try to refresh user data and auth state if we get a 401
+    // Access tokens are short-lived, so try to refresh once when we get a 401
     if (error.response?.status === 401) {
       // Only handle 401 errors on non-auth routes 
       if (!window.location.pathname.includes('/login') && 
@@ -23,23 +55,13 @@ api.interceptors.response.use(
           !window.location.pathname.includes('/auth/google') &&
           !window.location.pathname.includes('/admin/login')) {
         
-        // Check if we might be able to recover
-        const token = localStorage.getItem('auth_token');
-        if (token && error.config && !error.config._retry) {
+        if (error.config && !error.config._retry) {
           try {
-            // Try to get current user
-            const response = await axios.get(`${apiUrl}/user/me`, {
-              headers: {
-                'Authorization': `Bearer ${token}`
-              }
-            });
-            
-            // If successful, update user data and retry the original request
-            if (response.data) {
-              localStorage.setItem('user', JSON.stringify(response.data));
-              error.config._retry = true;
-              return api(error.config);
-            }
+            const sentToken = String(error.config.headers?.['Authorization'] ?? '').replace(/^Bearer /, '');
+            const token = await refreshAccessToken(sentToken || null);
+            error.config._retry = true;
+            error.config.headers['Authorization'] = `Bearer ${token}`;
+            return api(error.config);
           } catch (refreshError) {
             console.error('Session refresh failed:', refreshError);
             // If refresh failed, clear local data
@@ -48,7 +70,7 @@ api.interceptors.response.use(
             window.location.href = '/login?error=session_expired';
           }
         } else {
-          // No token or already retried, clear local data
+          // Already retried, clear local data
           localStorage.removeItem('auth_token');
           localStorage.removeItem('user');
           window.location.href = '/login?error=session_expired';
@@ -99,9 +121,157 @@ export const authAPI = {
       throw error;
     }
   },
+
+  // Second login step for accounts with two-factor authentication
+  loginTwoFactor: async (mfaToken: string, code: string) => {
+    const response = await api.post('/auth/login/2fa', {
+      mfa_token: mfaToken,
+      code: code.trim()
+    });
+
+    if (response.data?.token) {
+      localStorage.setItem('auth_token', response.data.token);
+    }
+
+    if (response.data?.user) {
+      localStorage.setItem('user', JSON.stringify(response.data.user));
+    }
+
+    return response.data;
+  },
+  
+  // Passwordless login. The email is optional; without it the browser offers any saved passkey.
+  loginWithPasskey: async (email?: string) => {
+    const begin = await api.post('/auth/passkey/login/begin', email ? { email: email.trim() } : {});
+    const options = begin.data.publicKey;
+    const credential = (await navigator.credentials.get({
+      publicKey: {
+        ...options,
+        challenge: fromBase64url(options.challenge),
+        allowCredentials: options.allowCredentials.map((c: any) => ({ ...c, id: fromBase64url(c.id) }))
+      }
+    })) as PublicKeyCredential;
+    const assertion = credential.response as AuthenticatorAssertionResponse;
+
+    const response = await api.post('/auth/passkey/login/finish', {
+      id: credential.id,
+      rawId: toBase64url(credential.rawId),
+      type: credential.type,
+      response: {
+        clientDataJSON: toBase64url(assertion.clientDataJSON),
+        authenticatorData: toBase64url(assertion.authenticatorData),
+        signature: toBase64url(assertion.signature),
+        userHandle: assertion.userHandle ? toBase64url(assertion.userHandle) : undefined
+      }
+    });
+
+    if (response.data?.token) {
+      localStorage.setItem('auth_token', response.data.token);
+    }
+
+    if (response.data?.user) {
+      localStorage.setItem('user', JSON.stringify(response.data.user));
+    }
+
+    return response.data;
+  },
+
+  // Adds a passkey to the logged-in account
+  registerPasskey: async (name: string) => {
+    const begin = await api.post('/user/passkeys/register/begin');
+    const options = begin.data.publicKey;
+    const credential = (await navigator.credentials.create({
+      publicKey: {
+        ...options,
+        challenge: fromBase64url(options.challenge),
+        user: { ...options.user, id: fromBase64url(options.user.id) },
+        excludeCredentials: options.excludeCredentials.map((c: any) => ({ ...c, id: fromBase64url(c.id) }))
+      }
+    })) as PublicKeyCredential;
+    const attestation = credential.response as AuthenticatorAttestationResponse;
+
+    return api.post('/user/passkeys/register/finish', {
+      name: name.trim(),
+      credential: {
+        id: credential.id,
+        rawId: toBase64url(credential.rawId),
+        type: credential.type,
+        response: {
+          clientDataJSON: toBase64url(attestation.clientDataJSON),
+          attestationObject: toBase64url(attestation.attestationObject),
+          transports: attestation.getTransports ? attestation.getTransports() : []
+        }
+      }
+    });
+  },
+
+  getPasskeys: async () => {
+    return api.get('/user/passkeys');
+  },
+
+  deletePasskey: async (id: number) => {
+    return api.delete(`/user/passkeys/${id}`);
+  },
+
+  // Accounts at Google or company identity providers linked to the logged-in user
+  getIdentities: async () => {
+    return api.get('/user/identities');
+  },
+
+  // Linking leaves the page for the provider. The ticket is form-posted so it stays out of the URL;
+  // the user comes back to redirectTo with ?linked=<provider> or ?link_error=<reason>
+  linkIdentity: async (provider: string, redirectTo = '/settings') => {
+    const response = await api.post(`/user/identities/${encodeURIComponent(provider)}/link`);
+
+    const form = document.createElement('form');
+    form.method = 'POST';
+    form.action = `${apiUrl}${response.data.link_url}`;
+    for (const [name, value] of [['link_token', response.data.link_token], ['redirect_to', redirectTo]]) {
+      const input = document.createElement('input');
+      input.type = 'hidden';
+      input.name = name;
+      input.value = value;
+      form.appendChild(input);
+    }
+    document.body.appendChild(form);
+    form.submit();
+  },
+
+  // Refused with LAST_LOGIN_METHOD when the account has no password, passkey or other linked account
+  unlinkIdentity: async (id: number) => {
+    return api.delete(`/user/identities/${id}`);
+  },
   
-  googleLogin: () => {
-    window.location.href = `${apiUrl}/auth/google/login`;
+  // redirectTo is a storefront path (or an allowlisted URL) to return to after logging in
+  googleLogin: (redirectTo?: string) => {
+    const query = redirectTo ? `?redirect_to=${encodeURIComponent(redirectTo)}` : '';
+    window.location.href = `${apiUrl}/auth/google/login${query}`;
+  },
+
+  // Identity providers configured on the backend, e.g. a company's single sign-on
+  getLoginProviders: async () => {
+    return api.get('/auth/providers');
+  },
+
+  providerLogin: (provider: string, redirectTo?: string) => {
+    const query = redirectTo ? `?redirect_to=${encodeURIComponent(redirectTo)}` : '';
+    window.location.href = `${apiUrl}/auth/oidc/${encodeURIComponent(provider)}/login${query}`;
+  },
+
+  // Trades the one-time code from a social login redirect for tokens or a two-factor challenge
+  // The backend refuses redirect targets outside the allowlist
+  exchangeLoginCode: async (code: string, redirectTo?: string) => {
+    const response = await api.post('/auth/exchange', { code, redirect_to: redirectTo });
+
+    if (response.data?.token) {
+      localStorage.setItem('auth_token', response.data.token);
+    }
+
+    if (response.data?.user) {
+      localStorage.setItem('user', JSON.stringify(response.data.user));
+    }
+
+    return response.data;
   },
   
   logout: async () => {
@@ -126,6 +296,24 @@ export const authAPI = {
   }
 };
 
+// WebAuthn binary fields travel as unpadded base64url
+function toBase64url(buffer: ArrayBuffer): string {
+  const bytes = new Uint8Array(buffer);
+  let binary = '';
+  bytes.forEach((b) => { binary += String.fromCharCode(b); });
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function fromBase64url(value: string): ArrayBuffer {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+  const binary = atob(base64.padEnd(base64.length + (4 - base64.length % 4) % 4, '='));
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes.buffer;
+}
+
 function maskEmail(email: string): string {
   if (!email) return '';
   